@@ -3,6 +3,8 @@ package archive
 import (
 	"bitbucket.org/bosgood/dep-get/command"
 	"bitbucket.org/bosgood/dep-get/lib/fs"
+	"bitbucket.org/bosgood/dep-get/lib/manifest"
+	"bitbucket.org/bosgood/dep-get/nodejs"
 	"flag"
 	"fmt"
 	"github.com/aws/aws-sdk-go/aws"
@@ -31,6 +33,8 @@ type archiveCommandFlags struct {
 	s3URL    string
 	bucket   string
 	s3Key    string
+	command.OmitFlags
+	omit nodejs.Omit
 }
 
 var (
@@ -65,11 +69,12 @@ func getConfig(args []string) (archiveCommandFlags, *flag.FlagSet, error) {
 
 	cmdFlags := flag.NewFlagSet("install", flag.ExitOnError)
 	cmdFlags.BoolVar(&cmdConfig.Help, "help", false, "show command help")
-	cmdFlags.StringVar(&cmdConfig.platform, "platform", "", "platform type (allowed: nodejs|python)")
+	cmdFlags.StringVar(&cmdConfig.platform, "platform", "", "platform type (allowed: nodejs|python|golang|ruby|rust)")
 	cmdFlags.StringVar(&cmdConfig.source, "source", "", "project directory (default: .)")
 	cmdFlags.StringVar(&cmdConfig.profile, "profile", "", "AWS credentials profile (default: default)")
 	cmdFlags.StringVar(&cmdConfig.region, "region", "", "AWS region")
 	cmdFlags.StringVar(&cmdConfig.s3URL, "path", "", "S3 upload path")
+	cmdConfig.OmitFlags.Register(cmdFlags)
 
 	if err := cmdFlags.Parse(args); err != nil {
 		errMsg := fmt.Sprintf(
@@ -111,17 +116,24 @@ func getConfig(args []string) (archiveCommandFlags, *flag.FlagSet, error) {
 		}
 	}
 
-	if cmdConfig.platform != "nodejs" {
+	if !command.ValidPlatform(cmdConfig.platform) {
+		return cmdConfig, cmdFlags, command.UnsupportedPlatformError(cmdConfig.platform)
+	}
+
+	// Parameter validation goes here
+	omit, err := nodejs.ParseOmit(cmdConfig.OmitFlags.Values())
+	if err != nil {
 		errMsg := fmt.Sprintf(
-			"%sOnly nodejs supported at the moment\n",
+			"%s%s\n",
 			command.LogErrorPrefix,
+			err,
 		)
 		return cmdConfig, cmdFlags, &command.ConfigError{
 			Explanation: errMsg,
 		}
 	}
+	cmdConfig.omit = omit
 
-	// Parameter validation goes here
 	s3URL, err := url.Parse(cmdConfig.s3URL)
 	if err != nil {
 		errMsg := fmt.Sprintf(
@@ -157,8 +169,35 @@ func (c *archiveCommand) InitS3() error {
 	return nil
 }
 
-func (c *archiveCommand) Upload(archiveFileInfo os.FileInfo, archiveFile io.ReadSeeker) error {
-	s3Path := path.Join(c.config.s3Key, archiveFileInfo.Name())
+// contentType returns the media type of a fetched file by its extension:
+// zip for python wheels, zip sdists and go module zips, text and JSON for
+// the other files of a GOPROXY, binary for ruby gems and rust crates and gzip
+// for tarballs
+func contentType(name string) string {
+	if path.Base(name) == "list" {
+		return "text/plain; charset=utf-8"
+	}
+	switch path.Ext(name) {
+	case ".whl", ".zip":
+		return "application/zip"
+	case ".mod":
+		return "text/plain; charset=utf-8"
+	case ".info":
+		return "application/json"
+	case ".gem", ".crate":
+		return "application/octet-stream"
+	case ".bz2":
+		return "application/x-bzip2"
+	case ".xz":
+		return "application/x-xz"
+	}
+	return "application/gzip"
+}
+
+// Upload posts the file name, a path relative to the source directory,
+// to the same path under the S3 key
+func (c *archiveCommand) Upload(name string, archiveFileInfo os.FileInfo, archiveFile io.ReadSeeker) error {
+	s3Path := path.Join(c.config.s3Key, name)
 	fmt.Printf(
 		"%sUploading to path: s3://%s%s\n",
 		command.LogInfoPrefix,
@@ -170,7 +209,7 @@ func (c *archiveCommand) Upload(archiveFileInfo os.FileInfo, archiveFile io.Read
 		Bucket:        aws.String(c.config.bucket),
 		Key:           aws.String(s3Path),
 		ContentLength: aws.Int64(archiveFileInfo.Size()),
-		ContentType:   aws.String("application/gzip"),
+		ContentType:   aws.String(contentType(name)),
 	})
 
 	return err
@@ -187,7 +226,8 @@ func (c *archiveCommand) Run(args []string) int {
 	}
 	c.config = cmdConfig
 
-	archives, err := c.os.ReadDir(c.config.source)
+	// Subdirectories are kept, such as the GOPROXY layout of go modules
+	archives, err := fs.Walk(c.os, c.config.source)
 	if err != nil {
 		fmt.Printf(
 			"%sError reading archive path: %s\n",
@@ -196,6 +236,20 @@ func (c *archiveCommand) Run(args []string) int {
 		)
 	}
 
+	// fetch records which packages are dev, optional or peer dependencies
+	fetched := manifest.New()
+	if c.config.omit.Any() {
+		fetched, err = manifest.Read(c.os, c.config.source)
+		if err != nil {
+			fmt.Printf(
+				"%sCan't read the fetch manifest to omit dependencies: %s\n",
+				command.LogErrorPrefix,
+				err,
+			)
+			return 1
+		}
+	}
+
 	err = c.InitS3()
 	if err != nil {
 		fmt.Printf(
@@ -213,10 +267,20 @@ func (c *archiveCommand) Run(args []string) int {
 		c.config.s3Key,
 	)
 
-	for _, archiveFileInfo := range archives {
+	numUploaded := 0
+	for _, name := range archives {
+		if fetched.Omitted(name, c.config.omit) {
+			fmt.Printf(
+				"%sOmitting dependency: %s\n",
+				command.LogInfoPrefix,
+				name,
+			)
+			continue
+		}
+
 		archiveFilePath := path.Join(
 			c.config.source,
-			archiveFileInfo.Name(),
+			name,
 		)
 		fmt.Printf(
 			"%sReading dependency file: %s\n",
@@ -224,6 +288,16 @@ func (c *archiveCommand) Run(args []string) int {
 			archiveFilePath,
 		)
 
+		archiveFileInfo, err := c.os.Stat(archiveFilePath)
+		if err != nil {
+			fmt.Printf(
+				"%sFailed to stat file %s: %s\n",
+				command.LogErrorPrefix,
+				archiveFilePath,
+				err,
+			)
+			return 1
+		}
 		archiveFile, err := c.os.Open(archiveFilePath)
 		if err != nil {
 			fmt.Printf(
@@ -241,7 +315,7 @@ func (c *archiveCommand) Run(args []string) int {
 			}
 		}()
 
-		err = c.Upload(archiveFileInfo, archiveFile)
+		err = c.Upload(name, archiveFileInfo, archiveFile)
 		if err != nil {
 			fmt.Printf(
 				"%sFailed to archive object to s3://%s%s, %s\n",
@@ -259,12 +333,13 @@ func (c *archiveCommand) Run(args []string) int {
 			c.config.bucket,
 			c.config.s3Key,
 		)
+		numUploaded++
 	}
 
 	fmt.Printf(
 		"%sUploaded %d objects\n",
 		command.LogSuccessPrefix,
-		len(archives),
+		numUploaded,
 	)
 
 	return 0
@@ -1,7 +1,10 @@
 package command
 
 import (
+	"flag"
+	"fmt"
 	"github.com/ttacon/chalk"
+	"strings"
 )
 
 var (
@@ -10,6 +13,31 @@ var (
 	LogInfoPrefix    = chalk.Yellow.Color("[INFO]     ")
 )
 
+// Platforms lists the values the --platform flag accepts
+var Platforms = []string{"nodejs", "python", "golang", "ruby", "rust"}
+
+// ValidPlatform reports whether platform is one of Platforms
+func ValidPlatform(platform string) bool {
+	for _, p := range Platforms {
+		if p == platform {
+			return true
+		}
+	}
+	return false
+}
+
+// UnsupportedPlatformError explains that platform isn't one of Platforms
+func UnsupportedPlatformError(platform string) *ConfigError {
+	return &ConfigError{
+		Explanation: fmt.Sprintf(
+			"%sUnsupported platform: %s (allowed: %s)\n",
+			LogErrorPrefix,
+			platform,
+			strings.Join(Platforms, "|"),
+		),
+	}
+}
+
 // BaseFlags defines command flags that all commands share
 type BaseFlags struct {
 	Help bool
@@ -23,3 +51,37 @@ type ConfigError struct {
 func (e *ConfigError) Error() string {
 	return e.Explanation
 }
+
+// StringsFlag collects the values of a flag which may be repeated
+type StringsFlag []string
+
+func (f *StringsFlag) String() string {
+	return strings.Join(*f, ", ")
+}
+
+// Set adds another value of the flag
+func (f *StringsFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+// OmitFlags select the types of dependencies to leave out, like npm's
+// --production and --omit options
+type OmitFlags struct {
+	Production bool
+	Omit       StringsFlag
+}
+
+// Register adds the --production and --omit flags to cmdFlags
+func (f *OmitFlags) Register(cmdFlags *flag.FlagSet) {
+	cmdFlags.BoolVar(&f.Production, "production", false, "leave out dev dependencies, same as --omit=dev")
+	cmdFlags.Var(&f.Omit, "omit", "dependency types to leave out, any of dev,optional,peer (repeatable)")
+}
+
+// Values returns the dependency types to leave out
+func (f *OmitFlags) Values() []string {
+	if f.Production {
+		return append([]string{"dev"}, f.Omit...)
+	}
+	return f.Omit
+}
@@ -2,35 +2,153 @@ package fetch
 
 import (
 	"bitbucket.org/bosgood/dep-get/command"
+	"bitbucket.org/bosgood/dep-get/golang"
+	"bitbucket.org/bosgood/dep-get/lib/download"
+	"bitbucket.org/bosgood/dep-get/lib/filter"
 	"bitbucket.org/bosgood/dep-get/lib/fs"
+	"bitbucket.org/bosgood/dep-get/lib/integrity"
+	"bitbucket.org/bosgood/dep-get/lib/manifest"
 	"bitbucket.org/bosgood/dep-get/nodejs"
-	"encoding/json"
+	"bitbucket.org/bosgood/dep-get/python"
+	"crypto/sha512"
 	"flag"
 	"fmt"
 	"github.com/mitchellh/cli"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"net/url"
+	"os"
 	"path"
-	"regexp"
 	"strings"
+	"sync"
+	"time"
 )
 
 var realOS fs.FileSystem = &fs.OSFS{}
 
+// maxRetryDelay caps the exponential backoff between download retries
+const maxRetryDelay = time.Minute
+
+const (
+	// localDepsPack packs local dependencies from their directories
+	localDepsPack = "pack"
+	// localDepsSkip leaves local dependencies out, reporting each one
+	localDepsSkip = "skip"
+)
+
 type fetchCommand struct {
-	npmShrinkwrap nodejs.NPMShrinkwrap
-	os            fs.FileSystem
-	config        fetchCommandFlags
+	os       fs.FileSystem
+	config   fetchCommandFlags
+	client   *http.Client
+	retryer  *download.Retryer
+	manifest *manifest.Manifest
+	npmrc    *nodejs.NPMRC
+	rewriter *nodejs.RegistryRewriter
+	gitHosts *nodejs.GitHostResolver
+
+	// projectDir is the directory local dependency paths are relative to
+	projectDir string
+
+	// printLock keeps output lines whole when downloading concurrently
+	printLock sync.Mutex
+
+	// lookupErrs are the dependencies whose files couldn't be looked up,
+	// which --keep-going leaves out and reports along with failed
+	// downloads
+	lookupErrs fetchErrors
 }
 
 type fetchCommandFlags struct {
 	command.BaseFlags
-	platform     string
-	source       string
-	destination  string
-	whitelistStr string
-	whitelist    *regexp.Regexp
+	platform       string
+	source         string
+	destination    string
+	whitelistStr   string
+	includes       command.StringsFlag
+	excludes       command.StringsFlag
+	filterFiles    command.StringsFlag
+	filter         *filter.Filter
+	verbose        bool
+	concurrency    int
+	keepGoing      bool
+	connectTimeout time.Duration
+	readTimeout    time.Duration
+	retries        int
+	retryDelay     time.Duration
+	force          bool
+	rewrites       command.StringsFlag
+	gitHosts       command.StringsFlag
+	localDeps      string
+	indexURL       string
+	pythonEnvs     command.StringsFlag
+	pythonEnv      python.Environment
+	pythonTagFlags command.StringsFlag
+	pythonTags     []python.Tag
+	goproxy        string
+	gemSource      string
+	crateIndex     string
+	command.OmitFlags
+	omit nodejs.Omit
+}
+
+// fetchStatus tells how a dependency ended up in the destination directory
+type fetchStatus int
+
+const (
+	// statusFetched dependencies were downloaded for the first time
+	statusFetched fetchStatus = iota
+	// statusSkipped dependencies were already present and verified
+	statusSkipped
+	// statusRepaired dependencies were present but failed verification,
+	// so were downloaded again
+	statusRepaired
+)
+
+// artifact is one file fetch downloads into its destination, such as a
+// package tarball or a python wheel, with how to download and check it
+type artifact struct {
+	// name identifies the artifact in output, such as ee-first@1.1.1
+	name string
+	// fileName is where the artifact goes, relative to the destination
+	fileName string
+	// url is where the lockfile or package index says to download from
+	url string
+	// integrity and shasum are the digests the lockfile records, as
+	// integrity.NewVerifier takes them
+	integrity string
+	shasum    string
+	// entry describes the artifact in the manifest, apart from its digest
+	// and the URL it was actually fetched from
+	entry manifest.Entry
+	// resolve returns the URL to download from when it may not be url
+	resolve func() (string, error)
+	// open starts a download which takes more than an HTTP GET
+	open func(string) (io.ReadCloser, error)
+	// validate checks that the contents are what kind says they are
+	validate func(io.Reader) error
+	kind     string
+}
+
+// fetchResult reports the outcome of fetching one dependency
+type fetchResult struct {
+	artifact    artifact
+	url         string
+	outFilePath string
+	status      fetchStatus
+	retries     int
+	err         error
+}
+
+// fetchErrors collects every failed download when fetching with --keep-going
+type fetchErrors []error
+
+func (e fetchErrors) Error() string {
+	var msgs []string
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "\n")
 }
 
 func newFetchCommandWithFS(os fs.FileSystem) (cli.Command, error) {
@@ -57,16 +175,48 @@ func (c *fetchCommand) Help() string {
 	return ""
 }
 
+// filterRanges returns the parser of the version ranges of rules for
+// platform. Only nodejs rules take ranges, npm's semver ones; python, go,
+// ruby and rust versions are matched by globs and regexps, since npm's
+// ranges would misread them.
+func filterRanges(platform string) filter.RangeParser {
+	if platform == "nodejs" {
+		return nodejs.ParseFilterRange
+	}
+	return nil
+}
+
 func getConfig(args []string) (fetchCommandFlags, *flag.FlagSet, error) {
 	var cmdConfig fetchCommandFlags
 
 	cmdFlags := flag.NewFlagSet("fetch", flag.ExitOnError)
 	cmdFlags.BoolVar(&cmdConfig.Help, "help", false,
 		"show command help")
-	cmdFlags.StringVar(&cmdConfig.platform, "platform", "", "platform type (allowed: nodejs|python)")
+	cmdFlags.StringVar(&cmdConfig.platform, "platform", "", "platform type (allowed: nodejs|python|golang|ruby|rust)")
 	cmdFlags.StringVar(&cmdConfig.source, "source", "", "project directory (default: .)")
 	cmdFlags.StringVar(&cmdConfig.destination, "destination", "", "dependencies download destination")
 	cmdFlags.StringVar(&cmdConfig.whitelistStr, "whitelist", "", "dependency name whitelist regexp")
+	cmdFlags.Var(&cmdConfig.includes, "include", "only fetch dependencies matching [name:|version:|scope:|host:]PATTERN, a glob, /regexp/ or version range (repeatable)")
+	cmdFlags.Var(&cmdConfig.excludes, "exclude", "don't fetch dependencies matching [name:|version:|scope:|host:]PATTERN, a glob, /regexp/ or version range (repeatable)")
+	cmdFlags.Var(&cmdConfig.filterFiles, "filter-file", "file of 'include RULE' and 'exclude RULE' lines (repeatable)")
+	cmdFlags.BoolVar(&cmdConfig.verbose, "verbose", false, "report why each dependency was left out")
+	cmdFlags.IntVar(&cmdConfig.concurrency, "concurrency", 1, "number of dependencies to download at once")
+	cmdFlags.BoolVar(&cmdConfig.keepGoing, "keep-going", false, "keep downloading after a dependency fails and report every failure")
+	cmdFlags.DurationVar(&cmdConfig.connectTimeout, "connect-timeout", 10*time.Second, "time allowed to connect to a registry")
+	cmdFlags.DurationVar(&cmdConfig.readTimeout, "read-timeout", 30*time.Second, "time allowed without receiving data from a registry")
+	cmdFlags.IntVar(&cmdConfig.retries, "retries", 3, "times to retry a download after a 5xx, 429 or connection failure")
+	cmdFlags.DurationVar(&cmdConfig.retryDelay, "retry-delay", time.Second, "delay before the first retry, doubling after each")
+	cmdFlags.BoolVar(&cmdConfig.force, "force", false, "download dependencies even if already present in the destination")
+	cmdFlags.Var(&cmdConfig.rewrites, "registry-rewrite", "registry rewrite rule, either 'FROM -> TO' URL prefixes or '@scope:registry=URL' (repeatable)")
+	cmdConfig.OmitFlags.Register(cmdFlags)
+	cmdFlags.StringVar(&cmdConfig.localDeps, "local-deps", localDepsPack, "what to do with file:, link: and workspace dependencies (allowed: pack|skip)")
+	cmdFlags.Var(&cmdConfig.gitHosts, "git-host", "git host serving tarballs, either 'github|gitlab|bitbucket=BASEURL' for self-hosted instances or 'HOST=TEMPLATE' (repeatable)")
+	cmdFlags.StringVar(&cmdConfig.indexURL, "index-url", "", "python package index, instead of the lockfile's or PyPI's")
+	cmdFlags.Var(&cmdConfig.pythonEnvs, "python-env", "python environment marker value such as python_version=3.11, leaving out dependencies other environments need (repeatable)")
+	cmdFlags.Var(&cmdConfig.pythonTagFlags, "python-tag", "python environment to fetch wheels for, as an interpreter-abi-platform tag such as cp311-cp311-manylinux_2_28_x86_64, falling back to sdists (repeatable)")
+	cmdFlags.StringVar(&cmdConfig.goproxy, "goproxy", "", "GOPROXY endpoint to download go modules from (default: $GOPROXY or "+golang.DefaultProxyURL+")")
+	cmdFlags.StringVar(&cmdConfig.gemSource, "gem-source", "", "ruby gem source to download gems from, instead of the lockfile's remotes")
+	cmdFlags.StringVar(&cmdConfig.crateIndex, "crate-index", "", "rust registry index whose config.json gives the endpoint to download crates.io crates from, instead of crates.io's")
 
 	if err := cmdFlags.Parse(args); err != nil {
 		errMsg := fmt.Sprintf(
@@ -104,41 +254,150 @@ func getConfig(args []string) (fetchCommandFlags, *flag.FlagSet, error) {
 		}
 	}
 
-	if cmdConfig.platform != "nodejs" {
+	if !command.ValidPlatform(cmdConfig.platform) {
+		return cmdConfig, cmdFlags, command.UnsupportedPlatformError(cmdConfig.platform)
+	}
+
+	omit, err := nodejs.ParseOmit(cmdConfig.OmitFlags.Values())
+	if err != nil {
+		errMsg := fmt.Sprintf(
+			"%s%s\n",
+			command.LogErrorPrefix,
+			err,
+		)
+		return cmdConfig, cmdFlags, &command.ConfigError{
+			Explanation: errMsg,
+		}
+	}
+	cmdConfig.omit = omit
+
+	pythonEnv, err := python.ParseEnvironment(cmdConfig.pythonEnvs)
+	if err != nil {
 		errMsg := fmt.Sprintf(
 			"%s%s\n",
 			command.LogErrorPrefix,
-			"Only nodejs supported at the moment",
+			err,
 		)
 		return cmdConfig, cmdFlags, &command.ConfigError{
 			Explanation: errMsg,
 		}
 	}
+	cmdConfig.pythonEnv = pythonEnv
 
-	// Additional command parsing goes here
-	if cmdConfig.whitelistStr != "" {
-		rgx, err := regexp.Compile(cmdConfig.whitelistStr)
+	for _, value := range cmdConfig.pythonTagFlags {
+		tag, err := python.ParseTag(value)
 		if err != nil {
 			errMsg := fmt.Sprintf(
-				"%sMalformed dependency whitelist regexp: %s\n",
+				"%s%s\n",
 				command.LogErrorPrefix,
-				cmdConfig.whitelistStr,
+				err,
 			)
 			return cmdConfig, cmdFlags, &command.ConfigError{
 				Explanation: errMsg,
 			}
 		}
-		cmdConfig.whitelist = rgx
+		cmdConfig.pythonTags = append(cmdConfig.pythonTags, tag)
+	}
+
+	if cmdConfig.localDeps != localDepsPack && cmdConfig.localDeps != localDepsSkip {
+		errMsg := fmt.Sprintf(
+			"%sUnknown local-deps mode: %s\n",
+			command.LogErrorPrefix,
+			cmdConfig.localDeps,
+		)
+		return cmdConfig, cmdFlags, &command.ConfigError{
+			Explanation: errMsg,
+		}
+	}
+
+	if cmdConfig.concurrency < 1 {
+		errMsg := fmt.Sprintf(
+			"%sConcurrency must be at least 1: %d\n",
+			command.LogErrorPrefix,
+			cmdConfig.concurrency,
+		)
+		return cmdConfig, cmdFlags, &command.ConfigError{
+			Explanation: errMsg,
+		}
+	}
+
+	if cmdConfig.retries < 0 {
+		errMsg := fmt.Sprintf(
+			"%sRetries can't be negative: %d\n",
+			command.LogErrorPrefix,
+			cmdConfig.retries,
+		)
+		return cmdConfig, cmdFlags, &command.ConfigError{
+			Explanation: errMsg,
+		}
+	}
+
+	for _, rule := range cmdConfig.rewrites {
+		if err := nodejs.NewRegistryRewriter().AddRule(rule); err != nil {
+			errMsg := fmt.Sprintf(
+				"%s%s\n",
+				command.LogErrorPrefix,
+				err,
+			)
+			return cmdConfig, cmdFlags, &command.ConfigError{
+				Explanation: errMsg,
+			}
+		}
+	}
+
+	for _, rule := range cmdConfig.gitHosts {
+		if err := nodejs.NewGitHostResolver().AddRule(rule); err != nil {
+			errMsg := fmt.Sprintf(
+				"%s%s\n",
+				command.LogErrorPrefix,
+				err,
+			)
+			return cmdConfig, cmdFlags, &command.ConfigError{
+				Explanation: errMsg,
+			}
+		}
+	}
+
+	// Additional command parsing goes here
+	cmdConfig.filter = filter.New(filterRanges(cmdConfig.platform))
+	var filterErr error
+	if cmdConfig.whitelistStr != "" {
+		rule, err := cmdConfig.filter.ParseRule("name:/"+cmdConfig.whitelistStr+"/", false, "--whitelist")
+		if err != nil {
+			filterErr = fmt.Errorf("Malformed dependency whitelist regexp: %s", cmdConfig.whitelistStr)
+		}
+		cmdConfig.filter.Add(rule)
+	}
+	for _, text := range cmdConfig.includes {
+		rule, err := cmdConfig.filter.ParseRule(text, false, "--include")
+		if err != nil && filterErr == nil {
+			filterErr = err
+		}
+		cmdConfig.filter.Add(rule)
+	}
+	for _, text := range cmdConfig.excludes {
+		rule, err := cmdConfig.filter.ParseRule(text, true, "--exclude")
+		if err != nil && filterErr == nil {
+			filterErr = err
+		}
+		cmdConfig.filter.Add(rule)
+	}
+	if filterErr != nil {
+		errMsg := fmt.Sprintf(
+			"%s%s\n",
+			command.LogErrorPrefix,
+			filterErr,
+		)
+		return cmdConfig, cmdFlags, &command.ConfigError{
+			Explanation: errMsg,
+		}
 	}
 
 	return cmdConfig, cmdFlags, nil
 }
 
-func (c *fetchCommand) readDependencies(dirPath string) (nodejs.NPMShrinkwrap, error) {
-	var npmShrinkwrap nodejs.NPMShrinkwrap
-
-	packageFilePath := path.Join(dirPath, nodejs.DependenciesFileName)
-	packageFileContents, err := c.os.ReadFile(packageFilePath)
+func (c *fetchCommand) readDependencies(dirPath string) ([]nodejs.NodeDependency, error) {
+	packageFilePath, packageFileContents, err := nodejs.FindDependenciesFile(c.os, dirPath)
 	if err != nil {
 		fmt.Printf(
 			"%s%s: %s\n",
@@ -146,10 +405,10 @@ func (c *fetchCommand) readDependencies(dirPath string) (nodejs.NPMShrinkwrap, e
 			"Can't open the dependencies file",
 			err,
 		)
-		return npmShrinkwrap, err
+		return nil, err
 	}
 
-	err = json.Unmarshal(packageFileContents, &npmShrinkwrap)
+	graph, err := nodejs.ParseDependencyGraph(path.Base(packageFilePath), packageFileContents)
 	if err != nil {
 		fmt.Printf(
 			"%s%s: %s\n",
@@ -157,117 +416,699 @@ func (c *fetchCommand) readDependencies(dirPath string) (nodejs.NPMShrinkwrap, e
 			"Failed to decode the dependencies file file",
 			err,
 		)
-		return npmShrinkwrap, err
+		return nil, err
 	}
 
-	return npmShrinkwrap, nil
-}
+	fmt.Printf(
+		"%sRead dependencies file: %s\n",
+		command.LogInfoPrefix,
+		packageFilePath,
+	)
+
+	// yarn lockfiles leave which dependencies are dev or optional to
+	// package.json
+	packageJSON, err := nodejs.ReadPackageJSON(c.os, dirPath)
+	if err == nil {
+		graph.LinkProject(packageJSON)
+	} else if !os.IsNotExist(err) {
+		fmt.Printf(
+			"%s%s: %s\n",
+			command.LogErrorPrefix,
+			"Can't read package.json",
+			err,
+		)
+		return nil, err
+	}
+	if c.config.omit.Any() && !graph.KnowsFlags() {
+		fmt.Printf(
+			"%s%s doesn't record which dependencies are dev or optional, and there's no package.json to tell; none will be omitted\n",
+			command.LogErrorPrefix,
+			packageFilePath,
+		)
+	}
 
-var repoPattern = regexp.MustCompile(`^/.+/.+\.git$`)
+	return graph.Dependencies(), nil
+}
 
-func (c *fetchCommand) resolveDependencyURL(depURL string) (string, error) {
-	urlObj, err := url.Parse(depURL)
-	if err != nil {
-		return depURL, nil
+// resolveDependencyURL returns the URL to download dep from, applying
+// registry rewrites and translating git URLs to tarball downloads
+func (c *fetchCommand) resolveDependencyURL(dep nodejs.NodeDependency) (string, error) {
+	if dep.IsLocal() {
+		return "file:" + c.localDependencyPath(dep), nil
 	}
 
+	depURL := c.rewriter.Rewrite(dep.Name, dep.PackageURL)
+
 	// Plain old HTTP(s) URLs
-	scheme := urlObj.Scheme
-	if scheme == "http" || scheme == "https" {
-		return depURL, nil
+	if urlObj, err := url.Parse(depURL); err == nil {
+		if urlObj.Scheme == "http" || urlObj.Scheme == "https" {
+			return depURL, nil
+		}
+	}
+
+	repo, ok := nodejs.ParseGitURL(depURL)
+	if !ok {
+		return depURL, fmt.Errorf("Unknown URL scheme: %s", depURL)
+	}
+	if tarballURL, ok := c.gitHosts.TarballURL(repo); ok {
+		return tarballURL, nil
 	}
+	// Hosts without a tarball endpoint are cloned and packed instead
+	return depURL, nil
+}
 
-	// git URLs whitelisted by site
-	if scheme == "git" {
-		if !repoPattern.MatchString(urlObj.Path) {
-			return depURL, fmt.Errorf("Unknown git URL path format: %s", urlObj.Path)
+// openDependency starts downloading the tarball at depURL, either over
+// HTTP or, for git URLs, by cloning the repository and packing it. Local
+// file: URLs name either a tarball or a directory to pack.
+func (c *fetchCommand) openDependency(depURL string) (io.ReadCloser, error) {
+	if strings.HasPrefix(depURL, "file:") {
+		localPath := strings.TrimPrefix(depURL, "file:")
+		info, err := c.os.Stat(localPath)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			return c.os.Open(localPath)
 		}
-		repoParts := strings.Split(strings.Split(urlObj.Path, ".")[0], "/")[1:]
-		owner := repoParts[0]
-		repo := repoParts[1]
+		tarballReader, tarballWriter := io.Pipe()
+		go func() {
+			tarballWriter.CloseWithError(nodejs.PackDirectory(c.os, localPath, tarballWriter))
+		}()
+		return tarballReader, nil
+	}
 
-		commit := urlObj.Fragment
-		if commit == "" {
-			commit = "master"
+	if repo, ok := nodejs.ParseGitURL(depURL); ok {
+		tarballReader, tarballWriter := io.Pipe()
+		go func() {
+			tarballWriter.CloseWithError(nodejs.PackGitRepo(repo, tarballWriter))
+		}()
+		return tarballReader, nil
+	}
+
+	return c.openURL(depURL, c.npmrc.AuthHeader(depURL))
+}
+
+// openURL starts downloading from an HTTP(S) URL, sending authHeader as
+// the Authorization header unless it's empty
+func (c *fetchCommand) openURL(depURL string, authHeader string) (io.ReadCloser, error) {
+	req, err := http.NewRequest("GET", depURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if authHeader != "" {
+		req.Header.Set("Authorization", authHeader)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		resp.Body.Close()
+		return nil, download.NewStatusError(resp)
+	}
+	return resp.Body, nil
+}
+
+// omitDependencies leaves out bundled dependencies, which ship inside the
+// tarball of the package depending on them, and those of the types to omit
+func (c *fetchCommand) omitDependencies(deps []nodejs.NodeDependency) []nodejs.NodeDependency {
+	var kept []nodejs.NodeDependency
+	numBundled, numOmitted := 0, 0
+	for _, dep := range deps {
+		if dep.Bundled {
+			numBundled++
+			continue
+		}
+		if dep.IsOmitted(c.config.omit) {
+			numOmitted++
+			continue
 		}
+		kept = append(kept, dep)
+	}
 
-		if urlObj.Host == "github.com" {
-			httpURL := fmt.Sprintf(
-				"https://github.com/%s/%s/archive/%s.tgz",
-				owner,
-				repo,
-				commit,
-			)
-			return httpURL, nil
-		} else if urlObj.Host == "bitbucket.com" {
-			httpURL := fmt.Sprintf(
-				"https://bitbucket.org/%s/%s/get/%s.tgz",
-				owner,
-				repo,
-				commit,
+	if numBundled > 0 || numOmitted > 0 {
+		fmt.Printf(
+			"%sLeaving out %d bundled and %d omitted dependencies.\n",
+			command.LogInfoPrefix,
+			numBundled,
+			numOmitted,
+		)
+	}
+	return kept
+}
+
+// filterDependencies keeps the dependencies the include and exclude rules
+// select, reporting the rule leaving out each of the others when verbose
+func (c *fetchCommand) filterDependencies(deps []nodejs.NodeDependency) []nodejs.NodeDependency {
+	var kept []nodejs.NodeDependency
+	for _, dep := range deps {
+		excluded, reason := c.config.filter.Excludes(dep.FilterDependency())
+		if !excluded {
+			kept = append(kept, dep)
+			continue
+		}
+		if c.config.verbose {
+			fmt.Printf(
+				"%sExcluding %s: %s\n",
+				command.LogInfoPrefix,
+				dep.GetCanonicalName(),
+				reason,
 			)
-			return httpURL, nil
 		}
 	}
+	return kept
+}
 
-	return depURL, fmt.Errorf("Unknown URL scheme: %s", urlObj.Scheme)
+// localDependencyPath returns where the local dependency dep is on disk
+func (c *fetchCommand) localDependencyPath(dep nodejs.NodeDependency) string {
+	if path.IsAbs(dep.LocalPath) {
+		return dep.LocalPath
+	}
+	return path.Join(c.projectDir, dep.LocalPath)
 }
 
-func (c *fetchCommand) fetchDependency(dep nodejs.NodeDependency) (string, error) {
-	depURL, err := c.resolveDependencyURL(dep.PackageURL)
+// prepareLocalDependencies drops local dependencies from deps when they
+// are to be skipped, reporting each one, and otherwise fills in versions
+// missing from the lockfile from their package.json
+func (c *fetchCommand) prepareLocalDependencies(deps []nodejs.NodeDependency) ([]nodejs.NodeDependency, error) {
+	var prepared []nodejs.NodeDependency
+	for _, dep := range deps {
+		if !dep.IsLocal() {
+			prepared = append(prepared, dep)
+			continue
+		}
+
+		if c.config.localDeps == localDepsSkip {
+			fmt.Printf(
+				"%sSkipping local dependency %s (%s)\n",
+				command.LogInfoPrefix,
+				dep.Name,
+				dep.LocalPath,
+			)
+			continue
+		}
+
+		if dep.Version == "" {
+			packageJSON, err := nodejs.ReadPackageJSON(c.os, c.localDependencyPath(dep))
+			if err != nil {
+				return nil, fmt.Errorf("%s (%s): %s", dep.Name, dep.LocalPath, err)
+			}
+			dep.Version = packageJSON.Version
+		}
+		prepared = append(prepared, dep)
+	}
+	return prepared, nil
+}
+
+// downloadArtifact makes one attempt at downloading a from depURL. The
+// file is written to a temporary file next to outFilePath, which is only
+// renamed into place once it has been verified, so an interrupted
+// download never leaves behind a file which looks complete.
+func (c *fetchCommand) downloadArtifact(
+	a artifact,
+	depURL string,
+	outFilePath string,
+) (string, error) {
+	verifier, err := integrity.NewVerifier(a.integrity, a.shasum)
+	if err != nil {
+		return "", fmt.Errorf("%s: %s", a.name, err)
+	}
+	hash := sha512.New()
+
+	var body io.ReadCloser
+	if a.open != nil {
+		body, err = a.open(depURL)
+	} else {
+		body, err = c.openURL(depURL, "")
+	}
 	if err != nil {
 		return "", err
 	}
+	defer body.Close()
 
-	resp, err := http.Get(depURL)
+	outDir, outFileName := path.Split(outFilePath)
+	if err = c.os.MkdirAll(outDir, 0755); err != nil {
+		return "", err
+	}
+	outFile, err := c.os.TempFile(outDir, "."+outFileName+".", 0644)
 	if err != nil {
 		return "", err
 	}
-	defer func() {
-		if rerr := resp.Body.Close(); rerr != nil && err == nil {
-			err = rerr
+	tempFilePath := outFile.Name()
+
+	// Hash and validate the file as it streams to disk
+	validatorReader, validatorWriter := io.Pipe()
+	validated := make(chan error, 1)
+	go func() {
+		var err error
+		if a.validate != nil {
+			err = a.validate(validatorReader)
 		}
+		io.Copy(ioutil.Discard, validatorReader)
+		validated <- err
 	}()
 
-	outFilePath := path.Join(c.config.destination, dep.GetCanonicalName()+".tgz")
-	outFile, err := c.os.Create(outFilePath)
-	defer func() {
-		if ferr := outFile.Close(); ferr != nil && err == nil {
-			err = ferr
+	_, err = io.Copy(io.MultiWriter(outFile, verifier, hash, validatorWriter), body)
+	validatorWriter.CloseWithError(err)
+	validateErr := <-validated
+
+	if err == nil {
+		if verr := verifier.Verify(); verr != nil {
+			err = fmt.Errorf(
+				"Integrity check failed for %s (%s): %s",
+				a.name,
+				depURL,
+				verr,
+			)
+		} else if validateErr != nil {
+			err = fmt.Errorf(
+				"Invalid %s for %s (%s): %s",
+				a.kind,
+				a.name,
+				depURL,
+				validateErr,
+			)
 		}
-	}()
+	}
+	if ferr := outFile.Close(); ferr != nil && err == nil {
+		err = ferr
+	}
+	if err == nil {
+		err = c.os.Rename(tempFilePath, outFilePath)
+	}
+
+	// Never leave a partial or tampered file behind
+	if err != nil {
+		if rerr := c.os.Remove(tempFilePath); rerr != nil {
+			c.printf(
+				"%sFailed to remove %s: %s\n",
+				command.LogErrorPrefix,
+				tempFilePath,
+				rerr,
+			)
+		}
+		return "", err
+	}
 
+	digest := integrity.Digest{Algorithm: "sha512", Sum: hash.Sum(nil)}
+	return digest.String(), nil
+}
+
+// verifyExisting checks a file left in the destination by a previous run
+// against the lockfile's integrity or, failing that, the manifest,
+// returning its sha512 digest
+func (c *fetchCommand) verifyExisting(a artifact, outFilePath string) (string, error) {
+	verifier, err := integrity.NewVerifier(a.integrity, a.shasum)
 	if err != nil {
 		return "", err
 	}
-	_, err = io.Copy(outFile, resp.Body)
+	hash := sha512.New()
+
+	outFile, err := c.os.Open(outFilePath)
 	if err != nil {
 		return "", err
 	}
+	defer outFile.Close()
+
+	tee := io.TeeReader(outFile, io.MultiWriter(verifier, hash))
+	var validateErr error
+	if a.validate != nil {
+		validateErr = a.validate(tee)
+	}
+	if _, err = io.Copy(ioutil.Discard, tee); err != nil {
+		return "", err
+	}
+	if validateErr != nil {
+		return "", validateErr
+	}
+
+	digest := integrity.Digest{Algorithm: "sha512", Sum: hash.Sum(nil)}.String()
+	if !verifier.Empty() {
+		if err = verifier.Verify(); err != nil {
+			return "", err
+		}
+		return digest, nil
+	}
 
-	return outFilePath, err
+	recorded := c.manifest.Get(c.manifestName(outFilePath)).Integrity
+	if recorded == "" {
+		return "", fmt.Errorf("No integrity recorded in the lockfile or manifest")
+	}
+	if recorded != digest {
+		return "", fmt.Errorf("Digest %s doesn't match the manifest's %s", digest, recorded)
+	}
+	return digest, nil
 }
 
-func (c *fetchCommand) fetchDependencies(deps []nodejs.NodeDependency) ([]string, error) {
-	numDeps := len(deps)
+// manifestName returns the manifest key of a file in the destination
+func (c *fetchCommand) manifestName(outFilePath string) string {
+	return strings.TrimPrefix(
+		strings.TrimPrefix(outFilePath, path.Clean(c.config.destination)),
+		"/",
+	)
+}
 
-	var outFilePaths []string
-	for i, dep := range deps {
-		fmt.Printf(
-			"%s(%d/%d) Downloading %s (%s)\n",
+// manifestEntry describes a in the manifest, along with the URL it was
+// fetched from when that isn't the one in the lockfile
+func (c *fetchCommand) manifestEntry(a artifact, fetchedURL string) manifest.Entry {
+	entry := a.entry
+	entry.URL = fetchedURL
+	return entry
+}
+
+// nodeArtifact describes the download of a nodejs package tarball
+func (c *fetchCommand) nodeArtifact(dep nodejs.NodeDependency) artifact {
+	return artifact{
+		name:      dep.GetCanonicalName(),
+		fileName:  dep.GetCanonicalName() + ".tgz",
+		url:       dep.PackageURL,
+		integrity: dep.Integrity,
+		shasum:    dep.Shasum,
+		entry: manifest.Entry{
+			Resolved:    dep.PackageURL,
+			Dev:         dep.Dev,
+			Optional:    dep.Optional,
+			DevOptional: dep.DevOptional,
+			Peer:        dep.Peer,
+		},
+		resolve: func() (string, error) {
+			return c.resolveDependencyURL(dep)
+		},
+		open:     c.openDependency,
+		validate: nodejs.ValidateTarball,
+		kind:     "tarball",
+	}
+}
+
+// fetchDependency downloads the tarball of the nodejs package dep
+func (c *fetchCommand) fetchDependency(dep nodejs.NodeDependency, progress string) fetchResult {
+	return c.fetchArtifact(c.nodeArtifact(dep), progress)
+}
+
+// fetchArtifact downloads a, retrying transient failures, unless a
+// verified copy is already present in the destination. progress prefixes
+// the line reporting what's being done.
+func (c *fetchCommand) fetchArtifact(a artifact, progress string) fetchResult {
+	result := fetchResult{artifact: a}
+	outFilePath := path.Join(c.config.destination, a.fileName)
+
+	if !c.config.force {
+		digest, err := c.verifyExisting(a, outFilePath)
+		if err == nil {
+			entry := c.manifestEntry(a, c.manifest.Get(c.manifestName(outFilePath)).URL)
+			entry.Integrity = digest
+			c.manifest.Set(c.manifestName(outFilePath), entry)
+			c.printf(
+				"%s%s Skipping %s (already fetched)\n",
+				command.LogInfoPrefix,
+				progress,
+				a.name,
+			)
+			result.status = statusSkipped
+			result.outFilePath = outFilePath
+			return result
+		}
+		if !os.IsNotExist(err) {
+			c.printf(
+				"%s%s Repairing %s: %s\n",
+				command.LogInfoPrefix,
+				progress,
+				a.name,
+				err,
+			)
+			result.status = statusRepaired
+		}
+	}
+
+	depURL := a.url
+	if a.resolve != nil {
+		var err error
+		depURL, err = a.resolve()
+		if err != nil {
+			result.err = err
+			return result
+		}
+	}
+	result.url = depURL
+
+	if result.status != statusRepaired {
+		via := ""
+		if depURL != a.url {
+			via = " via " + depURL
+		}
+		c.printf(
+			"%s%s Downloading %s (%s)%s\n",
 			command.LogInfoPrefix,
-			i, numDeps,
-			dep.GetCanonicalName(),
-			dep.PackageURL,
+			progress,
+			a.name,
+			a.url,
+			via,
 		)
-		outFilePath, err := c.fetchDependency(dep)
-		if err != nil {
-			return outFilePaths, err
+	}
+
+	var digest string
+	attempt := func() error {
+		var err error
+		digest, err = c.downloadArtifact(a, depURL, outFilePath)
+		return err
+	}
+	onRetry := func(retry int, delay time.Duration, err error) {
+		c.printf(
+			"%sRetrying %s (%d/%d) in %s: %s\n",
+			command.LogInfoPrefix,
+			a.name,
+			retry, c.retryer.MaxRetries,
+			delay,
+			err,
+		)
+	}
+	result.retries, result.err = c.retryer.Do(attempt, onRetry)
+	if result.err == nil {
+		var fetchedURL string
+		if depURL != a.url {
+			fetchedURL = depURL
 		}
-		outFilePaths = append(outFilePaths, outFilePath)
+		entry := c.manifestEntry(a, fetchedURL)
+		entry.Integrity = digest
+		c.manifest.Set(c.manifestName(outFilePath), entry)
+		result.outFilePath = outFilePath
 	}
+	return result
+}
+
+func (c *fetchCommand) printf(format string, a ...interface{}) {
+	c.printLock.Lock()
+	defer c.printLock.Unlock()
+	fmt.Printf(format, a...)
+}
 
-	return outFilePaths, nil
+// fetchDependencies downloads the tarballs of the nodejs packages deps
+func (c *fetchCommand) fetchDependencies(deps []nodejs.NodeDependency) ([]fetchResult, error) {
+	var artifacts []artifact
+	for _, dep := range deps {
+		artifacts = append(artifacts, c.nodeArtifact(dep))
+	}
+	return c.fetchArtifacts(artifacts)
+}
+
+// lookupArtifacts works out the artifacts of each of the dependencies
+// named by names with lookup, which may fetch index pages, using a pool of
+// --concurrency workers, and returns them in the order of the
+// dependencies. It stops after the first failed lookup unless
+// --keep-going is set, in which case the dependencies whose lookups fail
+// are reported and left out, and their failures kept in lookupErrs.
+func (c *fetchCommand) lookupArtifacts(names []string, lookup func(i int) ([]artifact, error)) ([]artifact, error) {
+	results := make([][]artifact, len(names))
+	errs := make([]error, len(names))
+
+	jobs := make(chan int)
+	stop := make(chan struct{})
+	var stopOnce sync.Once
+	var wg sync.WaitGroup
+	for w := 0; w < c.config.concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				select {
+				case <-stop:
+					continue
+				default:
+				}
+				results[i], errs[i] = lookup(i)
+				if errs[i] == nil {
+					continue
+				}
+				errs[i] = fmt.Errorf("Can't find a file to fetch for %s: %s", names[i], errs[i])
+				c.printf("%s%s\n", command.LogErrorPrefix, errs[i])
+				if !c.config.keepGoing {
+					stopOnce.Do(func() { close(stop) })
+				}
+			}
+		}()
+	}
+
+schedule:
+	for i := range names {
+		select {
+		case jobs <- i:
+		case <-stop:
+			break schedule
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	var artifacts []artifact
+	var failed fetchErrors
+	for i := range names {
+		if errs[i] != nil {
+			failed = append(failed, errs[i])
+			continue
+		}
+		artifacts = append(artifacts, results[i]...)
+	}
+	if len(failed) == 0 {
+		return artifacts, nil
+	}
+	if !c.config.keepGoing {
+		return nil, failed[0]
+	}
+	c.lookupErrs = append(c.lookupErrs, failed...)
+	return artifacts, nil
+}
+
+// fetchArtifacts downloads artifacts using a pool of --concurrency
+// workers, returning the results of every attempted download in the same
+// order as artifacts. It stops scheduling downloads after the first
+// failure unless --keep-going is set.
+func (c *fetchCommand) fetchArtifacts(artifacts []artifact) ([]fetchResult, error) {
+	numArtifacts := len(artifacts)
+	results := make([]*fetchResult, numArtifacts)
+
+	jobs := make(chan int)
+	stop := make(chan struct{})
+	var stopOnce sync.Once
+	var wg sync.WaitGroup
+	var startedLock sync.Mutex
+	started := 0
+
+	for w := 0; w < c.config.concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				select {
+				case <-stop:
+					continue
+				default:
+				}
+				a := artifacts[i]
+
+				startedLock.Lock()
+				started++
+				progress := fmt.Sprintf("(%d/%d)", started, numArtifacts)
+				startedLock.Unlock()
+
+				result := c.fetchArtifact(a, progress)
+				results[i] = &result
+				if result.err != nil {
+					if c.config.keepGoing {
+						c.printf(
+							"%sFailed to fetch %s: %s\n",
+							command.LogErrorPrefix,
+							a.name,
+							result.err,
+						)
+					} else {
+						stopOnce.Do(func() { close(stop) })
+					}
+				}
+			}
+		}()
+	}
+
+schedule:
+	for i := range artifacts {
+		select {
+		case jobs <- i:
+		case <-stop:
+			break schedule
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	var attempted []fetchResult
+	var failed fetchErrors
+	for _, result := range results {
+		if result == nil {
+			continue
+		}
+		attempted = append(attempted, *result)
+		if result.err != nil {
+			failed = append(failed, result.err)
+		}
+	}
+
+	if len(failed) == 0 {
+		return attempted, nil
+	}
+	if !c.config.keepGoing {
+		return attempted, failed[0]
+	}
+	return attempted, failed
+}
+
+// readNodeArtifacts reads the nodejs lockfile in dirPath along with the
+// .npmrc configuring its registries, returning the tarballs to fetch
+func (c *fetchCommand) readNodeArtifacts(dirPath string) ([]artifact, error) {
+	allDeps, err := c.readDependencies(dirPath)
+	if err != nil {
+		return nil, err
+	}
+
+	c.npmrc, err = nodejs.ReadNPMRC(c.os, dirPath)
+	if err != nil {
+		fmt.Printf(
+			"%s%s: %s\n",
+			command.LogErrorPrefix,
+			"Can't read .npmrc",
+			err,
+		)
+		return nil, err
+	}
+
+	// Rewrite rules given as flags take precedence over .npmrc
+	c.rewriter = nodejs.NewRegistryRewriter()
+	c.rewriter.AddNPMRC(c.npmrc)
+	for _, rule := range c.config.rewrites {
+		c.rewriter.AddRule(rule)
+	}
+	c.gitHosts = nodejs.NewGitHostResolver()
+	for _, rule := range c.config.gitHosts {
+		c.gitHosts.AddRule(rule)
+	}
+
+	allDeps, err = c.prepareLocalDependencies(allDeps)
+	if err != nil {
+		fmt.Printf(
+			"%s%s: %s\n",
+			command.LogErrorPrefix,
+			"Can't read local dependency",
+			err,
+		)
+		return nil, err
+	}
+
+	allDeps = c.omitDependencies(allDeps)
+
+	var artifacts []artifact
+	for _, dep := range c.filterDependencies(allDeps) {
+		artifacts = append(artifacts, c.nodeArtifact(dep))
+	}
+	return artifacts, nil
 }
 
 func (c *fetchCommand) Run(args []string) int {
@@ -298,57 +1139,152 @@ func (c *fetchCommand) Run(args []string) int {
 	} else {
 		dirPath = cmdConfig.source
 	}
+	c.projectDir = dirPath
 
-	npmShrinkwrap, err := c.readDependencies(dirPath)
-	if err != nil {
-		return 1
+	for _, filterFile := range cmdConfig.filterFiles {
+		contents, err := c.os.ReadFile(filterFile)
+		if err == nil {
+			err = cmdConfig.filter.AddRules(contents, filterFile)
+		}
+		if err != nil {
+			fmt.Printf(
+				"%sCan't read filter file %s: %s\n",
+				command.LogErrorPrefix,
+				filterFile,
+				err,
+			)
+			return 1
+		}
 	}
 
-	c.npmShrinkwrap = npmShrinkwrap
-
-	allDeps := nodejs.CollectDependencies(npmShrinkwrap)
-	var deps []nodejs.NodeDependency
+	c.client = download.NewHTTPClient(cmdConfig.connectTimeout, cmdConfig.readTimeout)
+	c.retryer = &download.Retryer{
+		MaxRetries: cmdConfig.retries,
+		BaseDelay:  cmdConfig.retryDelay,
+		MaxDelay:   maxRetryDelay,
+	}
 
-	// Filter deps according to whitelist if present
-	if cmdConfig.whitelistStr == "" {
-		deps = allDeps
-	} else {
-		for _, dep := range allDeps {
-			if cmdConfig.whitelist.MatchString(dep.Name) {
-				deps = append(deps, dep)
-			}
-		}
+	var artifacts []artifact
+	switch cmdConfig.platform {
+	case "golang":
+		artifacts, err = c.readGolangArtifacts(dirPath)
+	case "python":
+		artifacts, err = c.readPythonArtifacts(dirPath)
+	case "ruby":
+		artifacts, err = c.readRubyArtifacts(dirPath)
+	case "rust":
+		artifacts, err = c.readRustArtifacts(dirPath)
+	default:
+		artifacts, err = c.readNodeArtifacts(dirPath)
+	}
+	if err != nil {
+		return 1
 	}
 
 	fmt.Printf(
 		"%sFound %d matching dependencies.\n",
 		command.LogSuccessPrefix,
-		len(deps),
+		len(artifacts),
 	)
 
-	fetchedDeps, err := c.fetchDependencies(deps)
+	c.manifest, err = manifest.Read(c.os, cmdConfig.destination)
 	if err != nil {
 		fmt.Printf(
-			"%s%s: %s",
+			"%sCan't read the manifest in %s, verifying against the lockfile only: %s\n",
 			command.LogErrorPrefix,
-			"Error fetching dependencies",
+			cmdConfig.destination,
 			err,
 		)
-		return 1
+		c.manifest = manifest.New()
+	}
+
+	results, fetchErr := c.fetchArtifacts(artifacts)
+	if len(c.lookupErrs) > 0 {
+		failed := append(fetchErrors{}, c.lookupErrs...)
+		if fetchErrs, ok := fetchErr.(fetchErrors); ok {
+			failed = append(failed, fetchErrs...)
+		}
+		fetchErr = failed
+	}
+
+	if cmdConfig.platform == "golang" {
+		if err = c.writeModuleLists(results); err != nil {
+			fmt.Printf(
+				"%sFailed to write the module version lists in %s: %s\n",
+				command.LogErrorPrefix,
+				cmdConfig.destination,
+				err,
+			)
+		}
 	}
 
-	for _, dep := range fetchedDeps {
+	if err = c.manifest.Write(c.os, cmdConfig.destination); err != nil {
 		fmt.Printf(
-			"%sFetched: %s\n",
+			"%sFailed to write the manifest in %s: %s\n",
+			command.LogErrorPrefix,
+			cmdConfig.destination,
+			err,
+		)
+	}
+
+	counts := make(map[fetchStatus]int)
+	for _, result := range results {
+		if result.err != nil {
+			continue
+		}
+		counts[result.status]++
+		if result.status == statusSkipped {
+			continue
+		}
+		from := result.artifact.url
+		if result.url != "" && result.url != from {
+			from += " via " + result.url
+		}
+		fmt.Printf(
+			"%sFetched: %s from %s\n",
 			command.LogInfoPrefix,
-			dep,
+			result.outFilePath,
+			from,
 		)
 	}
 
+	totalRetries := 0
+	for _, result := range results {
+		if result.retries == 0 {
+			continue
+		}
+		totalRetries += result.retries
+		fmt.Printf(
+			"%sRetried %s %d times\n",
+			command.LogInfoPrefix,
+			result.artifact.name,
+			result.retries,
+		)
+	}
+	if totalRetries > 0 {
+		fmt.Printf(
+			"%sRetried %d downloads in total.\n",
+			command.LogInfoPrefix,
+			totalRetries,
+		)
+	}
+
+	if fetchErr != nil {
+		fmt.Printf(
+			"%s%s: %s\n",
+			command.LogErrorPrefix,
+			"Error fetching dependencies",
+			fetchErr,
+		)
+		return 1
+	}
+
 	fmt.Printf(
-		"%sFetched %d dependencies.\n",
+		"%sFetched %d dependencies, skipped %d already present, repaired %d.\n",
 		command.LogSuccessPrefix,
-		len(deps),
+		counts[statusFetched],
+		counts[statusSkipped],
+		counts[statusRepaired],
 	)
 
 	return 0
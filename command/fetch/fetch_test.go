@@ -1,8 +1,26 @@
 package fetch
 
 import (
+	"archive/tar"
+	"bitbucket.org/bosgood/dep-get/lib/download"
+	"bitbucket.org/bosgood/dep-get/lib/filter"
 	"bitbucket.org/bosgood/dep-get/lib/fs"
+	"bitbucket.org/bosgood/dep-get/lib/manifest"
+	"bitbucket.org/bosgood/dep-get/nodejs"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha512"
+	"encoding/base64"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 type invalidAssertionError struct {
@@ -63,3 +81,730 @@ func TestFetchCommandLoadFile(t *testing.T) {
 		t.Errorf("Err: non-zero return value for no args")
 	}
 }
+
+func testTarball(t *testing.T, name string) []byte {
+	packageJSON := []byte(`{"name": "` + name + `"}`)
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	err := tw.WriteHeader(&tar.Header{
+		Name: "package/package.json",
+		Mode: 0644,
+		Size: int64(len(packageJSON)),
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err = tw.Write(packageJSON); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err = tw.Close(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err = gz.Close(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	return buf.Bytes()
+}
+
+func assertNoTempFiles(t *testing.T, dir string) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	for _, file := range files {
+		if strings.HasPrefix(file.Name(), ".") && file.Name() != manifest.FileName {
+			t.Errorf("Expected temporary file %s to be removed", file.Name())
+		}
+	}
+}
+
+func newTestFetchCommand(t *testing.T) (*fetchCommand, string) {
+	dir, err := ioutil.TempDir("", "dep-get-fetch")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	cmd := &fetchCommand{
+		os: &fs.OSFS{},
+		config: fetchCommandFlags{
+			destination: dir,
+			concurrency: 1,
+			filter:      filter.New(nodejs.ParseFilterRange),
+		},
+		client: download.NewHTTPClient(time.Second, time.Second),
+		retryer: &download.Retryer{
+			MaxRetries: 2,
+			Sleep:      func(time.Duration) {},
+		},
+		manifest: manifest.New(),
+		npmrc:    nodejs.NewNPMRC(),
+		rewriter: nodejs.NewRegistryRewriter(),
+		gitHosts: nodejs.NewGitHostResolver(),
+	}
+	return cmd, dir
+}
+
+func TestFetchDependencyIntegrity(t *testing.T) {
+	tarball := testTarball(t, "ee-first")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(tarball)
+	}))
+	defer server.Close()
+
+	cmd, dir := newTestFetchCommand(t)
+	defer os.RemoveAll(dir)
+
+	sum := sha512.Sum512(tarball)
+	dep := nodejs.NodeDependency{
+		Name:       "ee-first",
+		Version:    "1.1.1",
+		PackageURL: server.URL + "/ee-first/-/ee-first-1.1.1.tgz",
+		Integrity:  "sha512-" + base64.StdEncoding.EncodeToString(sum[:]),
+	}
+	result := cmd.fetchDependency(dep, "(1/1)")
+	if result.err != nil {
+		t.Fatalf("err: %s", result.err)
+	}
+	contents, err := ioutil.ReadFile(result.outFilePath)
+	if err != nil || string(contents) != string(tarball) {
+		t.Errorf("Expected tarball to be written to %s", result.outFilePath)
+	}
+	// Fetched files are served and installed by other users
+	info, err := os.Stat(result.outFilePath)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if info.Mode().Perm() != 0644 {
+		t.Errorf("Expected mode 0644, got %o", info.Mode().Perm())
+	}
+}
+
+func TestFetchDependencyIntegrityMismatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("tampered"))
+	}))
+	defer server.Close()
+
+	cmd, dir := newTestFetchCommand(t)
+	defer os.RemoveAll(dir)
+
+	dep := nodejs.NodeDependency{
+		Name:       "ee-first",
+		Version:    "1.1.1",
+		PackageURL: server.URL + "/ee-first/-/ee-first-1.1.1.tgz",
+		Shasum:     "590c61156b0ae2f4f0255732a158b266bc56b21d",
+	}
+	err := cmd.fetchDependency(dep, "(1/1)").err
+	if err == nil {
+		t.Fatalf("Expected an integrity error")
+	}
+	if !strings.Contains(err.Error(), "ee-first@1.1.1") {
+		t.Errorf("Expected error to name the package: %s", err)
+	}
+	if _, err = os.Stat(path.Join(dir, "ee-first@1.1.1.tgz")); !os.IsNotExist(err) {
+		t.Errorf("Expected partial tarball to be removed")
+	}
+	assertNoTempFiles(t, dir)
+}
+
+func newTestDependencies(serverURL string, names ...string) []nodejs.NodeDependency {
+	var deps []nodejs.NodeDependency
+	for _, name := range names {
+		dep := nodejs.NodeDependency{
+			Name:       name,
+			Version:    "1.0.0",
+			PackageURL: serverURL + "/" + name + "/-/" + name + "-1.0.0.tgz",
+		}
+		// Packages named bad never match their integrity
+		if strings.HasPrefix(name, "bad") {
+			dep.Shasum = "590c61156b0ae2f4f0255732a158b266bc56b21d"
+		}
+		deps = append(deps, dep)
+	}
+	return deps
+}
+
+func TestFetchDependenciesConcurrentOrder(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(testTarball(t, r.URL.Path))
+	}))
+	defer server.Close()
+
+	cmd, dir := newTestFetchCommand(t)
+	defer os.RemoveAll(dir)
+	cmd.config.concurrency = 4
+
+	names := []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j"}
+	results, err := cmd.fetchDependencies(newTestDependencies(server.URL, names...))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(results) != len(names) {
+		t.Fatalf("Expected %d results, got %d", len(names), len(results))
+	}
+	for i, name := range names {
+		if path.Base(results[i].outFilePath) != name+"@1.0.0.tgz" {
+			t.Errorf("Expected %s at %d, got %s", name, i, results[i].outFilePath)
+		}
+	}
+}
+
+func TestFetchDependenciesStopsOnError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(testTarball(t, r.URL.Path))
+	}))
+	defer server.Close()
+
+	cmd, dir := newTestFetchCommand(t)
+	defer os.RemoveAll(dir)
+
+	results, err := cmd.fetchDependencies(newTestDependencies(server.URL, "a", "bad", "c"))
+	if err == nil {
+		t.Fatalf("Expected an error")
+	}
+	if _, ok := err.(fetchErrors); ok {
+		t.Errorf("Expected only the first error without --keep-going")
+	}
+	if len(results) != 2 {
+		t.Errorf("Expected downloads to stop after the failure, got %v", results)
+	}
+}
+
+func TestFetchDependenciesKeepGoing(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(testTarball(t, r.URL.Path))
+	}))
+	defer server.Close()
+
+	cmd, dir := newTestFetchCommand(t)
+	defer os.RemoveAll(dir)
+	cmd.config.concurrency = 2
+	cmd.config.keepGoing = true
+
+	results, err := cmd.fetchDependencies(newTestDependencies(server.URL, "a", "bad1", "c", "bad2"))
+	errs, ok := err.(fetchErrors)
+	if !ok || len(errs) != 2 {
+		t.Fatalf("Expected two collected errors, got %v", err)
+	}
+	if !strings.Contains(errs[0].Error(), "bad1@1.0.0") || !strings.Contains(errs[1].Error(), "bad2@1.0.0") {
+		t.Errorf("Expected errors in dependency order: %s", err)
+	}
+	if len(results) != 4 || results[0].err != nil || results[2].err != nil {
+		t.Errorf("Expected the other dependencies to be fetched, got %v", results)
+	}
+}
+
+func TestFetchDependencyRetries(t *testing.T) {
+	var lock sync.Mutex
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		requests++
+		if requests == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if requests == 2 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write(testTarball(t, r.URL.Path))
+	}))
+	defer server.Close()
+
+	cmd, dir := newTestFetchCommand(t)
+	defer os.RemoveAll(dir)
+
+	result := cmd.fetchDependency(newTestDependencies(server.URL, "a")[0], "(1/1)")
+	if result.err != nil {
+		t.Fatalf("err: %s", result.err)
+	}
+	if result.retries != 2 {
+		t.Errorf("Expected two retries, got %d", result.retries)
+	}
+}
+
+func TestFetchDependencyRetriesExhausted(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	cmd, dir := newTestFetchCommand(t)
+	defer os.RemoveAll(dir)
+
+	result := cmd.fetchDependency(newTestDependencies(server.URL, "a")[0], "(1/1)")
+	if _, ok := result.err.(*download.StatusError); !ok {
+		t.Fatalf("Expected a StatusError, got %v", result.err)
+	}
+	if result.retries != 2 {
+		t.Errorf("Expected two retries, got %d", result.retries)
+	}
+}
+
+func TestFetchDependencyNotFound(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	cmd, dir := newTestFetchCommand(t)
+	defer os.RemoveAll(dir)
+
+	dep := newTestDependencies(server.URL, "a")[0]
+	result := cmd.fetchDependency(dep, "(1/1)")
+	if result.err == nil {
+		t.Fatalf("Expected an error for a 404 response")
+	}
+	if !strings.Contains(result.err.Error(), "404") || !strings.Contains(result.err.Error(), dep.PackageURL) {
+		t.Errorf("Expected error to name the status code and URL: %s", result.err)
+	}
+	if result.retries != 0 {
+		t.Errorf("Expected a 404 not to be retried")
+	}
+	if _, err := os.Stat(path.Join(dir, "a@1.0.0.tgz")); !os.IsNotExist(err) {
+		t.Errorf("Expected no tarball to be written")
+	}
+}
+
+func TestFetchDependencyInvalidTarball(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<html>Sign in</html>"))
+	}))
+	defer server.Close()
+
+	cmd, dir := newTestFetchCommand(t)
+	defer os.RemoveAll(dir)
+
+	result := cmd.fetchDependency(newTestDependencies(server.URL, "a")[0], "(1/1)")
+	if result.err == nil || !strings.Contains(result.err.Error(), "Invalid tarball") {
+		t.Fatalf("Expected an invalid tarball error, got %v", result.err)
+	}
+	if _, err := os.Stat(path.Join(dir, "a@1.0.0.tgz")); !os.IsNotExist(err) {
+		t.Errorf("Expected the invalid tarball to be removed")
+	}
+	assertNoTempFiles(t, dir)
+}
+
+func TestFetchDependencyScoped(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(testTarball(t, r.URL.Path))
+	}))
+	defer server.Close()
+
+	cmd, dir := newTestFetchCommand(t)
+	defer os.RemoveAll(dir)
+
+	result := cmd.fetchDependency(newTestDependencies(server.URL, "@types/node")[0], "(1/1)")
+	if result.err != nil {
+		t.Fatalf("err: %s", result.err)
+	}
+	if result.outFilePath != path.Join(dir, "@types", "node@1.0.0.tgz") {
+		t.Errorf("Unexpected tarball path: %s", result.outFilePath)
+	}
+	if _, err := os.Stat(result.outFilePath); err != nil {
+		t.Errorf("err: %s", err)
+	}
+	assertNoTempFiles(t, path.Join(dir, "@types"))
+}
+
+func TestFetchDependenciesIncremental(t *testing.T) {
+	var lock sync.Mutex
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		requests++
+		lock.Unlock()
+		w.Write(testTarball(t, r.URL.Path))
+	}))
+	defer server.Close()
+
+	cmd, dir := newTestFetchCommand(t)
+	defer os.RemoveAll(dir)
+
+	deps := newTestDependencies(server.URL, "a", "b", "c")
+	if _, err := cmd.fetchDependencies(deps); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := cmd.manifest.Write(cmd.os, cmd.config.destination); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Corrupt one tarball and start over from the manifest on disk
+	err := ioutil.WriteFile(path.Join(dir, "b@1.0.0.tgz"), []byte("truncated"), 0644)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	cmd.manifest, err = manifest.Read(cmd.os, cmd.config.destination)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(cmd.manifest.Files) != 3 {
+		t.Fatalf("Expected three manifest entries, got %v", cmd.manifest.Files)
+	}
+
+	results, err := cmd.fetchDependencies(deps)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	expected := []fetchStatus{statusSkipped, statusRepaired, statusSkipped}
+	for i, status := range expected {
+		if results[i].status != status {
+			t.Errorf("Expected status %d for %s, got %d", status, results[i].artifact.name, results[i].status)
+		}
+	}
+	if requests != 4 {
+		t.Errorf("Expected only the corrupted tarball to be downloaded again, got %d requests", requests)
+	}
+
+	// Forcing downloads everything again
+	cmd.config.force = true
+	if _, err = cmd.fetchDependencies(deps); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if requests != 7 {
+		t.Errorf("Expected every tarball to be downloaded again, got %d requests", requests)
+	}
+}
+
+func TestFetchDependencySkipsVerifiedByIntegrity(t *testing.T) {
+	cmd, dir := newTestFetchCommand(t)
+	defer os.RemoveAll(dir)
+
+	tarball := testTarball(t, "ee-first")
+	err := ioutil.WriteFile(path.Join(dir, "ee-first@1.1.1.tgz"), tarball, 0644)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	sum := sha512.Sum512(tarball)
+	dep := nodejs.NodeDependency{
+		Name:       "ee-first",
+		Version:    "1.1.1",
+		PackageURL: "http://127.0.0.1:1/ee-first/-/ee-first-1.1.1.tgz",
+		Integrity:  "sha512-" + base64.StdEncoding.EncodeToString(sum[:]),
+	}
+	result := cmd.fetchDependency(dep, "(1/1)")
+	if result.err != nil {
+		t.Fatalf("err: %s", result.err)
+	}
+	if result.status != statusSkipped {
+		t.Errorf("Expected tarball matching the lockfile integrity to be skipped")
+	}
+}
+
+func TestFetchDependencyAuthorization(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer s3cr3t" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write(testTarball(t, r.URL.Path))
+	}))
+	defer server.Close()
+
+	cmd, dir := newTestFetchCommand(t)
+	defer os.RemoveAll(dir)
+
+	dep := newTestDependencies(server.URL, "@ourco/private")[0]
+	result := cmd.fetchDependency(dep, "(1/1)")
+	if result.err == nil || !strings.Contains(result.err.Error(), "401") {
+		t.Fatalf("Expected a 401 without credentials, got %v", result.err)
+	}
+
+	serverURL := strings.TrimPrefix(server.URL, "http:")
+	cmd.npmrc = nodejs.ParseNPMRC([]byte(serverURL + "/:_authToken=s3cr3t\n"))
+	result = cmd.fetchDependency(dep, "(1/1)")
+	if result.err != nil {
+		t.Fatalf("err: %s", result.err)
+	}
+}
+
+func TestFetchDependencyRegistryRewrite(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(testTarball(t, r.URL.Path))
+	}))
+	defer server.Close()
+
+	cmd, dir := newTestFetchCommand(t)
+	defer os.RemoveAll(dir)
+	cmd.rewriter.AddRule(nodejs.DefaultRegistryURL + " -> " + server.URL + "/mirror/")
+
+	dep := nodejs.NodeDependency{
+		Name:       "ee-first",
+		Version:    "1.1.1",
+		PackageURL: "https://registry.npmjs.org/ee-first/-/ee-first-1.1.1.tgz",
+	}
+	result := cmd.fetchDependency(dep, "(1/1)")
+	if result.err != nil {
+		t.Fatalf("err: %s", result.err)
+	}
+	if result.url != server.URL+"/mirror/ee-first/-/ee-first-1.1.1.tgz" {
+		t.Errorf("Expected download from the mirror, got %s", result.url)
+	}
+
+	entry := cmd.manifest.Get("ee-first@1.1.1.tgz")
+	if entry.Resolved != dep.PackageURL || entry.URL != result.url {
+		t.Errorf("Expected the manifest to record both URLs, got %+v", entry)
+	}
+}
+
+func TestResolveDependencyURL(t *testing.T) {
+	cmd, dir := newTestFetchCommand(t)
+	defer os.RemoveAll(dir)
+	cmd.gitHosts.AddRule("gitlab=https://gitlab.example.com/")
+
+	tests := []struct {
+		url      string
+		expected string
+	}{
+		{
+			"https://registry.npmjs.org/left-pad/-/left-pad-1.3.0.tgz",
+			"https://registry.npmjs.org/left-pad/-/left-pad-1.3.0.tgz",
+		},
+		{
+			"git+ssh://git@github.com/owner/repo.git#abc123",
+			"https://codeload.github.com/owner/repo/tar.gz/abc123",
+		},
+		{
+			"git+https://gitlab.example.com/group/repo.git#abc123",
+			"https://gitlab.example.com/group/repo/-/archive/abc123/repo-abc123.tar.gz",
+		},
+		{
+			"git://bitbucket.org/owner/repo.git#abc123",
+			"https://bitbucket.org/owner/repo/get/abc123.tar.gz",
+		},
+	}
+	for _, test := range tests {
+		depURL, err := cmd.resolveDependencyURL(nodejs.NodeDependency{PackageURL: test.url})
+		if err != nil {
+			t.Errorf("%s: %s", test.url, err)
+			continue
+		}
+		if depURL != test.expected {
+			t.Errorf("%s: expected %s, got %s", test.url, test.expected, depURL)
+		}
+	}
+
+	// Hosts without a tarball endpoint are cloned
+	gitURL := "git+ssh://git@git.example.com/owner/repo.git#abc123"
+	if depURL, err := cmd.resolveDependencyURL(nodejs.NodeDependency{PackageURL: gitURL}); err != nil || depURL != gitURL {
+		t.Errorf("Expected %s unchanged, got %s (%v)", gitURL, depURL, err)
+	}
+
+	if _, err := cmd.resolveDependencyURL(nodejs.NodeDependency{PackageURL: "file:../local"}); err == nil {
+		t.Errorf("Expected an error for a file: URL")
+	}
+}
+
+func TestFetchDependencyGitClone(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	repoDir, err := ioutil.TempDir("", "dep-get-git")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(repoDir)
+
+	ioutil.WriteFile(path.Join(repoDir, "package.json"), []byte(`{"name": "local-git"}`), 0644)
+	for _, args := range [][]string{
+		{"init", "--quiet"},
+		{"add", "-A"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--quiet", "-m", "init"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repoDir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %s: %s: %s", args[0], err, out)
+		}
+	}
+
+	cmd, dir := newTestFetchCommand(t)
+	defer os.RemoveAll(dir)
+
+	dep := nodejs.NodeDependency{
+		Name:       "local-git",
+		Version:    "1.0.0",
+		PackageURL: "git+file://" + repoDir + "#HEAD",
+	}
+	result := cmd.fetchDependency(dep, "(1/1)")
+	if result.err != nil {
+		t.Fatalf("err: %s", result.err)
+	}
+	if _, err := os.Stat(path.Join(dir, "local-git@1.0.0.tgz")); err != nil {
+		t.Errorf("Expected the packed repository to be written: %s", err)
+	}
+}
+
+func TestFetchDependencyLocal(t *testing.T) {
+	cmd, dir := newTestFetchCommand(t)
+	defer os.RemoveAll(dir)
+
+	projectDir, err := ioutil.TempDir("", "dep-get-project")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(projectDir)
+	cmd.projectDir = projectDir
+
+	os.MkdirAll(path.Join(projectDir, "packages/shared"), 0755)
+	ioutil.WriteFile(path.Join(projectDir, "packages/shared/package.json"), []byte(`{"name": "shared", "version": "2.0.0"}`), 0644)
+	ioutil.WriteFile(path.Join(projectDir, "vendored-0.1.0.tgz"), testTarball(t, "vendored"), 0644)
+
+	deps, err := cmd.prepareLocalDependencies([]nodejs.NodeDependency{
+		{Name: "shared", LocalPath: "packages/shared"},
+		{Name: "vendored", Version: "0.1.0", LocalPath: "vendored-0.1.0.tgz"},
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if deps[0].Version != "2.0.0" {
+		t.Errorf("Expected the version from package.json, got %q", deps[0].Version)
+	}
+
+	for _, dep := range deps {
+		result := cmd.fetchDependency(dep, "(1/2)")
+		if result.err != nil {
+			t.Fatalf("%s: %s", dep.Name, result.err)
+		}
+		if _, err := os.Stat(result.outFilePath); err != nil {
+			t.Errorf("Expected %s to be written: %s", result.outFilePath, err)
+		}
+	}
+}
+
+func TestPrepareLocalDependenciesSkip(t *testing.T) {
+	cmd, dir := newTestFetchCommand(t)
+	defer os.RemoveAll(dir)
+	cmd.config.localDeps = localDepsSkip
+
+	deps, err := cmd.prepareLocalDependencies([]nodejs.NodeDependency{
+		{Name: "shared", LocalPath: "../shared"},
+		{Name: "ee-first", Version: "1.1.1", PackageURL: "https://registry.npmjs.org/ee-first/-/ee-first-1.1.1.tgz"},
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(deps) != 1 || deps[0].Name != "ee-first" {
+		t.Errorf("Expected only ee-first, got %+v", deps)
+	}
+}
+
+func TestOmitDependencies(t *testing.T) {
+	cmd, dir := newTestFetchCommand(t)
+	defer os.RemoveAll(dir)
+	cmd.config.omit = nodejs.Omit{Dev: true}
+
+	deps := cmd.omitDependencies([]nodejs.NodeDependency{
+		{Name: "bundled", Version: "1.0.0", Bundled: true},
+		{Name: "dev", Version: "1.0.0", Dev: true},
+		{Name: "optional", Version: "1.0.0", Optional: true},
+		{Name: "prod", Version: "1.0.0"},
+	})
+	if len(deps) != 2 || deps[0].Name != "optional" || deps[1].Name != "prod" {
+		t.Errorf("Expected optional and prod, got %+v", deps)
+	}
+}
+
+func TestReadDependenciesYarnDevFlags(t *testing.T) {
+	cmd, dir := newTestFetchCommand(t)
+	defer os.RemoveAll(dir)
+	cmd.config.omit = nodejs.Omit{Dev: true}
+
+	yarnLock := `jest@^26.0.0:
+  version "26.6.3"
+  resolved "https://registry.yarnpkg.com/jest/-/jest-26.6.3.tgz"
+
+on-finished@^2.3.0:
+  version "2.3.0"
+  resolved "https://registry.yarnpkg.com/on-finished/-/on-finished-2.3.0.tgz"
+`
+	packageJSON := `{
+  "name": "my-app",
+  "dependencies": {"on-finished": "^2.3.0"},
+  "devDependencies": {"jest": "^26.0.0"}
+}`
+	if err := ioutil.WriteFile(path.Join(dir, nodejs.YarnLockfileName), []byte(yarnLock), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := ioutil.WriteFile(path.Join(dir, "package.json"), []byte(packageJSON), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	deps, err := cmd.readDependencies(dir)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	deps = cmd.omitDependencies(deps)
+	if len(deps) != 1 || deps[0].Name != "on-finished" {
+		t.Errorf("Expected package.json's dev dependencies to be omitted, got %+v", deps)
+	}
+}
+
+func TestFilterDependencies(t *testing.T) {
+	cmdConfig, _, err := getConfig([]string{
+		"--platform", "nodejs",
+		"--destination", "out",
+		"--include", "scope:ourco",
+		"--include", "host:*.internal",
+		"--exclude", "version:<1.0.0",
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	cmd, dir := newTestFetchCommand(t)
+	defer os.RemoveAll(dir)
+	cmd.config.filter = cmdConfig.filter
+
+	deps := cmd.filterDependencies([]nodejs.NodeDependency{
+		{Name: "@ourco/api", Version: "2.0.0", PackageURL: "https://registry.npmjs.org/@ourco/api/-/api-2.0.0.tgz"},
+		{Name: "@ourco/legacy", Version: "0.9.0", PackageURL: "https://registry.npmjs.org/@ourco/legacy/-/legacy-0.9.0.tgz"},
+		{Name: "mirrored", Version: "1.0.0", PackageURL: "https://npm.internal/mirrored/-/mirrored-1.0.0.tgz"},
+		{Name: "left-pad", Version: "1.3.0", PackageURL: "https://registry.npmjs.org/left-pad/-/left-pad-1.3.0.tgz"},
+	})
+	if len(deps) != 2 || deps[0].Name != "@ourco/api" || deps[1].Name != "mirrored" {
+		t.Errorf("Expected @ourco/api and mirrored, got %+v", deps)
+	}
+}
+
+func TestGetConfigFilterVersionRanges(t *testing.T) {
+	// Only nodejs rules take version ranges
+	for _, platform := range []string{"python", "golang", "ruby", "rust"} {
+		args := []string{"--platform", platform, "--destination", "out", "--exclude", "version:<1.0.0"}
+		if _, _, err := getConfig(args); err == nil {
+			t.Errorf("Expected %s to refuse a version range", platform)
+		}
+	}
+
+	cmdConfig, _, err := getConfig([]string{
+		"--platform", "python",
+		"--destination", "out",
+		"--exclude", "version:*rc*",
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if excluded, _ := cmdConfig.filter.Excludes(filter.Dependency{Name: "django", Version: "5.0rc1"}); !excluded {
+		t.Errorf("Expected the release candidate to be excluded")
+	}
+	if excluded, _ := cmdConfig.filter.Excludes(filter.Dependency{Name: "django", Version: "4.2.7"}); excluded {
+		t.Errorf("Expected the release to be kept")
+	}
+}
+
+func TestGetConfigMalformedFilter(t *testing.T) {
+	for _, args := range [][]string{
+		{"--exclude", "name:/(/"},
+		{"--include", "version:^a.b"},
+		{"--whitelist", "("},
+	} {
+		args = append([]string{"--platform", "nodejs", "--destination", "out"}, args...)
+		if _, _, err := getConfig(args); err == nil {
+			t.Errorf("Expected an error for %v", args)
+		}
+	}
+}
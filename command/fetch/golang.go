@@ -0,0 +1,183 @@
+package fetch
+
+import (
+	"bitbucket.org/bosgood/dep-get/command"
+	"bitbucket.org/bosgood/dep-get/golang"
+	"bitbucket.org/bosgood/dep-get/lib/filter"
+	"bitbucket.org/bosgood/dep-get/lib/manifest"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// goProxyURL returns the GOPROXY endpoint to download modules from:
+// --goproxy, or else the first proxy the GOPROXY environment variable
+// lists, falling back to proxy.golang.org
+func (c *fetchCommand) goProxyURL() string {
+	if c.config.goproxy != "" {
+		return strings.TrimSuffix(c.config.goproxy, "/")
+	}
+	return golang.ProxyURL(os.Getenv("GOPROXY"))
+}
+
+// goModuleArtifacts describes the downloads of the files a GOPROXY
+// serves for mod: its .info and go.mod files and, when go.sum has its
+// hash, its zip. Each goes where a GOPROXY serves it from, so the
+// destination can be served as one.
+func (c *fetchCommand) goModuleArtifacts(mod golang.GoModule, proxyURL string) ([]artifact, error) {
+	files := []struct {
+		extension string
+		kind      string
+		validate  func(io.Reader) error
+	}{
+		{".info", "module info", mod.ValidateInfo},
+		{".mod", "go.mod file", mod.ValidateGoMod},
+		{".zip", "module zip", mod.ValidateZip},
+	}
+
+	var artifacts []artifact
+	for _, file := range files {
+		if file.extension == ".zip" && mod.Hash == "" {
+			continue
+		}
+		fileName, err := golang.ProxyFilePath(mod.Path, mod.Version, file.extension)
+		if err != nil {
+			return nil, err
+		}
+		fileURL := proxyURL + "/" + fileName
+		artifacts = append(artifacts, artifact{
+			name:     mod.GetCanonicalName() + file.extension,
+			fileName: fileName,
+			url:      fileURL,
+			entry:    manifest.Entry{Resolved: fileURL},
+			validate: file.validate,
+			kind:     file.kind,
+		})
+	}
+	return artifacts, nil
+}
+
+// readGolangArtifacts reads the go.mod and go.sum files in dirPath,
+// returning the module files to fetch
+func (c *fetchCommand) readGolangArtifacts(dirPath string) ([]artifact, error) {
+	file, err := golang.ReadDependencies(c.os, dirPath)
+	if err != nil {
+		fmt.Printf(
+			"%s%s: %s\n",
+			command.LogErrorPrefix,
+			"Can't read the dependencies file",
+			err,
+		)
+		return nil, err
+	}
+
+	fmt.Printf(
+		"%sRead dependencies file: %s\n",
+		command.LogInfoPrefix,
+		file.Path,
+	)
+
+	for _, replace := range file.LocalReplaces {
+		fmt.Printf(
+			"%sSkipping local replacement %s => %s\n",
+			command.LogInfoPrefix,
+			replace.OldPath,
+			replace.NewPath,
+		)
+	}
+
+	proxyURL := c.goProxyURL()
+	var artifacts []artifact
+	for _, mod := range file.Modules {
+		excluded, reason := c.config.filter.Excludes(filter.Dependency{
+			Name:    mod.Path,
+			Version: mod.Version,
+			Host:    filter.URLHost(proxyURL),
+		})
+		if excluded {
+			if c.config.verbose {
+				fmt.Printf(
+					"%sExcluding %s: %s\n",
+					command.LogInfoPrefix,
+					mod.GetCanonicalName(),
+					reason,
+				)
+			}
+			continue
+		}
+
+		modArtifacts, err := c.goModuleArtifacts(mod, proxyURL)
+		if err != nil {
+			fmt.Printf(
+				"%sCan't fetch %s: %s\n",
+				command.LogErrorPrefix,
+				mod.GetCanonicalName(),
+				err,
+			)
+			return nil, err
+		}
+		artifacts = append(artifacts, modArtifacts...)
+	}
+	return artifacts, nil
+}
+
+// writeModuleLists adds the module versions fetched to the @v/list files
+// a GOPROXY serves, keeping those listed by earlier runs
+func (c *fetchCommand) writeModuleLists(results []fetchResult) error {
+	versions := make(map[string][]string)
+	for _, result := range results {
+		if result.err != nil {
+			continue
+		}
+		modPath, version, extension, ok := golang.ParseProxyFilePath(result.artifact.fileName)
+		if !ok || extension != ".mod" {
+			continue
+		}
+		listPath, err := golang.ListFilePath(modPath)
+		if err != nil {
+			return err
+		}
+		versions[listPath] = append(versions[listPath], version)
+	}
+
+	for listPath, fetched := range versions {
+		listFilePath := path.Join(c.config.destination, listPath)
+		listed := make(map[string]bool)
+		if contents, err := c.os.ReadFile(listFilePath); err == nil {
+			for _, version := range strings.Fields(string(contents)) {
+				listed[version] = true
+			}
+		}
+		for _, version := range fetched {
+			listed[version] = true
+		}
+		var sorted []string
+		for version := range listed {
+			sorted = append(sorted, version)
+		}
+		sort.Strings(sorted)
+
+		// Write beside the list and rename so a GOPROXY client never
+		// reads a partial list
+		listFile, err := c.os.TempFile(path.Dir(listFilePath), ".list.", 0644)
+		if err != nil {
+			return err
+		}
+		tempFilePath := listFile.Name()
+		_, err = listFile.Write([]byte(strings.Join(sorted, "\n") + "\n"))
+		if cerr := listFile.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+		if err == nil {
+			err = c.os.Rename(tempFilePath, listFilePath)
+		}
+		if err != nil {
+			c.os.Remove(tempFilePath)
+			return err
+		}
+	}
+	return nil
+}
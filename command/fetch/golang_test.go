@@ -0,0 +1,138 @@
+package fetch
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+// newTestGoProxy serves example.com/hello@v1.0.0 the way a GOPROXY does
+func newTestGoProxy(t *testing.T) *httptest.Server {
+	var buf bytes.Buffer
+	z := zip.NewWriter(&buf)
+	files := []struct{ name, contents string }{
+		{"example.com/hello@v1.0.0/go.mod", "module example.com/hello\n\ngo 1.21\n"},
+		{"example.com/hello@v1.0.0/hello.go", "package hello\n\nfunc Hello() string { return \"hello\" }\n"},
+	}
+	for _, file := range files {
+		w, err := z.Create(file.name)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		w.Write([]byte(file.contents))
+	}
+	if err := z.Close(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/example.com/hello/@v/v1.0.0.info", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"Version":"v1.0.0","Time":"2023-08-01T00:00:00Z"}`))
+	})
+	mux.HandleFunc("/example.com/hello/@v/v1.0.0.mod", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(files[0].contents))
+	})
+	mux.HandleFunc("/example.com/hello/@v/v1.0.0.zip", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(buf.Bytes())
+	})
+	return httptest.NewServer(mux)
+}
+
+func writeGoModule(t *testing.T, goSum string) string {
+	dir, err := ioutil.TempDir("", "dep-get-fetch-golang")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	goMod := "module example.com/service\n\nrequire example.com/hello v1.0.0\n"
+	if err = ioutil.WriteFile(path.Join(dir, "go.mod"), []byte(goMod), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err = ioutil.WriteFile(path.Join(dir, "go.sum"), []byte(goSum), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	return dir
+}
+
+func TestFetchGoModules(t *testing.T) {
+	server := newTestGoProxy(t)
+	defer server.Close()
+
+	sourceDir := writeGoModule(t, `example.com/hello v1.0.0 h1:BMZLHt1FA6FLPC+H4ZSxZSphx9O63MG/XrVlfD3weYw=
+example.com/hello v1.0.0/go.mod h1:NnGvEkTHyKKlgPcQSue0skqyqiS1EfBSAh+0WaWZYmE=
+`)
+	defer os.RemoveAll(sourceDir)
+
+	cmd, dir := newTestFetchCommand(t)
+	defer os.RemoveAll(dir)
+	cmd.config.goproxy = server.URL + "/"
+
+	artifacts, err := cmd.readGolangArtifacts(sourceDir)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(artifacts) != 3 {
+		t.Fatalf("Expected the .info, .mod and .zip files, got %+v", artifacts)
+	}
+	results, err := cmd.fetchArtifacts(artifacts)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err = cmd.writeModuleLists(results); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	for _, fileName := range []string{"v1.0.0.info", "v1.0.0.mod", "v1.0.0.zip"} {
+		if _, err := os.Stat(path.Join(dir, "example.com/hello/@v", fileName)); err != nil {
+			t.Errorf("Expected %s in the GOPROXY layout: %s", fileName, err)
+		}
+	}
+	list, err := ioutil.ReadFile(path.Join(dir, "example.com/hello/@v/list"))
+	if err != nil || string(list) != "v1.0.0\n" {
+		t.Errorf("Expected the version to be listed, got %q (%v)", list, err)
+	}
+	if info, err := os.Stat(path.Join(dir, "example.com/hello/@v/list")); err != nil || info.Mode().Perm() != 0644 {
+		t.Errorf("Expected the list to have mode 0644, got %v (%v)", info, err)
+	}
+	entries, err := ioutil.ReadDir(path.Join(dir, "example.com/hello/@v"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") {
+			t.Errorf("Expected no temporary files left, found %s", entry.Name())
+		}
+	}
+	if entry := cmd.manifest.Get("example.com/hello/@v/v1.0.0.zip"); entry.Resolved != server.URL+"/example.com/hello/@v/v1.0.0.zip" {
+		t.Errorf("Expected the manifest to record the zip's URL, got %+v", entry)
+	}
+}
+
+func TestFetchGoModuleChecksumMismatch(t *testing.T) {
+	server := newTestGoProxy(t)
+	defer server.Close()
+
+	sourceDir := writeGoModule(t, `example.com/hello v1.0.0 h1:AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=
+example.com/hello v1.0.0/go.mod h1:NnGvEkTHyKKlgPcQSue0skqyqiS1EfBSAh+0WaWZYmE=
+`)
+	defer os.RemoveAll(sourceDir)
+
+	cmd, dir := newTestFetchCommand(t)
+	defer os.RemoveAll(dir)
+	cmd.config.goproxy = server.URL
+
+	artifacts, err := cmd.readGolangArtifacts(sourceDir)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	result := cmd.fetchArtifact(artifacts[2], "(3/3)")
+	if result.err == nil || !strings.Contains(result.err.Error(), "Invalid module zip") {
+		t.Fatalf("Expected a checksum error, got %v", result.err)
+	}
+	assertNoTempFiles(t, dir)
+}
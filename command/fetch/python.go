@@ -0,0 +1,278 @@
+package fetch
+
+import (
+	"bitbucket.org/bosgood/dep-get/command"
+	"bitbucket.org/bosgood/dep-get/lib/download"
+	"bitbucket.org/bosgood/dep-get/lib/filter"
+	"bitbucket.org/bosgood/dep-get/lib/manifest"
+	"bitbucket.org/bosgood/dep-get/python"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// pythonIndexURLs returns the package indexes to look distributions up
+// in, in order: --index-url or the lockfile's index, falling back to
+// PyPI, and then the lockfile's extra indexes
+func (c *fetchCommand) pythonIndexURLs(file *python.DependenciesFile) []string {
+	indexURL := c.config.indexURL
+	if indexURL == "" {
+		indexURL = file.IndexURL
+	}
+	if indexURL == "" {
+		indexURL = python.DefaultIndexURL
+	}
+	return append([]string{indexURL}, file.ExtraIndexURLs...)
+}
+
+// pythonFilterDependency describes dep to the include and exclude rules,
+// whose host: rules match the host it's downloaded from
+func pythonFilterDependency(dep python.PythonDependency, indexURL string) filter.Dependency {
+	if dep.IndexURL != "" {
+		indexURL = dep.IndexURL
+	}
+	packageURL := dep.URL
+	if packageURL == "" {
+		packageURL = python.ProjectURL(indexURL, dep.Name)
+	}
+	return filter.Dependency{
+		Name:    dep.Name,
+		Version: dep.Version,
+		Host:    filter.URLHost(packageURL),
+	}
+}
+
+// selectPythonDependencies leaves out the distributions other
+// environments need, those of the types to omit and those fetch can't
+// download, which are local directories and version control checkouts,
+// reporting each of the latter, and then applies the include and exclude
+// rules
+func (c *fetchCommand) selectPythonDependencies(
+	deps []python.PythonDependency,
+	indexURL string,
+) ([]python.PythonDependency, error) {
+	var kept []python.PythonDependency
+	numOtherEnvs, numOmitted := 0, 0
+	for _, dep := range deps {
+		applies, err := dep.AppliesTo(c.config.pythonEnv)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", dep.Name, err)
+		}
+		if !applies {
+			numOtherEnvs++
+			if c.config.verbose {
+				fmt.Printf(
+					"%sExcluding %s: needed where %s\n",
+					command.LogInfoPrefix,
+					dep.GetCanonicalName(),
+					dep.Marker,
+				)
+			}
+			continue
+		}
+		if c.config.omit.Omits(dep.Dev, dep.Optional, false, false) {
+			numOmitted++
+			continue
+		}
+
+		if dep.IsLocal() {
+			fmt.Printf(
+				"%sSkipping local dependency %s (%s)\n",
+				command.LogInfoPrefix,
+				dep.Name,
+				dep.LocalPath,
+			)
+			continue
+		}
+		if dep.IsVCS() {
+			fmt.Printf(
+				"%sSkipping version control dependency %s (%s)\n",
+				command.LogInfoPrefix,
+				dep.Name,
+				dep.URL,
+			)
+			continue
+		}
+
+		excluded, reason := c.config.filter.Excludes(pythonFilterDependency(dep, indexURL))
+		if excluded {
+			if c.config.verbose {
+				fmt.Printf(
+					"%sExcluding %s: %s\n",
+					command.LogInfoPrefix,
+					dep.GetCanonicalName(),
+					reason,
+				)
+			}
+			continue
+		}
+		kept = append(kept, dep)
+	}
+
+	if numOtherEnvs > 0 || numOmitted > 0 {
+		fmt.Printf(
+			"%sLeaving out %d dependencies for other environments and %d omitted dependencies.\n",
+			command.LogInfoPrefix,
+			numOtherEnvs,
+			numOmitted,
+		)
+	}
+	return kept, nil
+}
+
+// fetchPage downloads a package index page, retrying transient failures
+func (c *fetchCommand) fetchPage(pageURL string) ([]byte, error) {
+	var contents []byte
+	attempt := func() error {
+		body, err := c.openURL(pageURL, "")
+		if err != nil {
+			return err
+		}
+		defer body.Close()
+		contents, err = ioutil.ReadAll(body)
+		return err
+	}
+	onRetry := func(retry int, delay time.Duration, err error) {
+		c.printf(
+			"%sRetrying %s (%d/%d) in %s: %s\n",
+			command.LogInfoPrefix,
+			pageURL,
+			retry, c.retryer.MaxRetries,
+			delay,
+			err,
+		)
+	}
+	_, err := c.retryer.Do(attempt, onRetry)
+	return contents, err
+}
+
+// findDistributionFiles looks dep up in each package index in turn,
+// returning the files to download from the first listing ones for it:
+// the best wheel or sdist for each target environment
+func (c *fetchCommand) findDistributionFiles(
+	dep python.PythonDependency,
+	indexURLs []string,
+) ([]python.DistributionFile, error) {
+	var lastErr error
+	for _, indexURL := range indexURLs {
+		pageURL := python.ProjectURL(indexURL, dep.Name)
+		contents, err := c.fetchPage(pageURL)
+		if statusErr, ok := err.(*download.StatusError); ok && statusErr.StatusCode == http.StatusNotFound {
+			lastErr = err
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		files, err := python.ParseProjectPage(contents, pageURL)
+		if err != nil {
+			return nil, err
+		}
+		selected, err := python.SelectFiles(files, dep, c.config.pythonTags)
+		if err == nil {
+			return selected, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// pythonArtifacts describes the downloads of dep's distribution files,
+// looking them up in the package index the lockfile pins it to or else
+// in indexURLs, unless dep gives its URL. The hashes pinned in the
+// lockfile are checked or, when there are none, those the index gives.
+func (c *fetchCommand) pythonArtifacts(dep python.PythonDependency, indexURLs []string) ([]artifact, error) {
+	sri, err := dep.Integrity()
+	if err != nil {
+		return nil, err
+	}
+
+	if dep.URL != "" {
+		return []artifact{c.pythonArtifact(dep, python.FileNameFromURL(dep.URL), dep.URL, sri)}, nil
+	}
+
+	if dep.IndexURL != "" {
+		indexURLs = []string{dep.IndexURL}
+	}
+	files, err := c.findDistributionFiles(dep, indexURLs)
+	if err != nil {
+		return nil, err
+	}
+	var artifacts []artifact
+	for _, file := range files {
+		fileSRI := sri
+		if fileSRI == "" {
+			listed := python.PythonDependency{Name: dep.Name, Hashes: file.Hashes}
+			// Old indexes give md5 sums, which aren't worth checking
+			if listedSRI, err := listed.Integrity(); err == nil {
+				fileSRI = listedSRI
+			}
+		}
+		artifacts = append(artifacts, c.pythonArtifact(dep, file.Filename, file.URL, fileSRI))
+	}
+	return artifacts, nil
+}
+
+// pythonArtifact describes the download of one of dep's distribution
+// files
+func (c *fetchCommand) pythonArtifact(dep python.PythonDependency, fileName, fileURL, sri string) artifact {
+	return artifact{
+		name:      dep.GetCanonicalName(),
+		fileName:  fileName,
+		url:       fileURL,
+		integrity: sri,
+		entry: manifest.Entry{
+			Resolved: fileURL,
+			Dev:      dep.Dev,
+			Optional: dep.Optional,
+		},
+		validate: func(r io.Reader) error {
+			return python.ValidateDistribution(fileName, r)
+		},
+		kind: "distribution",
+	}
+}
+
+// readPythonArtifacts reads the python lockfile in dirPath, returning the
+// distribution files to fetch
+func (c *fetchCommand) readPythonArtifacts(dirPath string) ([]artifact, error) {
+	file, err := python.ReadDependencies(c.os, dirPath)
+	if err != nil {
+		fmt.Printf(
+			"%s%s: %s\n",
+			command.LogErrorPrefix,
+			"Can't read the dependencies file",
+			err,
+		)
+		return nil, err
+	}
+
+	fmt.Printf(
+		"%sRead dependencies file: %s\n",
+		command.LogInfoPrefix,
+		file.Path,
+	)
+
+	indexURLs := c.pythonIndexURLs(file)
+	deps, err := c.selectPythonDependencies(file.Dependencies, indexURLs[0])
+	if err != nil {
+		fmt.Printf(
+			"%s%s: %s\n",
+			command.LogErrorPrefix,
+			"Can't evaluate environment marker",
+			err,
+		)
+		return nil, err
+	}
+
+	var names []string
+	for _, dep := range deps {
+		names = append(names, dep.GetCanonicalName())
+	}
+	return c.lookupArtifacts(names, func(i int) ([]artifact, error) {
+		return c.pythonArtifacts(deps[i], indexURLs)
+	})
+}
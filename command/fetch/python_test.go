@@ -0,0 +1,292 @@
+package fetch
+
+import (
+	"bitbucket.org/bosgood/dep-get/nodejs"
+	"bitbucket.org/bosgood/dep-get/python"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+func testSdist(t *testing.T) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write([]byte("six")); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	return buf.Bytes()
+}
+
+// newTestIndex serves a simple index listing six at /simple/ and another
+// listing attrs at /extra/
+func newTestIndex(t *testing.T, sdist []byte) *httptest.Server {
+	sum := sha256.Sum256(sdist)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/simple/six/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `<html><body>
+<a href="../../packages/six-1.16.0.tar.gz#sha256=%s">six-1.16.0.tar.gz</a>
+<a href="../../packages/six-1.16.0-cp311-cp311-manylinux_2_28_x86_64.whl">six-1.16.0-cp311-cp311-manylinux_2_28_x86_64.whl</a>
+</body></html>`, hex.EncodeToString(sum[:]))
+	})
+	mux.HandleFunc("/extra/attrs/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<a href="/packages/attrs-23.1.0-py3-none-any.whl">attrs-23.1.0-py3-none-any.whl</a>`)
+	})
+	mux.HandleFunc("/packages/six-1.16.0.tar.gz", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(sdist)
+	})
+	mux.HandleFunc("/packages/six-1.16.0-cp311-cp311-manylinux_2_28_x86_64.whl", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("PK\x03\x04six"))
+	})
+	mux.HandleFunc("/packages/attrs-23.1.0-py3-none-any.whl", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("PK\x03\x04attrs"))
+	})
+	return httptest.NewServer(mux)
+}
+
+func writeRequirements(t *testing.T, contents string) string {
+	dir, err := ioutil.TempDir("", "dep-get-fetch-python")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	err = ioutil.WriteFile(path.Join(dir, "requirements.txt"), []byte(contents), 0644)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	return dir
+}
+
+func TestFetchPythonDependencies(t *testing.T) {
+	sdist := testSdist(t)
+	server := newTestIndex(t, sdist)
+	defer server.Close()
+
+	sourceDir := writeRequirements(t, `--index-url `+server.URL+`/simple/
+--extra-index-url `+server.URL+`/extra/
+six==1.16
+attrs==23.1.0
+colorama==0.4.6 ; sys_platform == "win32"
+-e ./libs/shared#egg=shared
+`)
+	defer os.RemoveAll(sourceDir)
+
+	cmd, dir := newTestFetchCommand(t)
+	defer os.RemoveAll(dir)
+	cmd.config.pythonEnv = map[string]string{"sys_platform": "linux"}
+
+	artifacts, err := cmd.readPythonArtifacts(sourceDir)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(artifacts) != 2 {
+		t.Fatalf("Expected six and attrs, got %+v", artifacts)
+	}
+	if !strings.HasPrefix(artifacts[0].integrity, "sha256-") {
+		t.Errorf("Expected the index's hash to be checked, got %q", artifacts[0].integrity)
+	}
+
+	results, err := cmd.fetchArtifacts(artifacts)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	expected := []string{"six-1.16.0.tar.gz", "attrs-23.1.0-py3-none-any.whl"}
+	for i, fileName := range expected {
+		if results[i].outFilePath != path.Join(dir, fileName) {
+			t.Errorf("Expected %s, got %s", fileName, results[i].outFilePath)
+		}
+	}
+	contents, err := ioutil.ReadFile(path.Join(dir, "six-1.16.0.tar.gz"))
+	if err != nil || !bytes.Equal(contents, sdist) {
+		t.Errorf("Expected the sdist to be written")
+	}
+	if entry := cmd.manifest.Get("six-1.16.0.tar.gz"); entry.Resolved != server.URL+"/packages/six-1.16.0.tar.gz" {
+		t.Errorf("Expected the manifest to record the file's URL, got %+v", entry)
+	}
+}
+
+func TestFetchPythonWheelsForTargets(t *testing.T) {
+	server := newTestIndex(t, testSdist(t))
+	defer server.Close()
+
+	sourceDir := writeRequirements(t, "six==1.16.0\n")
+	defer os.RemoveAll(sourceDir)
+
+	cmd, dir := newTestFetchCommand(t)
+	defer os.RemoveAll(dir)
+	cmd.config.indexURL = server.URL + "/simple/"
+	cmd.config.pythonTags = []python.Tag{
+		{Interpreter: "cp311", ABI: "cp311", Platform: "manylinux_2_28_x86_64"},
+		{Interpreter: "cp311", ABI: "cp311", Platform: "manylinux_2_31_x86_64"},
+		{Interpreter: "cp311", ABI: "cp311", Platform: "win_amd64"},
+	}
+
+	artifacts, err := cmd.readPythonArtifacts(sourceDir)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	expected := []string{"six-1.16.0-cp311-cp311-manylinux_2_28_x86_64.whl", "six-1.16.0.tar.gz"}
+	if len(artifacts) != len(expected) {
+		t.Fatalf("Expected %q, got %+v", expected, artifacts)
+	}
+	for i, fileName := range expected {
+		if artifacts[i].fileName != fileName {
+			t.Errorf("Expected %s, got %s", fileName, artifacts[i].fileName)
+		}
+	}
+
+	if _, err = cmd.fetchArtifacts(artifacts); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	for _, fileName := range expected {
+		if _, err := os.Stat(path.Join(dir, fileName)); err != nil {
+			t.Errorf("Expected %s to be written: %s", fileName, err)
+		}
+	}
+}
+
+func TestFetchPythonDependencyHashMismatch(t *testing.T) {
+	server := newTestIndex(t, testSdist(t))
+	defer server.Close()
+
+	sourceDir := writeRequirements(t, `six==1.16.0 \
+    --hash=sha256:0000000000000000000000000000000000000000000000000000000000000000
+`)
+	defer os.RemoveAll(sourceDir)
+
+	cmd, dir := newTestFetchCommand(t)
+	defer os.RemoveAll(dir)
+	cmd.config.indexURL = server.URL + "/simple/"
+
+	// The index's hash rules out its only sdist
+	if _, err := cmd.readPythonArtifacts(sourceDir); err == nil {
+		t.Fatalf("Expected no file to match the pinned hash")
+	}
+
+	sourceDir2 := writeRequirements(t, `six @ `+server.URL+`/packages/six-1.16.0.tar.gz#sha256=0000000000000000000000000000000000000000000000000000000000000000
+`)
+	defer os.RemoveAll(sourceDir2)
+	artifacts, err := cmd.readPythonArtifacts(sourceDir2)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	result := cmd.fetchArtifact(artifacts[0], "(1/1)")
+	if result.err == nil || !strings.Contains(result.err.Error(), "Integrity check failed") {
+		t.Fatalf("Expected an integrity error, got %v", result.err)
+	}
+	assertNoTempFiles(t, dir)
+}
+
+func TestFetchPythonDependenciesKeepGoing(t *testing.T) {
+	server := newTestIndex(t, testSdist(t))
+	defer server.Close()
+
+	// The index has no page for missing
+	sourceDir := writeRequirements(t, "missing==1.0.0\nsix==1.16.0\n")
+	defer os.RemoveAll(sourceDir)
+
+	cmd, dir := newTestFetchCommand(t)
+	defer os.RemoveAll(dir)
+	cmd.config.indexURL = server.URL + "/simple/"
+	cmd.config.concurrency = 2
+
+	if _, err := cmd.readPythonArtifacts(sourceDir); err == nil {
+		t.Fatalf("Expected the failed lookup to stop the run")
+	}
+
+	cmd.config.keepGoing = true
+	artifacts, err := cmd.readPythonArtifacts(sourceDir)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(artifacts) != 1 || artifacts[0].fileName != "six-1.16.0.tar.gz" {
+		t.Errorf("Expected six's sdist alone, got %+v", artifacts)
+	}
+	if len(cmd.lookupErrs) != 1 || !strings.Contains(cmd.lookupErrs[0].Error(), "missing==1.0.0") {
+		t.Errorf("Expected the failed lookup of missing to be kept, got %v", cmd.lookupErrs)
+	}
+}
+
+func TestFetchPythonDependencyInvalidFile(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<html>Sign in</html>"))
+	}))
+	defer server.Close()
+
+	sourceDir := writeRequirements(t, "six @ "+server.URL+"/six-1.16.0.tar.gz\n")
+	defer os.RemoveAll(sourceDir)
+
+	cmd, dir := newTestFetchCommand(t)
+	defer os.RemoveAll(dir)
+
+	artifacts, err := cmd.readPythonArtifacts(sourceDir)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	result := cmd.fetchArtifact(artifacts[0], "(1/1)")
+	if result.err == nil || !strings.Contains(result.err.Error(), "Invalid distribution") {
+		t.Fatalf("Expected an invalid distribution error, got %v", result.err)
+	}
+}
+
+func TestFetchPipfileLockDependencies(t *testing.T) {
+	server := newTestIndex(t, testSdist(t))
+	defer server.Close()
+
+	sourceDir, err := ioutil.TempDir("", "dep-get-fetch-python")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(sourceDir)
+	pipfileLock := `{
+    "_meta": {"sources": [
+        {"name": "pypi", "url": "` + server.URL + `/simple/"},
+        {"name": "extra", "url": "` + server.URL + `/extra/"}
+    ]},
+    "default": {
+        "attrs": {"hashes": [], "index": "extra", "version": "==23.1.0"}
+    },
+    "develop": {
+        "six": {"hashes": [], "index": "pypi", "version": "==1.16.0"}
+    }
+}`
+	err = ioutil.WriteFile(path.Join(sourceDir, "Pipfile.lock"), []byte(pipfileLock), 0644)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	cmd, dir := newTestFetchCommand(t)
+	defer os.RemoveAll(dir)
+	// attrs is only found in the index the lockfile pins it to
+	cmd.config.indexURL = server.URL + "/simple/"
+
+	artifacts, err := cmd.readPythonArtifacts(sourceDir)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(artifacts) != 2 || !artifacts[1].entry.Dev {
+		t.Fatalf("Expected attrs and the dev dependency six, got %+v", artifacts)
+	}
+	if artifacts[0].url != server.URL+"/packages/attrs-23.1.0-py3-none-any.whl" {
+		t.Errorf("Unexpected URL for attrs: %s", artifacts[0].url)
+	}
+
+	cmd.config.omit, _ = nodejs.ParseOmit([]string{"dev"})
+	artifacts, err = cmd.readPythonArtifacts(sourceDir)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(artifacts) != 1 || artifacts[0].name != "attrs==23.1.0" {
+		t.Errorf("Expected the dev dependency to be omitted, got %+v", artifacts)
+	}
+}
@@ -0,0 +1,131 @@
+package fetch
+
+import (
+	"bitbucket.org/bosgood/dep-get/command"
+	"bitbucket.org/bosgood/dep-get/lib/filter"
+	"bitbucket.org/bosgood/dep-get/lib/manifest"
+	"bitbucket.org/bosgood/dep-get/ruby"
+	"fmt"
+)
+
+// selectGems leaves out the gems fetch can't download, which are those
+// from git and local paths, reporting each, along with precompiled gems
+// for none of the lockfile's platforms, and then applies the include and
+// exclude rules
+func (c *fetchCommand) selectGems(file *ruby.DependenciesFile) []ruby.GemDependency {
+	var kept []ruby.GemDependency
+	numOtherPlatforms := 0
+	for _, dep := range file.Dependencies {
+		if dep.IsLocal() {
+			fmt.Printf(
+				"%sSkipping local dependency %s (%s)\n",
+				command.LogInfoPrefix,
+				dep.Name,
+				dep.Path,
+			)
+			continue
+		}
+		if dep.IsGit() {
+			fmt.Printf(
+				"%sSkipping git dependency %s (%s@%s)\n",
+				command.LogInfoPrefix,
+				dep.Name,
+				dep.Git,
+				dep.Revision,
+			)
+			continue
+		}
+		if len(file.Platforms) > 0 && !dep.ForPlatforms(file.Platforms) {
+			numOtherPlatforms++
+			if c.config.verbose {
+				fmt.Printf(
+					"%sExcluding %s: built for %s\n",
+					command.LogInfoPrefix,
+					dep.GetCanonicalName(),
+					dep.Platform,
+				)
+			}
+			continue
+		}
+
+		excluded, reason := c.config.filter.Excludes(filter.Dependency{
+			Name:    dep.Name,
+			Version: dep.Version,
+			Host:    filter.URLHost(dep.GemURL(c.config.gemSource)),
+		})
+		if excluded {
+			if c.config.verbose {
+				fmt.Printf(
+					"%sExcluding %s: %s\n",
+					command.LogInfoPrefix,
+					dep.GetCanonicalName(),
+					reason,
+				)
+			}
+			continue
+		}
+		kept = append(kept, dep)
+	}
+
+	if numOtherPlatforms > 0 {
+		fmt.Printf(
+			"%sLeaving out %d gems for other platforms.\n",
+			command.LogInfoPrefix,
+			numOtherPlatforms,
+		)
+	}
+	return kept
+}
+
+// gemArtifact describes the download of dep's .gem file from its remote,
+// or --gem-source when given, checked against the lockfile's checksums
+func (c *fetchCommand) gemArtifact(dep ruby.GemDependency) (artifact, error) {
+	sri, err := dep.Integrity()
+	if err != nil {
+		return artifact{}, err
+	}
+	gemURL := dep.GemURL(c.config.gemSource)
+	return artifact{
+		name:      dep.GetCanonicalName(),
+		fileName:  dep.FullName() + ".gem",
+		url:       gemURL,
+		integrity: sri,
+		entry:     manifest.Entry{Resolved: gemURL},
+		validate:  ruby.ValidateGem,
+		kind:      "gem",
+	}, nil
+}
+
+// readRubyArtifacts reads the Gemfile.lock in dirPath, returning the gems
+// to fetch
+func (c *fetchCommand) readRubyArtifacts(dirPath string) ([]artifact, error) {
+	file, err := ruby.ReadDependencies(c.os, dirPath)
+	if err != nil {
+		fmt.Printf(
+			"%s%s: %s\n",
+			command.LogErrorPrefix,
+			"Can't read the dependencies file",
+			err,
+		)
+		return nil, err
+	}
+
+	fmt.Printf(
+		"%sRead dependencies file: %s\n",
+		command.LogInfoPrefix,
+		file.Path,
+	)
+
+	deps := c.selectGems(file)
+	var names []string
+	for _, dep := range deps {
+		names = append(names, dep.GetCanonicalName())
+	}
+	return c.lookupArtifacts(names, func(i int) ([]artifact, error) {
+		a, err := c.gemArtifact(deps[i])
+		if err != nil {
+			return nil, fmt.Errorf("Can't read checksum: %s", err)
+		}
+		return []artifact{a}, nil
+	})
+}
@@ -0,0 +1,121 @@
+package fetch
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+// testGem returns a file starting with a tar header, as gems do
+func testGem(name string) []byte {
+	gem := make([]byte, 1024)
+	copy(gem, name)
+	copy(gem[257:], "ustar\x0000")
+	return gem
+}
+
+func writeGemfileLock(t *testing.T, contents string) string {
+	dir, err := ioutil.TempDir("", "dep-get-fetch-ruby")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	err = ioutil.WriteFile(path.Join(dir, "Gemfile.lock"), []byte(contents), 0644)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	return dir
+}
+
+func TestFetchGems(t *testing.T) {
+	rack := testGem("rack")
+	sum := sha256.Sum256(rack)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/gems/rack-3.0.8.gem":
+			w.Write(rack)
+		case "/gems/nokogiri-1.15.0-x86_64-linux.gem":
+			w.Write(testGem("nokogiri"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	sourceDir := writeGemfileLock(t, `PATH
+  remote: engines/billing
+  specs:
+    billing (0.1.0)
+
+GEM
+  remote: `+server.URL+`/
+  specs:
+    nokogiri (1.15.0-arm64-darwin)
+    nokogiri (1.15.0-x86_64-linux)
+    rack (3.0.8)
+
+PLATFORMS
+  x86_64-linux
+
+CHECKSUMS
+  rack (3.0.8) sha256=`+hex.EncodeToString(sum[:])+`
+`)
+	defer os.RemoveAll(sourceDir)
+
+	cmd, dir := newTestFetchCommand(t)
+	defer os.RemoveAll(dir)
+
+	artifacts, err := cmd.readRubyArtifacts(sourceDir)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(artifacts) != 2 || !strings.HasPrefix(artifacts[1].integrity, "sha256-") {
+		t.Fatalf("Expected the linux nokogiri and the checksummed rack, got %+v", artifacts)
+	}
+
+	results, err := cmd.fetchArtifacts(artifacts)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	expected := []string{"nokogiri-1.15.0-x86_64-linux.gem", "rack-3.0.8.gem"}
+	for i, fileName := range expected {
+		if results[i].outFilePath != path.Join(dir, fileName) {
+			t.Errorf("Expected %s, got %s", fileName, results[i].outFilePath)
+		}
+	}
+}
+
+func TestFetchGemChecksumMismatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(testGem("rack"))
+	}))
+	defer server.Close()
+
+	sourceDir := writeGemfileLock(t, `GEM
+  remote: `+server.URL+`/
+  specs:
+    rack (3.0.8)
+
+CHECKSUMS
+  rack (3.0.8) sha256=0000000000000000000000000000000000000000000000000000000000000000
+`)
+	defer os.RemoveAll(sourceDir)
+
+	cmd, dir := newTestFetchCommand(t)
+	defer os.RemoveAll(dir)
+
+	artifacts, err := cmd.readRubyArtifacts(sourceDir)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	result := cmd.fetchArtifact(artifacts[0], "(1/1)")
+	if result.err == nil || !strings.Contains(result.err.Error(), "Integrity check failed") {
+		t.Fatalf("Expected an integrity error, got %v", result.err)
+	}
+	assertNoTempFiles(t, dir)
+}
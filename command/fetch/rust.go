@@ -0,0 +1,174 @@
+package fetch
+
+import (
+	"bitbucket.org/bosgood/dep-get/command"
+	"bitbucket.org/bosgood/dep-get/lib/filter"
+	"bitbucket.org/bosgood/dep-get/lib/manifest"
+	"bitbucket.org/bosgood/dep-get/rust"
+	"fmt"
+	"sync"
+)
+
+// crateEndpoints holds the dl templates of the registries crates come
+// from, or the errors looking them up, by source
+type crateEndpoints struct {
+	lock sync.Mutex
+	dl   map[string]string
+	errs map[string]error
+}
+
+func newCrateEndpoints() *crateEndpoints {
+	return &crateEndpoints{
+		dl:   make(map[string]string),
+		errs: make(map[string]error),
+	}
+}
+
+// crateDownloadEndpoint returns the dl template of the registry dep
+// comes from, reading the config.json of its index unless it's
+// crates.io's and --crate-index doesn't replace it. Endpoints are looked
+// up once per source, even when that fails.
+func (c *fetchCommand) crateDownloadEndpoint(dep rust.CrateDependency, endpoints *crateEndpoints) (string, error) {
+	endpoints.lock.Lock()
+	defer endpoints.lock.Unlock()
+	if dl, ok := endpoints.dl[dep.Source]; ok {
+		return dl, nil
+	}
+	if err, ok := endpoints.errs[dep.Source]; ok {
+		return "", err
+	}
+
+	dl, err := c.lookupCrateDownloadEndpoint(dep)
+	if err != nil {
+		endpoints.errs[dep.Source] = err
+		return "", err
+	}
+	endpoints.dl[dep.Source] = dl
+	return dl, nil
+}
+
+// lookupCrateDownloadEndpoint reads the dl template of the registry dep
+// comes from
+func (c *fetchCommand) lookupCrateDownloadEndpoint(dep rust.CrateDependency) (string, error) {
+	var indexURL string
+	if rust.IsCratesIO(dep.Source) {
+		if c.config.crateIndex == "" {
+			return rust.CratesIODownloadURL, nil
+		}
+		indexURL = c.config.crateIndex
+	} else {
+		var ok bool
+		indexURL, ok = rust.SparseIndexURL(dep.Source)
+		if !ok {
+			return "", fmt.Errorf("Can't find the download endpoint of the git index %s, only of sparse indexes", dep.Source)
+		}
+	}
+
+	contents, err := c.fetchPage(rust.ConfigURL(indexURL))
+	if err != nil {
+		return "", err
+	}
+	config, err := rust.ParseRegistryConfig(contents)
+	if err != nil {
+		return "", fmt.Errorf("%s: %s", rust.ConfigURL(indexURL), err)
+	}
+	return config.DL, nil
+}
+
+// selectCrates leaves out the crates fetch can't download, which are
+// the workspace's own, path dependencies and those from git, reporting
+// the latter, and then applies the include and exclude rules
+func (c *fetchCommand) selectCrates(deps []rust.CrateDependency) []rust.CrateDependency {
+	var kept []rust.CrateDependency
+	for _, dep := range deps {
+		if dep.IsGit() {
+			fmt.Printf(
+				"%sSkipping git dependency %s (%s)\n",
+				command.LogInfoPrefix,
+				dep.GetCanonicalName(),
+				dep.Source,
+			)
+			continue
+		}
+		if !dep.IsRegistry() {
+			continue
+		}
+
+		excluded, reason := c.config.filter.Excludes(filter.Dependency{
+			Name:    dep.Name,
+			Version: dep.Version,
+			Host:    filter.URLHost(dep.Source),
+		})
+		if excluded {
+			if c.config.verbose {
+				fmt.Printf(
+					"%sExcluding %s: %s\n",
+					command.LogInfoPrefix,
+					dep.GetCanonicalName(),
+					reason,
+				)
+			}
+			continue
+		}
+		kept = append(kept, dep)
+	}
+	return kept
+}
+
+// crateArtifact describes the download of dep's .crate file from its
+// registry, checked against the lockfile's checksum
+func (c *fetchCommand) crateArtifact(dep rust.CrateDependency, endpoints *crateEndpoints) (artifact, error) {
+	dl, err := c.crateDownloadEndpoint(dep, endpoints)
+	if err != nil {
+		return artifact{}, err
+	}
+	sri, err := dep.Integrity()
+	if err != nil {
+		return artifact{}, err
+	}
+	crateURL := rust.DownloadURL(dl, dep)
+	return artifact{
+		name:      dep.GetCanonicalName(),
+		fileName:  dep.FileName(),
+		url:       crateURL,
+		integrity: sri,
+		entry:     manifest.Entry{Resolved: crateURL},
+		validate:  rust.ValidateCrate,
+		kind:      "crate",
+	}, nil
+}
+
+// readRustArtifacts reads the Cargo.lock in dirPath, returning the
+// crates to fetch
+func (c *fetchCommand) readRustArtifacts(dirPath string) ([]artifact, error) {
+	file, err := rust.ReadDependencies(c.os, dirPath)
+	if err != nil {
+		fmt.Printf(
+			"%s%s: %s\n",
+			command.LogErrorPrefix,
+			"Can't read the dependencies file",
+			err,
+		)
+		return nil, err
+	}
+
+	fmt.Printf(
+		"%sRead dependencies file: %s\n",
+		command.LogInfoPrefix,
+		file.Path,
+	)
+
+	deps := c.selectCrates(file.Dependencies)
+	var names []string
+	for _, dep := range deps {
+		names = append(names, dep.GetCanonicalName())
+	}
+	endpoints := newCrateEndpoints()
+	return c.lookupArtifacts(names, func(i int) ([]artifact, error) {
+		a, err := c.crateArtifact(deps[i], endpoints)
+		if err != nil {
+			return nil, err
+		}
+		return []artifact{a}, nil
+	})
+}
@@ -0,0 +1,133 @@
+package fetch
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+// testCrate returns a gzipped file, as .crate files are
+func testCrate(t *testing.T, name string) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write([]byte(name)); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	return buf.Bytes()
+}
+
+func writeCargoLock(t *testing.T, contents string) string {
+	dir, err := ioutil.TempDir("", "dep-get-fetch-rust")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	err = ioutil.WriteFile(path.Join(dir, "Cargo.lock"), []byte(contents), 0644)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	return dir
+}
+
+func TestFetchCrates(t *testing.T) {
+	serde := testCrate(t, "serde")
+	sum := sha256.Sum256(serde)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/index/config.json":
+			w.Write([]byte(`{"dl": "http://` + r.Host + `/crates/{prefix}/{crate}/{crate}-{version}.crate"}`))
+		case "/crates/se/rd/serde/serde-1.0.188.crate":
+			w.Write(serde)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	sourceDir := writeCargoLock(t, `version = 3
+
+[[package]]
+name = "app"
+version = "0.1.0"
+
+[[package]]
+name = "serde"
+version = "1.0.188"
+source = "registry+https://github.com/rust-lang/crates.io-index"
+checksum = "`+hex.EncodeToString(sum[:])+`"
+
+[[package]]
+name = "tokio"
+version = "1.32.0"
+source = "git+https://github.com/tokio-rs/tokio#1e2dd5d0"
+`)
+	defer os.RemoveAll(sourceDir)
+
+	cmd, dir := newTestFetchCommand(t)
+	defer os.RemoveAll(dir)
+	cmd.config.crateIndex = server.URL + "/index"
+
+	artifacts, err := cmd.readRustArtifacts(sourceDir)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(artifacts) != 1 || artifacts[0].url != server.URL+"/crates/se/rd/serde/serde-1.0.188.crate" {
+		t.Fatalf("Expected serde from the mirror, got %+v", artifacts)
+	}
+
+	results, err := cmd.fetchArtifacts(artifacts)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if results[0].outFilePath != path.Join(dir, "serde-1.0.188.crate") {
+		t.Errorf("Unexpected file %s", results[0].outFilePath)
+	}
+}
+
+func TestFetchCrateChecksumMismatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/config.json":
+			w.Write([]byte(`{"dl": "http://` + r.Host + `/api/v1/crates"}`))
+		default:
+			w.Write(testCrate(t, "serde"))
+		}
+	}))
+	defer server.Close()
+
+	sourceDir := writeCargoLock(t, `version = 4
+
+[[package]]
+name = "serde"
+version = "1.0.188"
+source = "sparse+`+server.URL+`/"
+checksum = "0000000000000000000000000000000000000000000000000000000000000000"
+`)
+	defer os.RemoveAll(sourceDir)
+
+	cmd, dir := newTestFetchCommand(t)
+	defer os.RemoveAll(dir)
+
+	artifacts, err := cmd.readRustArtifacts(sourceDir)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if artifacts[0].url != server.URL+"/api/v1/crates/serde/1.0.188/download" {
+		t.Errorf("Unexpected url %s", artifacts[0].url)
+	}
+	result := cmd.fetchArtifact(artifacts[0], "(1/1)")
+	if result.err == nil || !strings.Contains(result.err.Error(), "Integrity check failed") {
+		t.Fatalf("Expected an integrity error, got %v", result.err)
+	}
+	assertNoTempFiles(t, dir)
+}
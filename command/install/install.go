@@ -2,10 +2,14 @@ package install
 
 import (
 	"bitbucket.org/bosgood/dep-get/command"
+	"bitbucket.org/bosgood/dep-get/golang"
 	"bitbucket.org/bosgood/dep-get/lib/fs"
+	"bitbucket.org/bosgood/dep-get/lib/manifest"
+	"bitbucket.org/bosgood/dep-get/nodejs"
 	"flag"
 	"fmt"
 	"github.com/mitchellh/cli"
+	"os"
 	"os/exec"
 	"path"
 )
@@ -17,8 +21,12 @@ type installCommand struct {
 
 type installCommandFlags struct {
 	command.BaseFlags
-	platform string
-	source   string
+	platform    string
+	source      string
+	vendorDir   string
+	gemPlatform string
+	command.OmitFlags
+	omit nodejs.Omit
 }
 
 var realOS fs.FileSystem = &fs.OSFS{}
@@ -51,8 +59,11 @@ func getConfig(args []string) (installCommandFlags, *flag.FlagSet, error) {
 
 	cmdFlags := flag.NewFlagSet("install", flag.ExitOnError)
 	cmdFlags.BoolVar(&cmdConfig.Help, "help", false, "show command help")
-	cmdFlags.StringVar(&cmdConfig.platform, "platform", "", "platform type (allowed: nodejs|python)")
+	cmdFlags.StringVar(&cmdConfig.platform, "platform", "", "platform type (allowed: nodejs|python|golang|ruby|rust)")
 	cmdFlags.StringVar(&cmdConfig.source, "source", "", "project directory (default: .)")
+	cmdFlags.StringVar(&cmdConfig.vendorDir, "vendor-dir", "vendor", "directory rust crates are vendored into")
+	cmdFlags.StringVar(&cmdConfig.gemPlatform, "gem-platform", "", "platform to install precompiled ruby gems for (default: the one gem reports)")
+	cmdConfig.OmitFlags.Register(cmdFlags)
 
 	if err := cmdFlags.Parse(args); err != nil {
 		errMsg := fmt.Sprintf(
@@ -88,16 +99,22 @@ func getConfig(args []string) (installCommandFlags, *flag.FlagSet, error) {
 		}
 	}
 
-	if cmdConfig.platform != "nodejs" {
+	if !command.ValidPlatform(cmdConfig.platform) {
+		return cmdConfig, cmdFlags, command.UnsupportedPlatformError(cmdConfig.platform)
+	}
+
+	omit, err := nodejs.ParseOmit(cmdConfig.OmitFlags.Values())
+	if err != nil {
 		errMsg := fmt.Sprintf(
 			"%s%s\n",
 			command.LogErrorPrefix,
-			"Only nodejs supported at the moment",
+			err,
 		)
 		return cmdConfig, cmdFlags, &command.ConfigError{
 			Explanation: errMsg,
 		}
 	}
+	cmdConfig.omit = omit
 
 	return cmdConfig, cmdFlags, nil
 }
@@ -112,7 +129,7 @@ func (c *installCommand) Run(args []string) int {
 		return cli.RunResultHelp
 	}
 
-	archives, err := c.os.ReadDir(cmdConfig.source)
+	archives, err := fs.Walk(c.os, cmdConfig.source)
 	if err != nil {
 		fmt.Printf(
 			"%sError reading archive path: %s\n",
@@ -121,19 +138,86 @@ func (c *installCommand) Run(args []string) int {
 		)
 	}
 
-	for _, fileInfo := range archives {
+	// fetch records which packages are dev, optional or peer dependencies
+	fetched := manifest.New()
+	if cmdConfig.omit.Any() {
+		fetched, err = manifest.Read(c.os, cmdConfig.source)
+		if err != nil {
+			fmt.Printf(
+				"%sCan't read the fetch manifest to omit dependencies: %s\n",
+				command.LogErrorPrefix,
+				err,
+			)
+			return 1
+		}
+	}
+
+	switch cmdConfig.platform {
+	case "python":
+		return c.installDistributions(cmdConfig, archives, fetched)
+	case "ruby":
+		return c.installGems(cmdConfig, archives, fetched)
+	case "rust":
+		// cargo builds from a vendor directory rather than a cache
+		return c.vendorCrates(cmdConfig, archives, fetched)
+	}
+
+	for _, name := range archives {
+		if fetched.Omitted(name, cmdConfig.omit) {
+			fmt.Printf(
+				"%sOmitting dependency: %s\n",
+				command.LogInfoPrefix,
+				name,
+			)
+			continue
+		}
+
 		archiveFilePath := path.Join(
 			cmdConfig.source,
-			fileInfo.Name(),
-		)
-
-		fmt.Printf(
-			"%sCaching dependency: %s\n",
-			command.LogInfoPrefix,
-			fileInfo.Name(),
+			name,
 		)
 
-		cmd := exec.Command("npm", "cache", "add", archiveFilePath)
+		var cmd *exec.Cmd
+		switch cmdConfig.platform {
+		case "golang":
+			// The source directory is a GOPROXY, whose zips are the modules
+			// to download into the module cache
+			modPath, version, extension, ok := golang.ParseProxyFilePath(name)
+			if !ok || extension != ".zip" {
+				continue
+			}
+			proxyDir, err := c.absPath(cmdConfig.source)
+			if err != nil {
+				fmt.Printf(
+					"%sCan't find the source directory: %s\n",
+					command.LogErrorPrefix,
+					err,
+				)
+				return 1
+			}
+			fmt.Printf(
+				"%sDownloading module: %s@%s\n",
+				command.LogInfoPrefix,
+				modPath,
+				version,
+			)
+			cmd = exec.Command("go", "mod", "download", modPath+"@"+version)
+			// fetch checked every file against go.sum already, and the
+			// checksum database can't be reached through a directory
+			cmd.Env = append(
+				os.Environ(),
+				"GOPROXY=file://"+proxyDir,
+				"GOSUMDB=off",
+				"GOFLAGS=-mod=mod",
+			)
+		default:
+			fmt.Printf(
+				"%sCaching dependency: %s\n",
+				command.LogInfoPrefix,
+				name,
+			)
+			cmd = exec.Command("npm", "cache", "add", archiveFilePath)
+		}
 		err = cmd.Run()
 		if err != nil {
 			fmt.Printf(
@@ -147,3 +231,16 @@ func (c *installCommand) Run(args []string) int {
 
 	return 0
 }
+
+// absPath returns dirPath relative to the working directory when it isn't
+// absolute already
+func (c *installCommand) absPath(dirPath string) (string, error) {
+	if path.IsAbs(dirPath) {
+		return dirPath, nil
+	}
+	cwd, err := c.os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	return path.Join(cwd, dirPath), nil
+}
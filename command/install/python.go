@@ -0,0 +1,63 @@
+package install
+
+import (
+	"bitbucket.org/bosgood/dep-get/command"
+	"bitbucket.org/bosgood/dep-get/lib/manifest"
+	"bitbucket.org/bosgood/dep-get/python"
+	"fmt"
+	"os/exec"
+)
+
+// installDistributions installs the distributions among archives with
+// one pip run finding them in the source directory. fetch may have
+// downloaded several files of a release, one for each target
+// environment, so pip is given the releases to install rather than the
+// files, and picks the file fitting the interpreter it runs on.
+func (c *installCommand) installDistributions(cmdConfig installCommandFlags, archives []string, fetched *manifest.Manifest) int {
+	var requirements []string
+	seen := make(map[string]bool)
+	for _, name := range archives {
+		project, version, ok := python.ParseDistributionFileName(name, "")
+		if !ok {
+			continue
+		}
+		if fetched.Omitted(name, cmdConfig.omit) {
+			fmt.Printf(
+				"%sOmitting dependency: %s\n",
+				command.LogInfoPrefix,
+				name,
+			)
+			continue
+		}
+		requirement := python.NormalizeName(project) + "==" + version
+		if seen[requirement] {
+			continue
+		}
+		seen[requirement] = true
+		fmt.Printf(
+			"%sInstalling dependency: %s\n",
+			command.LogInfoPrefix,
+			requirement,
+		)
+		requirements = append(requirements, requirement)
+	}
+	if len(requirements) == 0 {
+		return 0
+	}
+
+	findLinks := cmdConfig.source
+	if findLinks == "" {
+		findLinks = "."
+	}
+	// Every dependency is in the source directory already
+	args := append([]string{"install", "--no-index", "--no-deps", "--find-links", findLinks}, requirements...)
+	if err := exec.Command("pip", args...).Run(); err != nil {
+		fmt.Printf(
+			"%sError executing command: %s\n",
+			command.LogErrorPrefix,
+			err,
+		)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,73 @@
+package install
+
+import (
+	"bitbucket.org/bosgood/dep-get/command"
+	"bitbucket.org/bosgood/dep-get/lib/manifest"
+	"bitbucket.org/bosgood/dep-get/ruby"
+	"fmt"
+	"os/exec"
+	"path"
+	"strings"
+)
+
+// localGemPlatforms returns the platforms to install precompiled gems
+// for: the one given with --gem-platform, or those gem reports it runs
+// on, leaving out the pure ruby platform
+func localGemPlatforms(gemPlatform string) ([]string, error) {
+	if gemPlatform != "" {
+		return []string{gemPlatform}, nil
+	}
+	output, err := exec.Command("gem", "environment", "platform").Output()
+	if err != nil {
+		return nil, err
+	}
+	var platforms []string
+	for _, platform := range strings.Split(strings.TrimSpace(string(output)), ":") {
+		if platform != "" && platform != "ruby" {
+			platforms = append(platforms, platform)
+		}
+	}
+	return platforms, nil
+}
+
+// installGems installs one .gem file of each gem among archives. fetch
+// downloads the gems of every platform the lockfile was resolved for, so
+// only those built for this one, or the pure ruby gems where there are
+// none, are installed.
+func (c *installCommand) installGems(cmdConfig installCommandFlags, archives []string, fetched *manifest.Manifest) int {
+	platforms, err := localGemPlatforms(cmdConfig.gemPlatform)
+	if err != nil {
+		fmt.Printf(
+			"%sCan't find the platform gems are installed for, set --gem-platform: %s\n",
+			command.LogErrorPrefix,
+			err,
+		)
+		return 1
+	}
+
+	for _, name := range ruby.SelectGemFiles(archives, platforms) {
+		if fetched.Omitted(name, cmdConfig.omit) {
+			fmt.Printf(
+				"%sOmitting dependency: %s\n",
+				command.LogInfoPrefix,
+				name,
+			)
+			continue
+		}
+		fmt.Printf(
+			"%sInstalling dependency: %s\n",
+			command.LogInfoPrefix,
+			name,
+		)
+		cmd := exec.Command("gem", "install", "--local", "--ignore-dependencies", path.Join(cmdConfig.source, name))
+		if err := cmd.Run(); err != nil {
+			fmt.Printf(
+				"%sError executing command: %s\n",
+				command.LogErrorPrefix,
+				err,
+			)
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,83 @@
+package install
+
+import (
+	"bitbucket.org/bosgood/dep-get/command"
+	"bitbucket.org/bosgood/dep-get/lib/manifest"
+	"bitbucket.org/bosgood/dep-get/rust"
+	"fmt"
+	"path"
+)
+
+// vendoredCrate is a .crate file in the source directory
+type vendoredCrate struct {
+	fileName string
+	name     string
+	version  string
+}
+
+// vendorCrates unpacks the .crate files among archives into the vendor
+// directory the way cargo vendor does, so that cargo can build offline
+// once its crates.io source is replaced with the directory
+func (c *installCommand) vendorCrates(cmdConfig installCommandFlags, archives []string, fetched *manifest.Manifest) int {
+	var crates []vendoredCrate
+	versions := make(map[string]int)
+	for _, name := range archives {
+		crateName, version, ok := rust.ParseCrateFileName(name)
+		if !ok {
+			continue
+		}
+		if fetched.Omitted(name, cmdConfig.omit) {
+			fmt.Printf(
+				"%sOmitting dependency: %s\n",
+				command.LogInfoPrefix,
+				name,
+			)
+			continue
+		}
+		crates = append(crates, vendoredCrate{fileName: name, name: crateName, version: version})
+		versions[crateName]++
+	}
+
+	for _, crate := range crates {
+		fmt.Printf(
+			"%sVendoring dependency: %s\n",
+			command.LogInfoPrefix,
+			crate.fileName,
+		)
+		contents, err := c.os.ReadFile(path.Join(cmdConfig.source, crate.fileName))
+		if err != nil {
+			fmt.Printf(
+				"%sError reading crate: %s\n",
+				command.LogErrorPrefix,
+				err,
+			)
+			return 1
+		}
+		// cargo vendor names the directory after the crate alone unless
+		// several of its versions are locked
+		dstDir := path.Join(
+			cmdConfig.vendorDir,
+			rust.VendorDirName(crate.name, crate.version, versions[crate.name] > 1),
+		)
+		if err = rust.VendorCrate(c.os, contents, crate.name, crate.version, dstDir); err != nil {
+			fmt.Printf(
+				"%sError vendoring %s: %s\n",
+				command.LogErrorPrefix,
+				crate.fileName,
+				err,
+			)
+			return 1
+		}
+	}
+
+	fmt.Printf(
+		"%sVendored %d crates into %s. To build with them, add to .cargo/config.toml:\n\n"+
+			"[source.crates-io]\nreplace-with = \"vendored-sources\"\n\n"+
+			"[source.vendored-sources]\ndirectory = \"%s\"\n",
+		command.LogSuccessPrefix,
+		len(crates),
+		cmdConfig.vendorDir,
+		cmdConfig.vendorDir,
+	)
+	return 0
+}
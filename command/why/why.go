@@ -0,0 +1,308 @@
+package why
+
+import (
+	"bitbucket.org/bosgood/dep-get/command"
+	"bitbucket.org/bosgood/dep-get/lib/fs"
+	"bitbucket.org/bosgood/dep-get/nodejs"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/mitchellh/cli"
+	"os"
+	"path"
+	"strings"
+)
+
+type whyCommand struct {
+	os     fs.FileSystem
+	config whyCommandFlags
+}
+
+type whyCommandFlags struct {
+	command.BaseFlags
+	platform string
+	source   string
+	json     bool
+	// name and version are the package asked about, where an empty
+	// version matches every installed version
+	name    string
+	version string
+}
+
+// whyPackage explains one installed copy of a package
+type whyPackage struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	// Path is where the lockfile installs the package
+	Path string `json:"path"`
+	// Paths lead from the root project to the package, each starting
+	// with the root and ending with the package
+	Paths [][]whyStep `json:"paths"`
+	// Truncated is set when there may be more paths than Paths lists
+	Truncated bool `json:"truncated"`
+}
+
+// whyStep is one package on a path from the root project
+type whyStep struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+var realOS fs.FileSystem = &fs.OSFS{}
+
+func newWhyCommandWithFS(os fs.FileSystem) (cli.Command, error) {
+	cmd := &whyCommand{
+		os: os,
+	}
+	return cmd, nil
+}
+
+// NewWhyCommand is used to generate a command object
+// which explains why a package is among a project's dependencies
+func NewWhyCommand() (cli.Command, error) {
+	return newWhyCommandWithFS(realOS)
+}
+
+func (c *whyCommand) Synopsis() string {
+	return "Explains why a package is a dependency"
+}
+
+func (c *whyCommand) Help() string {
+	_, flagSet, _ := getConfig([]string{})
+	var defaults bytes.Buffer
+	flagSet.SetOutput(&defaults)
+	flagSet.PrintDefaults()
+	return "Usage: dep-get why [options] <package>[@version]\n\n" + defaults.String()
+}
+
+// splitPackageArg splits a package argument such as minimist@0.0.8 or
+// @babel/core into its name and version, taking care of the scope's
+// leading @
+func splitPackageArg(arg string) (string, string) {
+	i := strings.LastIndex(arg, "@")
+	if i <= 0 {
+		return arg, ""
+	}
+	return arg[:i], arg[i+1:]
+}
+
+func getConfig(args []string) (whyCommandFlags, *flag.FlagSet, error) {
+	var cmdConfig whyCommandFlags
+
+	cmdFlags := flag.NewFlagSet("why", flag.ExitOnError)
+	cmdFlags.BoolVar(&cmdConfig.Help, "help", false, "show command help")
+	cmdFlags.StringVar(&cmdConfig.platform, "platform", "nodejs", "platform type (allowed: nodejs)")
+	cmdFlags.StringVar(&cmdConfig.source, "source", "", "project directory (default: .)")
+	cmdFlags.BoolVar(&cmdConfig.json, "json", false, "print the paths as JSON")
+
+	// Flags may come before or after the package
+	var positional []string
+	for {
+		if err := cmdFlags.Parse(args); err != nil {
+			errMsg := fmt.Sprintf(
+				"%s%s: %s\n",
+				command.LogErrorPrefix,
+				"Error parsing args",
+				err,
+			)
+			return cmdConfig, cmdFlags, &command.ConfigError{
+				Explanation: errMsg,
+			}
+		}
+		if cmdFlags.NArg() == 0 {
+			break
+		}
+		positional = append(positional, cmdFlags.Arg(0))
+		args = cmdFlags.Args()[1:]
+	}
+
+	if cmdConfig.Help {
+		return cmdConfig, cmdFlags, &command.ConfigError{}
+	}
+
+	if len(positional) != 1 {
+		errMsg := fmt.Sprintf(
+			"%s%s\n",
+			command.LogErrorPrefix,
+			"Expected exactly one package",
+		)
+		return cmdConfig, cmdFlags, &command.ConfigError{
+			Explanation: errMsg,
+		}
+	}
+	cmdConfig.name, cmdConfig.version = splitPackageArg(positional[0])
+
+	if cmdConfig.platform != "nodejs" {
+		errMsg := fmt.Sprintf(
+			"%s%s\n",
+			command.LogErrorPrefix,
+			"Only nodejs supported at the moment",
+		)
+		return cmdConfig, cmdFlags, &command.ConfigError{
+			Explanation: errMsg,
+		}
+	}
+
+	return cmdConfig, cmdFlags, nil
+}
+
+// explain lists every installed copy of the named package matching
+// version, if given, along with the paths leading to it
+func explain(graph *nodejs.DependencyGraph, name, version string) []whyPackage {
+	var packages []whyPackage
+	for _, node := range graph.Find(name) {
+		if version != "" && node.Version != version {
+			continue
+		}
+		pkg := whyPackage{
+			Name:    node.Name,
+			Version: node.Version,
+			Path:    node.Path,
+			Paths:   [][]whyStep{},
+		}
+		nodePaths, truncated := graph.PathsTo(node)
+		pkg.Truncated = truncated
+		for _, nodePath := range nodePaths {
+			var steps []whyStep
+			for _, step := range nodePath {
+				steps = append(steps, whyStep{step.Name, step.Version})
+			}
+			pkg.Paths = append(pkg.Paths, steps)
+		}
+		packages = append(packages, pkg)
+	}
+	return packages
+}
+
+// describe formats a path from the root project as a > b@1.0.0 > c@2.0.0
+func describe(steps []whyStep) string {
+	var names []string
+	for i, step := range steps {
+		name := step.Name
+		if i == 0 && name == "" {
+			name = "(project)"
+		}
+		if step.Version != "" {
+			name += "@" + step.Version
+		}
+		names = append(names, name)
+	}
+	return strings.Join(names, " > ")
+}
+
+func (c *whyCommand) Run(args []string) int {
+	cmdConfig, _, err := getConfig(args)
+	if err != nil {
+		errMsg := err.Error()
+		if errMsg != "" {
+			fmt.Print(err.Error())
+		}
+		return cli.RunResultHelp
+	}
+
+	c.config = cmdConfig
+
+	var dirPath string
+	if cmdConfig.source == "" {
+		cwd, err := c.os.Getwd()
+		if err != nil {
+			fmt.Printf(
+				"%s%s: %s\n",
+				command.LogErrorPrefix,
+				"Can't read current directory",
+				err,
+			)
+			return 1
+		}
+		dirPath = cwd
+	} else {
+		dirPath = cmdConfig.source
+	}
+
+	packageFilePath, packageFileContents, err := nodejs.FindDependenciesFile(c.os, dirPath)
+	if err != nil {
+		fmt.Printf(
+			"%s%s: %s\n",
+			command.LogErrorPrefix,
+			"Can't open the dependencies file",
+			err,
+		)
+		return 1
+	}
+
+	graph, err := nodejs.ParseDependencyGraph(path.Base(packageFilePath), packageFileContents)
+	if err != nil {
+		fmt.Printf(
+			"%s%s: %s\n",
+			command.LogErrorPrefix,
+			"Failed to decode the dependencies file",
+			err,
+		)
+		return 1
+	}
+
+	// Older lockfiles leave the project's own dependencies to package.json
+	packageJSON, err := nodejs.ReadPackageJSON(c.os, dirPath)
+	if err == nil {
+		graph.LinkProject(packageJSON)
+	} else if !os.IsNotExist(err) {
+		fmt.Printf(
+			"%s%s: %s\n",
+			command.LogErrorPrefix,
+			"Can't read package.json",
+			err,
+		)
+		return 1
+	}
+
+	packages := explain(graph, cmdConfig.name, cmdConfig.version)
+
+	if cmdConfig.json {
+		if packages == nil {
+			packages = []whyPackage{}
+		}
+		out, err := json.MarshalIndent(packages, "", "  ")
+		if err != nil {
+			fmt.Printf("%s%s\n", command.LogErrorPrefix, err)
+			return 1
+		}
+		fmt.Println(string(out))
+		if len(packages) == 0 {
+			return 1
+		}
+		return 0
+	}
+
+	if len(packages) == 0 {
+		name := cmdConfig.name
+		if cmdConfig.version != "" {
+			name += "@" + cmdConfig.version
+		}
+		fmt.Printf(
+			"%s%s isn't in %s\n",
+			command.LogErrorPrefix,
+			name,
+			packageFilePath,
+		)
+		return 1
+	}
+
+	for _, pkg := range packages {
+		fmt.Printf(
+			"%s%s@%s is installed at %s by:\n",
+			command.LogInfoPrefix,
+			pkg.Name,
+			pkg.Version,
+			pkg.Path,
+		)
+		for _, steps := range pkg.Paths {
+			fmt.Printf("  %s\n", describe(steps))
+		}
+		if pkg.Truncated {
+			fmt.Printf("  (only the %d shortest paths found are shown; there may be more)\n", len(pkg.Paths))
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,144 @@
+package why
+
+import (
+	"bitbucket.org/bosgood/dep-get/lib/fs"
+	"bitbucket.org/bosgood/dep-get/nodejs"
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+)
+
+var packageLock = []byte(`{
+  "name": "my-app",
+  "version": "1.0.0",
+  "lockfileVersion": 3,
+  "packages": {
+    "": {
+      "name": "my-app",
+      "version": "1.0.0",
+      "dependencies": {
+        "mkdirp": "^0.5.1",
+        "optimist": "^0.6.1"
+      }
+    },
+    "node_modules/minimist": {
+      "version": "1.2.8"
+    },
+    "node_modules/mkdirp": {
+      "version": "0.5.1",
+      "dependencies": {
+        "minimist": "0.0.8"
+      }
+    },
+    "node_modules/mkdirp/node_modules/minimist": {
+      "version": "0.0.8"
+    },
+    "node_modules/optimist": {
+      "version": "0.6.1",
+      "dependencies": {
+        "minimist": "~1.2.0",
+        "mkdirp": "^0.5.0"
+      }
+    }
+  }
+}`)
+
+func TestNewWhyCommand(t *testing.T) {
+	cmd, err := NewWhyCommand()
+	if err != nil {
+		t.Errorf("err: %s", err)
+	}
+	if cmd.Synopsis() == "" {
+		t.Errorf("Err: No synopsis text")
+	}
+	if cmd.Help() == "" {
+		t.Errorf("Err: No help text")
+	}
+}
+
+func TestGetConfig(t *testing.T) {
+	tests := []struct {
+		args    []string
+		name    string
+		version string
+		json    bool
+	}{
+		{[]string{"minimist"}, "minimist", "", false},
+		{[]string{"--json", "minimist@0.0.8"}, "minimist", "0.0.8", true},
+		{[]string{"@babel/core", "--json"}, "@babel/core", "", true},
+		{[]string{"@babel/core@7.0.0", "--source", "app"}, "@babel/core", "7.0.0", false},
+	}
+	for _, test := range tests {
+		cmdConfig, _, err := getConfig(test.args)
+		if err != nil {
+			t.Errorf("%v: %s", test.args, err)
+			continue
+		}
+		if cmdConfig.name != test.name || cmdConfig.version != test.version || cmdConfig.json != test.json {
+			t.Errorf("%v: expected %s@%s json %t, got %+v", test.args, test.name, test.version, test.json, cmdConfig)
+		}
+	}
+
+	for _, args := range [][]string{{}, {"a", "b"}, {"--platform", "python", "a"}} {
+		if _, _, err := getConfig(args); err == nil {
+			t.Errorf("%v: expected an error", args)
+		}
+	}
+}
+
+func TestExplain(t *testing.T) {
+	graph, err := nodejs.ParseDependencyGraph(nodejs.PackageLockFileName, packageLock)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	packages := explain(graph, "minimist", "0.0.8")
+	expected := []whyPackage{
+		{
+			Name:    "minimist",
+			Version: "0.0.8",
+			Path:    "node_modules/mkdirp/node_modules/minimist",
+			Paths: [][]whyStep{
+				{{"my-app", "1.0.0"}, {"mkdirp", "0.5.1"}, {"minimist", "0.0.8"}},
+				{{"my-app", "1.0.0"}, {"optimist", "0.6.1"}, {"mkdirp", "0.5.1"}, {"minimist", "0.0.8"}},
+			},
+		},
+	}
+	if !reflect.DeepEqual(packages, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, packages)
+	}
+	if described := describe(packages[0].Paths[1]); described != "my-app@1.0.0 > optimist@0.6.1 > mkdirp@0.5.1 > minimist@0.0.8" {
+		t.Errorf("Unexpected description: %s", described)
+	}
+
+	if packages := explain(graph, "minimist", ""); len(packages) != 2 {
+		t.Errorf("Expected both copies of minimist, got %+v", packages)
+	}
+	if packages := explain(graph, "left-pad", ""); len(packages) != 0 {
+		t.Errorf("Expected no packages, got %+v", packages)
+	}
+}
+
+func TestRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dep-get-why")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(path.Join(dir, nodejs.PackageLockFileName), packageLock, 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	cmd, _ := newWhyCommandWithFS(&fs.OSFS{})
+	if status := cmd.Run([]string{"--source", dir, "minimist@0.0.8"}); status != 0 {
+		t.Errorf("Expected status 0, got %d", status)
+	}
+	if status := cmd.Run([]string{"--source", dir, "--json", "mkdirp"}); status != 0 {
+		t.Errorf("Expected status 0, got %d", status)
+	}
+	if status := cmd.Run([]string{"--source", dir, "left-pad"}); status != 1 {
+		t.Errorf("Expected status 1 for a missing package, got %d", status)
+	}
+}
@@ -0,0 +1,85 @@
+package golang
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+)
+
+// hash1 computes the h1: hash go.sum records for a set of files: the
+// base64 sha256 of a summary listing the sha256 of each file and its
+// name, sorted by name
+func hash1(names []string, open func(string) (io.ReadCloser, error)) (string, error) {
+	sorted := append([]string(nil), names...)
+	sort.Strings(sorted)
+
+	summary := sha256.New()
+	for _, name := range sorted {
+		if strings.Contains(name, "\n") {
+			return "", fmt.Errorf("File name %q holds a newline", name)
+		}
+		r, err := open(name)
+		if err != nil {
+			return "", err
+		}
+		fileHash := sha256.New()
+		_, err = io.Copy(fileHash, r)
+		r.Close()
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(summary, "%x  %s\n", fileHash.Sum(nil), name)
+	}
+	return "h1:" + base64.StdEncoding.EncodeToString(summary.Sum(nil)), nil
+}
+
+// HashGoMod returns the h1: hash of a module's go.mod file
+func HashGoMod(contents []byte) (string, error) {
+	return hash1([]string{GoModFileName}, func(string) (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(contents)), nil
+	})
+}
+
+// HashZip returns the h1: hash of a module zip, whose files are all
+// under a module@version/ directory
+func HashZip(contents []byte) (string, error) {
+	z, err := zip.NewReader(bytes.NewReader(contents), int64(len(contents)))
+	if err != nil {
+		return "", err
+	}
+	files := make(map[string]*zip.File)
+	var names []string
+	for _, file := range z.File {
+		if _, ok := files[file.Name]; ok {
+			return "", fmt.Errorf("%s is in the zip twice", file.Name)
+		}
+		files[file.Name] = file
+		names = append(names, file.Name)
+	}
+	return hash1(names, func(name string) (io.ReadCloser, error) {
+		return files[name].Open()
+	})
+}
+
+// verifyHash checks the contents of r with hashFunc against the h1: hash
+// go.sum records
+func verifyHash(r io.Reader, expected string, hashFunc func([]byte) (string, error)) error {
+	contents, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	actual, err := hashFunc(contents)
+	if err != nil {
+		return err
+	}
+	if actual != expected {
+		return fmt.Errorf("Checksum %s doesn't match go.sum's %s", actual, expected)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package golang
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const (
+	testGoMod   = "module example.com/hello\n\ngo 1.21\n"
+	testGoFile  = "package hello\n\nfunc Hello() string { return \"hello\" }\n"
+	testZipHash = "h1:BMZLHt1FA6FLPC+H4ZSxZSphx9O63MG/XrVlfD3weYw="
+	testModHash = "h1:NnGvEkTHyKKlgPcQSue0skqyqiS1EfBSAh+0WaWZYmE="
+)
+
+// testModuleZip returns the zip of example.com/hello@v1.0.0
+func testModuleZip(t *testing.T) []byte {
+	var buf bytes.Buffer
+	z := zip.NewWriter(&buf)
+	// Hashes don't depend on the order files are zipped in
+	for _, file := range []struct{ name, contents string }{
+		{"example.com/hello@v1.0.0/hello.go", testGoFile},
+		{"example.com/hello@v1.0.0/go.mod", testGoMod},
+	} {
+		w, err := z.Create(file.name)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if _, err = w.Write([]byte(file.contents)); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+	if err := z.Close(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	return buf.Bytes()
+}
+
+func TestHashZip(t *testing.T) {
+	hash, err := HashZip(testModuleZip(t))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if hash != testZipHash {
+		t.Errorf("Expected %s, got %s", testZipHash, hash)
+	}
+
+	if _, err = HashZip([]byte("<html>Not found</html>")); err == nil {
+		t.Errorf("Expected an error hashing something other than a zip")
+	}
+}
+
+func TestHashGoMod(t *testing.T) {
+	hash, err := HashGoMod([]byte(testGoMod))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if hash != testModHash {
+		t.Errorf("Expected %s, got %s", testModHash, hash)
+	}
+}
+
+func TestValidateModuleFiles(t *testing.T) {
+	mod := GoModule{Path: "example.com/hello", Version: "v1.0.0", Hash: testZipHash, ModHash: testModHash}
+	if err := mod.ValidateZip(bytes.NewReader(testModuleZip(t))); err != nil {
+		t.Errorf("err: %s", err)
+	}
+	if err := mod.ValidateGoMod(strings.NewReader(testGoMod)); err != nil {
+		t.Errorf("err: %s", err)
+	}
+	if err := mod.ValidateInfo(strings.NewReader(`{"Version":"v1.0.0","Time":"2023-08-01T00:00:00Z"}`)); err != nil {
+		t.Errorf("err: %s", err)
+	}
+
+	err := mod.ValidateGoMod(strings.NewReader(testGoMod + "require example.com/evil v1.0.0\n"))
+	if err == nil || !strings.Contains(err.Error(), "doesn't match go.sum") {
+		t.Errorf("Expected a checksum mismatch, got %v", err)
+	}
+	if err = mod.ValidateInfo(strings.NewReader(`{"Version":"v1.0.1"}`)); err == nil {
+		t.Errorf("Expected an error for another version's info")
+	}
+}
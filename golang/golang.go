@@ -0,0 +1,113 @@
+package golang
+
+import (
+	"bitbucket.org/bosgood/dep-get/lib/fs"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+// GoModule is a module version whose checksums go.sum records. Hash is
+// the h1: hash of its zip, which go.sum only has for modules providing
+// packages to the build, and ModHash that of its go.mod file, which it
+// has for every module in the build's module graph.
+type GoModule struct {
+	Path    string
+	Version string
+	Hash    string
+	ModHash string
+}
+
+// GetCanonicalName returns a unique name for the module version, as the
+// go command writes it
+func (m *GoModule) GetCanonicalName() string {
+	return m.Path + "@" + m.Version
+}
+
+// ValidateZip checks that r is the module's zip, matching go.sum
+func (m *GoModule) ValidateZip(r io.Reader) error {
+	return verifyHash(r, m.Hash, HashZip)
+}
+
+// ValidateGoMod checks that r is the module's go.mod file, matching
+// go.sum when it has its hash
+func (m *GoModule) ValidateGoMod(r io.Reader) error {
+	if m.ModHash == "" {
+		return nil
+	}
+	return verifyHash(r, m.ModHash, HashGoMod)
+}
+
+// ValidateInfo checks that r is the .info file of the module's version
+func (m *GoModule) ValidateInfo(r io.Reader) error {
+	return validateInfo(m.Version, r)
+}
+
+// DependenciesFile holds the modules a go.sum file records checksums
+// for, along with the go.mod file next to it
+type DependenciesFile struct {
+	Path    string
+	GoMod   *GoMod
+	Modules []GoModule
+	// LocalReplaces are the replace directives pointing to directories on
+	// disk, which there's nothing to download for
+	LocalReplaces []GoReplace
+}
+
+// checkRequires makes sure go.sum has checksums for every module go.mod
+// requires, or whatever replaces them
+func (f *DependenciesFile) checkRequires() error {
+	summed := make(map[string]bool)
+	for _, mod := range f.Modules {
+		summed[mod.GetCanonicalName()] = true
+	}
+
+	var missing []string
+	for _, require := range f.GoMod.Requires {
+		modPath, version, local := f.GoMod.Replace(require.Path, require.Version)
+		if local || summed[modPath+"@"+version] {
+			continue
+		}
+		missing = append(missing, modPath+"@"+version)
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("Missing %s entry for %s (run go mod tidy)", GoSumFileName, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// ReadDependencies reads the go.mod and go.sum files in dirPath. go.sum
+// may only be missing when the module has no dependencies.
+func ReadDependencies(fileSystem fs.FileSystem, dirPath string) (*DependenciesFile, error) {
+	goModPath := path.Join(dirPath, GoModFileName)
+	contents, err := fileSystem.ReadFile(goModPath)
+	if err != nil {
+		return nil, err
+	}
+	goMod, err := ParseGoMod(contents)
+	if err != nil {
+		return nil, err
+	}
+
+	goSumPath := path.Join(dirPath, GoSumFileName)
+	file := &DependenciesFile{Path: goSumPath, GoMod: goMod}
+	contents, err = fileSystem.ReadFile(goSumPath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		file.Modules, err = ParseGoSum(contents)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, replace := range goMod.Replaces {
+		if replace.NewVersion == "" {
+			file.LocalReplaces = append(file.LocalReplaces, replace)
+		}
+	}
+	return file, file.checkRequires()
+}
@@ -0,0 +1,49 @@
+package golang
+
+import (
+	"bitbucket.org/bosgood/dep-get/lib/fs"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestReadDependencies(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dep-get-golang")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	goMod := `module example.com/service
+
+require (
+	example.com/hello v1.0.0
+	example.com/shared v0.0.0
+)
+
+replace example.com/shared => ../shared
+`
+	if err = ioutil.WriteFile(path.Join(dir, GoModFileName), []byte(goMod), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err = ReadDependencies(&fs.OSFS{}, dir); err == nil || !strings.Contains(err.Error(), "Missing go.sum entry for example.com/hello@v1.0.0") {
+		t.Errorf("Expected a missing go.sum entry error, got %v", err)
+	}
+
+	goSum := "example.com/hello v1.0.0 " + testZipHash + "\nexample.com/hello v1.0.0/go.mod " + testModHash + "\n"
+	if err = ioutil.WriteFile(path.Join(dir, GoSumFileName), []byte(goSum), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	file, err := ReadDependencies(&fs.OSFS{}, dir)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if file.Path != path.Join(dir, GoSumFileName) || len(file.Modules) != 1 || file.Modules[0].Hash != testZipHash {
+		t.Errorf("Unexpected dependencies %+v", file)
+	}
+	if len(file.LocalReplaces) != 1 || file.LocalReplaces[0].NewPath != "../shared" {
+		t.Errorf("Expected the local replacement, got %+v", file.LocalReplaces)
+	}
+}
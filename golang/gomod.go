@@ -0,0 +1,153 @@
+package golang
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// GoModFileName is the name of a Go module's definition file
+const GoModFileName = "go.mod"
+
+// GoMod represents the parts of a go.mod file fetch needs: the modules it
+// requires and those replacing them
+type GoMod struct {
+	Module   string
+	Go       string
+	Requires []GoRequire
+	Replaces []GoReplace
+}
+
+// GoRequire is a module required by a require directive. Indirect ones
+// are only needed by other dependencies.
+type GoRequire struct {
+	Path     string
+	Version  string
+	Indirect bool
+}
+
+// GoReplace is a replace directive. OldVersion is empty when every
+// version is replaced, and NewVersion when the replacement is a
+// directory on disk.
+type GoReplace struct {
+	OldPath    string
+	OldVersion string
+	NewPath    string
+	NewVersion string
+}
+
+// Replace returns the module replacing path at version, and whether it
+// is a directory on disk
+func (m *GoMod) Replace(path, version string) (string, string, bool) {
+	for _, replace := range m.Replaces {
+		if replace.OldPath == path && replace.OldVersion == version {
+			return replace.NewPath, replace.NewVersion, replace.NewVersion == ""
+		}
+	}
+	for _, replace := range m.Replaces {
+		if replace.OldPath == path && replace.OldVersion == "" {
+			return replace.NewPath, replace.NewVersion, replace.NewVersion == ""
+		}
+	}
+	return path, version, false
+}
+
+// goModFields splits a go.mod line into its fields, unquoting quoted
+// ones and dropping the comment, which is returned separately
+func goModFields(line string) ([]string, string, error) {
+	comment := ""
+	if i := strings.Index(line, "//"); i >= 0 {
+		comment = strings.TrimSpace(line[i+2:])
+		line = line[:i]
+	}
+	var fields []string
+	for _, field := range strings.Fields(line) {
+		if strings.HasPrefix(field, `"`) || strings.HasPrefix(field, "`") {
+			unquoted, err := strconv.Unquote(field)
+			if err != nil {
+				return nil, "", fmt.Errorf("Malformed quoted string %s", field)
+			}
+			field = unquoted
+		}
+		fields = append(fields, field)
+	}
+	return fields, comment, nil
+}
+
+// parseGoModDirective adds the directive verb with the arguments fields
+// to goMod
+func parseGoModDirective(goMod *GoMod, verb string, fields []string, comment string) error {
+	switch verb {
+	case "module":
+		if len(fields) != 1 {
+			return fmt.Errorf("Usage: module path")
+		}
+		goMod.Module = fields[0]
+	case "go":
+		if len(fields) != 1 {
+			return fmt.Errorf("Usage: go 1.21")
+		}
+		goMod.Go = fields[0]
+	case "require":
+		if len(fields) != 2 {
+			return fmt.Errorf("Usage: require module/path v1.2.3")
+		}
+		goMod.Requires = append(goMod.Requires, GoRequire{
+			Path:     fields[0],
+			Version:  fields[1],
+			Indirect: comment == "indirect" || strings.HasPrefix(comment, "indirect;"),
+		})
+	case "replace":
+		arrow := -1
+		for i, field := range fields {
+			if field == "=>" {
+				arrow = i
+			}
+		}
+		if arrow < 1 || arrow > 2 || len(fields)-arrow-1 < 1 || len(fields)-arrow-1 > 2 {
+			return fmt.Errorf("Usage: replace module/path [v1.2.3] => other/module v1.4.5 or ../local/directory")
+		}
+		replace := GoReplace{OldPath: fields[0], NewPath: fields[arrow+1]}
+		if arrow == 2 {
+			replace.OldVersion = fields[1]
+		}
+		if len(fields) == arrow+3 {
+			replace.NewVersion = fields[arrow+2]
+		}
+		goMod.Replaces = append(goMod.Replaces, replace)
+	}
+	// Other directives, such as exclude, retract and toolchain, don't
+	// change what's downloaded
+	return nil
+}
+
+// ParseGoMod parses the contents of a go.mod file
+func ParseGoMod(contents []byte) (*GoMod, error) {
+	goMod := &GoMod{}
+	block := ""
+	for i, line := range strings.Split(string(contents), "\n") {
+		fields, comment, err := goModFields(line)
+		if err == nil && block != "" {
+			if len(fields) == 1 && fields[0] == ")" {
+				block = ""
+				continue
+			}
+			if len(fields) > 0 {
+				err = parseGoModDirective(goMod, block, fields, comment)
+			}
+		} else if err == nil && len(fields) > 0 {
+			if len(fields) == 2 && fields[1] == "(" {
+				block = fields[0]
+				continue
+			}
+			err = parseGoModDirective(goMod, fields[0], fields[1:], comment)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %s", GoModFileName, i+1, err)
+		}
+	}
+	if block != "" {
+		return nil, fmt.Errorf("%s: Unterminated %s block", GoModFileName, block)
+	}
+	return goMod, nil
+}
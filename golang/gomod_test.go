@@ -0,0 +1,94 @@
+package golang
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var goMod = []byte(`// The service
+module example.com/service
+
+go 1.21
+
+toolchain go1.21.3
+
+require github.com/google/uuid v1.3.1
+
+require (
+	golang.org/x/text v0.13.0
+	"gopkg.in/yaml.v3" v3.0.1 // indirect
+	github.com/BurntSushi/toml v1.3.2 // indirect; used by the config loader
+)
+
+replace (
+	golang.org/x/text v0.13.0 => golang.org/x/text v0.14.0
+	example.com/shared => ../shared
+)
+
+exclude golang.org/x/net v0.1.0
+`)
+
+func TestParseGoMod(t *testing.T) {
+	parsed, err := ParseGoMod(goMod)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	expected := &GoMod{
+		Module: "example.com/service",
+		Go:     "1.21",
+		Requires: []GoRequire{
+			{Path: "github.com/google/uuid", Version: "v1.3.1"},
+			{Path: "golang.org/x/text", Version: "v0.13.0"},
+			{Path: "gopkg.in/yaml.v3", Version: "v3.0.1", Indirect: true},
+			{Path: "github.com/BurntSushi/toml", Version: "v1.3.2", Indirect: true},
+		},
+		Replaces: []GoReplace{
+			{OldPath: "golang.org/x/text", OldVersion: "v0.13.0", NewPath: "golang.org/x/text", NewVersion: "v0.14.0"},
+			{OldPath: "example.com/shared", NewPath: "../shared"},
+		},
+	}
+	if !reflect.DeepEqual(parsed, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, parsed)
+	}
+
+	tests := []struct {
+		path, version       string
+		newPath, newVersion string
+		local               bool
+	}{
+		{"golang.org/x/text", "v0.13.0", "golang.org/x/text", "v0.14.0", false},
+		{"golang.org/x/text", "v0.12.0", "golang.org/x/text", "v0.12.0", false},
+		{"example.com/shared", "v0.0.0", "../shared", "", true},
+	}
+	for _, test := range tests {
+		newPath, newVersion, local := parsed.Replace(test.path, test.version)
+		if newPath != test.newPath || newVersion != test.newVersion || local != test.local {
+			t.Errorf(
+				"Expected %s@%s to be replaced by %s@%s (local: %t), got %s@%s (local: %t)",
+				test.path, test.version,
+				test.newPath, test.newVersion, test.local,
+				newPath, newVersion, local,
+			)
+		}
+	}
+}
+
+func TestParseGoModErrors(t *testing.T) {
+	tests := []struct {
+		contents string
+		expected string
+	}{
+		{"module a b\n", "go.mod:1: Usage: module"},
+		{"module a\nrequire example.com/a\n", "go.mod:2: Usage: require"},
+		{"require (\n\texample.com/a v1.0.0\n", "Unterminated require block"},
+		{"replace example.com/a => \n", "Usage: replace"},
+		{"module \"a\n", "Malformed quoted string"},
+	}
+	for _, test := range tests {
+		_, err := ParseGoMod([]byte(test.contents))
+		if err == nil || !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("Expected an error containing %q parsing %q, got %v", test.expected, test.contents, err)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package golang
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// GoSumFileName is the name of the file holding the checksums of a Go
+// module's dependencies
+const GoSumFileName = "go.sum"
+
+// ParseGoSum parses the contents of a go.sum file into the modules it
+// holds checksums for, in the order of their paths and versions. Lines
+// for a module's zip give its Hash and lines for its go.mod file, whose
+// version ends in /go.mod, its ModHash.
+func ParseGoSum(contents []byte) ([]GoModule, error) {
+	modules := make(map[string]*GoModule)
+	for i, line := range strings.Split(string(contents), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("%s:%d: Malformed line (expected module version hash)", GoSumFileName, i+1)
+		}
+		if !strings.HasPrefix(fields[2], "h1:") {
+			// Newer hash algorithms aren't something to check against
+			continue
+		}
+
+		version := strings.TrimSuffix(fields[1], "/go.mod")
+		key := fields[0] + "@" + version
+		mod, ok := modules[key]
+		if !ok {
+			mod = &GoModule{Path: fields[0], Version: version}
+			modules[key] = mod
+		}
+		if version != fields[1] {
+			mod.ModHash = fields[2]
+		} else {
+			mod.Hash = fields[2]
+		}
+	}
+
+	var sorted []GoModule
+	for _, mod := range modules {
+		sorted = append(sorted, *mod)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Path != sorted[j].Path {
+			return sorted[i].Path < sorted[j].Path
+		}
+		return sorted[i].Version < sorted[j].Version
+	})
+	return sorted, nil
+}
@@ -0,0 +1,46 @@
+package golang
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseGoSum(t *testing.T) {
+	contents := []byte(`golang.org/x/text v0.14.0 h1:ScX5w1eTa3QqT8oi6+ziP7dTV1S2+ALU0bI+0zXKWiQ=
+golang.org/x/text v0.14.0/go.mod h1:18ZOQIKpY8NJVqYksKHtTdi31H5itFRjB5/qKTNYzSU=
+github.com/google/uuid v1.3.1 h1:KjJaJ9iWZ3jOFZIf1Lqf4laDRCasjl0BCmnEGxkdLb4=
+github.com/google/uuid v1.3.1/go.mod h1:TIyPZe4MgqvfeYDBFedMoGGpEw/LqOeaOT+nhxU+yHo=
+
+gopkg.in/check.v1 v0.0.0-20161208181325-20d25e280405/go.mod h1:Co6ibVJAznAaIkqp8huTwlJQCZ016jof/cbN4VW5Yz0=
+`)
+	modules, err := ParseGoSum(contents)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	expected := []GoModule{
+		{
+			Path:    "github.com/google/uuid",
+			Version: "v1.3.1",
+			Hash:    "h1:KjJaJ9iWZ3jOFZIf1Lqf4laDRCasjl0BCmnEGxkdLb4=",
+			ModHash: "h1:TIyPZe4MgqvfeYDBFedMoGGpEw/LqOeaOT+nhxU+yHo=",
+		},
+		{
+			Path:    "golang.org/x/text",
+			Version: "v0.14.0",
+			Hash:    "h1:ScX5w1eTa3QqT8oi6+ziP7dTV1S2+ALU0bI+0zXKWiQ=",
+			ModHash: "h1:18ZOQIKpY8NJVqYksKHtTdi31H5itFRjB5/qKTNYzSU=",
+		},
+		{
+			Path:    "gopkg.in/check.v1",
+			Version: "v0.0.0-20161208181325-20d25e280405",
+			ModHash: "h1:Co6ibVJAznAaIkqp8huTwlJQCZ016jof/cbN4VW5Yz0=",
+		},
+	}
+	if !reflect.DeepEqual(modules, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, modules)
+	}
+
+	if _, err = ParseGoSum([]byte("golang.org/x/text v0.14.0\n")); err == nil {
+		t.Errorf("Expected an error for a line without a hash")
+	}
+}
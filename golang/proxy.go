@@ -0,0 +1,133 @@
+package golang
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultProxyURL is the Go module mirror run by Google
+const DefaultProxyURL = "https://proxy.golang.org"
+
+// ProxyURL returns the first proxy a GOPROXY setting lists, falling back
+// to the default one when it lists none, or only direct or off
+func ProxyURL(goproxy string) string {
+	for _, entry := range strings.FieldsFunc(goproxy, func(r rune) bool { return r == ',' || r == '|' }) {
+		entry = strings.TrimSpace(entry)
+		if entry != "" && entry != "direct" && entry != "off" {
+			return strings.TrimSuffix(entry, "/")
+		}
+	}
+	return DefaultProxyURL
+}
+
+// EscapePath escapes a module path or version for the GOPROXY protocol,
+// which writes each upper case letter as ! followed by the lower case
+// one, so paths differing only in case don't collide on case insensitive
+// file systems
+func EscapePath(path string) (string, error) {
+	var escaped strings.Builder
+	for _, r := range path {
+		if r == '!' || r >= utf8.RuneSelf {
+			return "", fmt.Errorf("Invalid character %q in %s", r, path)
+		}
+		if 'A' <= r && r <= 'Z' {
+			escaped.WriteByte('!')
+			r += 'a' - 'A'
+		}
+		escaped.WriteRune(r)
+	}
+	return escaped.String(), nil
+}
+
+// UnescapePath reverses EscapePath
+func UnescapePath(escaped string) (string, error) {
+	var path strings.Builder
+	bang := false
+	for _, r := range escaped {
+		switch {
+		case bang && 'a' <= r && r <= 'z':
+			path.WriteRune(r - ('a' - 'A'))
+			bang = false
+		case bang || 'A' <= r && r <= 'Z':
+			return "", fmt.Errorf("Invalid escaped path %s", escaped)
+		case r == '!':
+			bang = true
+		default:
+			path.WriteRune(r)
+		}
+	}
+	if bang {
+		return "", fmt.Errorf("Invalid escaped path %s", escaped)
+	}
+	return path.String(), nil
+}
+
+// ProxyFilePath returns where a GOPROXY serves one of the files of a
+// module version, such as golang.org/x/text/@v/v0.3.0.zip for the .zip
+// extension
+func ProxyFilePath(path, version, extension string) (string, error) {
+	escapedPath, err := EscapePath(path)
+	if err != nil {
+		return "", err
+	}
+	escapedVersion, err := EscapePath(version)
+	if err != nil {
+		return "", err
+	}
+	return escapedPath + "/@v/" + escapedVersion + extension, nil
+}
+
+// ListFilePath returns where a GOPROXY lists the versions of a module
+func ListFilePath(path string) (string, error) {
+	escapedPath, err := EscapePath(path)
+	if err != nil {
+		return "", err
+	}
+	return escapedPath + "/@v/list", nil
+}
+
+// ParseProxyFilePath reverses ProxyFilePath, returning the module path,
+// version and extension of a file in a GOPROXY's directory structure
+func ParseProxyFilePath(filePath string) (string, string, string, bool) {
+	i := strings.LastIndex(filePath, "/@v/")
+	if i < 0 {
+		return "", "", "", false
+	}
+	fileName := filePath[i+len("/@v/"):]
+	for _, extension := range []string{".info", ".mod", ".zip"} {
+		if !strings.HasSuffix(fileName, extension) {
+			continue
+		}
+		path, err := UnescapePath(filePath[:i])
+		if err != nil {
+			return "", "", "", false
+		}
+		version, err := UnescapePath(strings.TrimSuffix(fileName, extension))
+		if err != nil {
+			return "", "", "", false
+		}
+		return path, version, extension, true
+	}
+	return "", "", "", false
+}
+
+// ModuleInfo is the JSON a GOPROXY serves as a version's .info file
+type ModuleInfo struct {
+	Version string
+	Time    string
+}
+
+// validateInfo checks that r is the .info file of version
+func validateInfo(version string, r io.Reader) error {
+	var info ModuleInfo
+	if err := json.NewDecoder(r).Decode(&info); err != nil {
+		return fmt.Errorf("Not a version info file: %s", err)
+	}
+	if info.Version != version {
+		return fmt.Errorf("Describes version %s", info.Version)
+	}
+	return nil
+}
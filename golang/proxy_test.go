@@ -0,0 +1,51 @@
+package golang
+
+import (
+	"testing"
+)
+
+func TestProxyURL(t *testing.T) {
+	tests := []struct {
+		goproxy  string
+		expected string
+	}{
+		{"", DefaultProxyURL},
+		{"direct", DefaultProxyURL},
+		{"https://goproxy.internal/,https://proxy.golang.org,direct", "https://goproxy.internal"},
+		{"off|https://athens.internal", "https://athens.internal"},
+	}
+	for _, test := range tests {
+		if proxyURL := ProxyURL(test.goproxy); proxyURL != test.expected {
+			t.Errorf("Expected %s for GOPROXY=%s, got %s", test.expected, test.goproxy, proxyURL)
+		}
+	}
+}
+
+func TestProxyFilePath(t *testing.T) {
+	filePath, err := ProxyFilePath("github.com/BurntSushi/toml", "v1.3.2", ".zip")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if filePath != "github.com/!burnt!sushi/toml/@v/v1.3.2.zip" {
+		t.Errorf("Unexpected path %s", filePath)
+	}
+
+	modPath, version, extension, ok := ParseProxyFilePath(filePath)
+	if !ok || modPath != "github.com/BurntSushi/toml" || version != "v1.3.2" || extension != ".zip" {
+		t.Errorf("Expected the path to parse back, got %s %s %s (%t)", modPath, version, extension, ok)
+	}
+
+	for _, invalid := range []string{"github.com/a/@v/list", "github.com/!!a/@v/v1.0.0.mod", "github.com/A/@v/v1.0.0.mod", "v1.0.0.zip"} {
+		if _, _, _, ok := ParseProxyFilePath(invalid); ok {
+			t.Errorf("Expected %s not to parse", invalid)
+		}
+	}
+
+	if _, err = ProxyFilePath("example.com/a!b", "v1.0.0", ".mod"); err == nil {
+		t.Errorf("Expected an error escaping a path holding !")
+	}
+	listPath, err := ListFilePath("github.com/BurntSushi/toml")
+	if err != nil || listPath != "github.com/!burnt!sushi/toml/@v/list" {
+		t.Errorf("Unexpected list path %s (%v)", listPath, err)
+	}
+}
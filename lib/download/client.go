@@ -0,0 +1,50 @@
+package download
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"time"
+)
+
+// idleTimeoutConn fails reads which stall for longer than timeout,
+// without limiting how long a whole download may take
+type idleTimeoutConn struct {
+	net.Conn
+	timeout time.Duration
+}
+
+func (c *idleTimeoutConn) Read(b []byte) (int, error) {
+	if err := c.Conn.SetReadDeadline(time.Now().Add(c.timeout)); err != nil {
+		return 0, err
+	}
+	return c.Conn.Read(b)
+}
+
+// NewHTTPClient creates an HTTP client which gives up connecting after
+// connectTimeout and reading after readTimeout without receiving any data.
+// A zero timeout disables it.
+func NewHTTPClient(connectTimeout, readTimeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   connectTimeout,
+		KeepAlive: 30 * time.Second,
+	}
+
+	dial := func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dialer.DialContext(ctx, network, addr)
+		if err != nil || readTimeout == 0 {
+			return conn, err
+		}
+		return &idleTimeoutConn{Conn: conn, timeout: readTimeout}, nil
+	}
+
+	transport := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dial,
+		TLSHandshakeTimeout:   connectTimeout,
+		ResponseHeaderTimeout: readTimeout,
+		MaxIdleConnsPerHost:   8,
+	}
+
+	return &http.Client{Transport: transport}
+}
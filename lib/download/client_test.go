@@ -0,0 +1,35 @@
+package download
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHTTPClientReadTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("partial"))
+		w.(http.Flusher).Flush()
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client := NewHTTPClient(time.Second, 50*time.Millisecond)
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer resp.Body.Close()
+
+	_, err = ioutil.ReadAll(resp.Body)
+	if err == nil {
+		t.Fatalf("Expected a stalled read to time out")
+	}
+	if !IsRetryable(err) {
+		t.Errorf("Expected a read timeout to be retryable: %s", err)
+	}
+}
@@ -0,0 +1,24 @@
+package download
+
+import (
+	"bufio"
+	"fmt"
+)
+
+// GzipMagic is the start of every gzip file
+const GzipMagic = "\x1f\x8b"
+
+// CheckMagic checks that the bytes offset bytes into br are magic, as
+// they are in every file of format, without consuming them. Registries
+// and proxies answering with an HTML error page fail it, which is a
+// clearer error than the integrity check would give.
+func CheckMagic(br *bufio.Reader, format string, offset int, magic string) error {
+	start, err := br.Peek(offset + len(magic))
+	if err != nil {
+		return fmt.Errorf("Not a %s file: %s", format, err)
+	}
+	if found := string(start[offset:]); found != magic {
+		return fmt.Errorf("Not a %s file (found %q where %q belongs)", format, found, magic)
+	}
+	return nil
+}
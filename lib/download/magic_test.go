@@ -0,0 +1,33 @@
+package download
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+)
+
+func TestCheckMagic(t *testing.T) {
+	tests := []struct {
+		contents string
+		offset   int
+		magic    string
+		valid    bool
+	}{
+		{GzipMagic + "rest", 0, GzipMagic, true},
+		{"<html>", 0, GzipMagic, false},
+		{"", 0, GzipMagic, false},
+		{strings.Repeat("\x00", 257) + "ustar", 257, "ustar", true},
+		{strings.Repeat("\x00", 262), 257, "ustar", false},
+	}
+	for _, test := range tests {
+		br := bufio.NewReader(strings.NewReader(test.contents))
+		err := CheckMagic(br, "test", test.offset, test.magic)
+		if (err == nil) != test.valid {
+			t.Errorf("Expected %q to be valid: %t, got %v", test.contents, test.valid, err)
+		}
+		// The checked bytes are left to read
+		if rest, _ := br.Peek(len(test.contents)); string(rest) != test.contents {
+			t.Errorf("Expected %q to be left unread, got %q", test.contents, rest)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package download
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// StatusError reports an unsuccessful HTTP response
+type StatusError struct {
+	URL        string
+	StatusCode int
+	RetryAfter time.Duration
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf(
+		"%s returned %d %s",
+		e.URL,
+		e.StatusCode,
+		http.StatusText(e.StatusCode),
+	)
+}
+
+// Temporary reports whether the request may succeed when retried
+func (e *StatusError) Temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// NewStatusError creates a StatusError from a response,
+// taking note of any Retry-After header
+func NewStatusError(resp *http.Response) *StatusError {
+	return &StatusError{
+		URL:        resp.Request.URL.String(),
+		StatusCode: resp.StatusCode,
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+	}
+}
+
+// parseRetryAfter reads a Retry-After header given either
+// in seconds or as an HTTP date
+func parseRetryAfter(header string, now time.Time) time.Duration {
+	if header == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(header); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(header); err == nil {
+		if delay := date.Sub(now); delay > 0 {
+			return delay
+		}
+	}
+	return 0
+}
+
+// IsRetryable reports whether err is a transient failure such as a
+// 5xx or 429 response, a timeout or a reset connection. Failures which
+// will happen again, like an unknown host or a bad certificate, aren't.
+func IsRetryable(err error) bool {
+	if urlErr, ok := err.(*url.Error); ok {
+		err = urlErr.Err
+	}
+	switch e := err.(type) {
+	case *StatusError:
+		return e.Temporary()
+	case net.Error:
+		if e.Timeout() {
+			return true
+		}
+	}
+	if opErr, ok := err.(*net.OpError); ok {
+		err = opErr.Err
+	}
+	if syscallErr, ok := err.(*os.SyscallError); ok {
+		err = syscallErr.Err
+	}
+	switch err {
+	case syscall.ECONNRESET, syscall.ECONNREFUSED, syscall.ECONNABORTED, syscall.EPIPE, io.ErrUnexpectedEOF:
+		return true
+	}
+	return false
+}
+
+// Retryer retries transient failures with jittered exponential backoff
+type Retryer struct {
+	MaxRetries int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+
+	// Sleep waits between attempts, and defaults to time.Sleep
+	Sleep func(time.Duration)
+
+	randLock sync.Mutex
+	rand     *rand.Rand
+}
+
+// backoff returns how long to wait before the given retry, counting from 1:
+// half of the exponential delay plus a random share of the other half
+func (r *Retryer) backoff(retry int) time.Duration {
+	delay := r.BaseDelay
+	for i := 1; i < retry && delay < r.MaxDelay; i++ {
+		delay *= 2
+	}
+	if r.MaxDelay > 0 && delay > r.MaxDelay {
+		delay = r.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+
+	r.randLock.Lock()
+	defer r.randLock.Unlock()
+	if r.rand == nil {
+		r.rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	half := delay / 2
+	return half + time.Duration(r.rand.Int63n(int64(delay-half)+1))
+}
+
+// Do calls attempt until it succeeds, fails with an error which isn't
+// retryable or runs out of retries. A Retry-After longer than MaxDelay
+// gives up too, rather than stalling for as long as the server asks.
+// It returns how many retries it made. If set, onRetry is called before
+// waiting to retry.
+func (r *Retryer) Do(
+	attempt func() error,
+	onRetry func(retry int, delay time.Duration, err error),
+) (int, error) {
+	sleep := r.Sleep
+	if sleep == nil {
+		sleep = time.Sleep
+	}
+
+	retries := 0
+	for {
+		err := attempt()
+		if err == nil || retries >= r.MaxRetries || !IsRetryable(err) {
+			return retries, err
+		}
+
+		delay := r.backoff(retries + 1)
+		if statusErr, ok := err.(*StatusError); ok && statusErr.RetryAfter > delay {
+			if r.MaxDelay > 0 && statusErr.RetryAfter > r.MaxDelay {
+				return retries, err
+			}
+			delay = statusErr.RetryAfter
+		}
+		retries++
+		if onRetry != nil {
+			onRetry(retries, delay, err)
+		}
+		sleep(delay)
+	}
+}
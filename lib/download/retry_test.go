@@ -0,0 +1,177 @@
+package download
+
+import (
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func newTestRetryer(maxRetries int, slept *[]time.Duration) *Retryer {
+	return &Retryer{
+		MaxRetries: maxRetries,
+		BaseDelay:  100 * time.Millisecond,
+		MaxDelay:   time.Second,
+		Sleep: func(d time.Duration) {
+			*slept = append(*slept, d)
+		},
+	}
+}
+
+func TestRetryerSucceedsAfterRetries(t *testing.T) {
+	var slept []time.Duration
+	r := newTestRetryer(3, &slept)
+
+	attempts := 0
+	retries, err := r.Do(func() error {
+		attempts++
+		if attempts < 3 {
+			return &StatusError{StatusCode: http.StatusServiceUnavailable}
+		}
+		return nil
+	}, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if retries != 2 || len(slept) != 2 {
+		t.Errorf("Expected two retries, got %d", retries)
+	}
+}
+
+func TestRetryerGivesUp(t *testing.T) {
+	var slept []time.Duration
+	r := newTestRetryer(2, &slept)
+
+	retries, err := r.Do(func() error {
+		return io.ErrUnexpectedEOF
+	}, nil)
+	if err != io.ErrUnexpectedEOF {
+		t.Errorf("Expected the last error, got %v", err)
+	}
+	if retries != 2 {
+		t.Errorf("Expected two retries, got %d", retries)
+	}
+}
+
+func TestRetryerPermanentError(t *testing.T) {
+	var slept []time.Duration
+	r := newTestRetryer(3, &slept)
+
+	retries, err := r.Do(func() error {
+		return &StatusError{StatusCode: http.StatusNotFound}
+	}, nil)
+	if err == nil || retries != 0 {
+		t.Errorf("Expected 404 not to be retried")
+	}
+
+	retries, err = r.Do(func() error {
+		return errors.New("integrity mismatch")
+	}, nil)
+	if err == nil || retries != 0 {
+		t.Errorf("Expected other errors not to be retried")
+	}
+}
+
+func TestRetryerBackoff(t *testing.T) {
+	var slept []time.Duration
+	r := newTestRetryer(5, &slept)
+
+	r.Do(func() error {
+		return &StatusError{StatusCode: http.StatusBadGateway}
+	}, nil)
+	if len(slept) != 5 {
+		t.Fatalf("Expected five waits, got %d", len(slept))
+	}
+	limits := []time.Duration{
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+		400 * time.Millisecond,
+		800 * time.Millisecond,
+		time.Second,
+	}
+	for i, limit := range limits {
+		if slept[i] < limit/2 || slept[i] > limit {
+			t.Errorf("Expected wait %d between %s and %s, got %s", i, limit/2, limit, slept[i])
+		}
+	}
+}
+
+func TestRetryerHonorsRetryAfter(t *testing.T) {
+	var slept []time.Duration
+	r := newTestRetryer(1, &slept)
+
+	r.Do(func() error {
+		return &StatusError{
+			StatusCode: http.StatusTooManyRequests,
+			RetryAfter: 800 * time.Millisecond,
+		}
+	}, nil)
+	if len(slept) != 1 || slept[0] != 800*time.Millisecond {
+		t.Errorf("Expected to wait 800ms, waited %v", slept)
+	}
+}
+
+func TestRetryerRetryAfterAboveMaxDelay(t *testing.T) {
+	var slept []time.Duration
+	r := newTestRetryer(3, &slept)
+
+	retries, err := r.Do(func() error {
+		return &StatusError{
+			StatusCode: http.StatusTooManyRequests,
+			RetryAfter: 24 * time.Hour,
+		}
+	}, nil)
+	if err == nil || retries != 0 || len(slept) != 0 {
+		t.Errorf("Expected to give up rather than wait a day, retried %d times and waited %v", retries, slept)
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	urlErr := func(err error) error {
+		return &url.Error{Op: "Get", URL: "https://registry.npmjs.org/", Err: err}
+	}
+	cases := []struct {
+		name      string
+		err       error
+		retryable bool
+	}{
+		{"503", &StatusError{StatusCode: http.StatusServiceUnavailable}, true},
+		{"429", &StatusError{StatusCode: http.StatusTooManyRequests}, true},
+		{"404", &StatusError{StatusCode: http.StatusNotFound}, false},
+		{"timeout", urlErr(&net.DNSError{Err: "i/o timeout", Name: "registry.npmjs.org", IsTimeout: true}), true},
+		{"reset", urlErr(&net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ECONNRESET)}), true},
+		{"refused", urlErr(&net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}), true},
+		{"truncated", urlErr(io.ErrUnexpectedEOF), true},
+		{"unknown host", urlErr(&net.OpError{Op: "dial", Net: "tcp", Err: &net.DNSError{Err: "no such host", Name: "registry.npmjs.invalid", IsNotFound: true}}), false},
+		{"bad certificate", urlErr(x509.UnknownAuthorityError{}), false},
+		{"unsupported scheme", urlErr(errors.New(`unsupported protocol scheme "ftp"`)), false},
+	}
+	for _, c := range cases {
+		if retryable := IsRetryable(c.err); retryable != c.retryable {
+			t.Errorf("Expected %s to be retryable: %t, got %t", c.name, c.retryable, retryable)
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2016, 9, 1, 12, 0, 0, 0, time.UTC)
+	cases := map[string]time.Duration{
+		"":                              0,
+		"120":                           2 * time.Minute,
+		"-1":                            0,
+		"Thu, 01 Sep 2016 12:00:10 GMT": 10 * time.Second,
+		"Thu, 01 Sep 2016 11:00:00 GMT": 0,
+		"soon":                          0,
+	}
+	for header, expected := range cases {
+		if delay := parseRetryAfter(header, now); delay != expected {
+			t.Errorf("Expected %s for %q, got %s", expected, header, delay)
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package filter
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Attributes of a dependency a Rule can match on
+const (
+	FieldName    = "name"
+	FieldVersion = "version"
+	FieldScope   = "scope"
+	FieldHost    = "host"
+)
+
+var fields = []string{FieldName, FieldVersion, FieldScope, FieldHost}
+
+// Dependency describes a dependency of any platform to the rules
+type Dependency struct {
+	Name    string
+	Version string
+	// Scope is the scope of a package name such as @babel/core, or "" on
+	// platforms without scopes
+	Scope string
+	// Host is the host the dependency is downloaded from, or "" for local
+	// dependencies
+	Host string
+}
+
+// RangeParser parses a version range of a platform into a function
+// reporting whether a version is in it
+type RangeParser func(versionRange string) (func(version string) bool, error)
+
+// rangeOperators are the characters of version ranges rather than of
+// versions, which platforms without a RangeParser refuse
+const rangeOperators = "<>=^~| "
+
+// Rule includes or excludes dependencies by matching one of their
+// attributes. Rules are written FIELD:PATTERN, where FIELD is name (the
+// default), version, scope or host. Patterns between slashes are regexps
+// and others are globs, except for versions, which are ranges on
+// platforms with a RangeParser.
+type Rule struct {
+	// Text is the rule as written, such as scope:@ourco or name:/^lodash/
+	Text string
+	// Source tells where the rule came from, such as --exclude or
+	// rules.txt:3
+	Source  string
+	Exclude bool
+
+	field string
+	match func(string) bool
+}
+
+// globMatcher matches whole strings against a glob, where * stands for
+// any run of characters and ? for any one
+func globMatcher(glob string) func(string) bool {
+	var expr strings.Builder
+	expr.WriteString("^")
+	for _, r := range glob {
+		switch r {
+		case '*':
+			expr.WriteString(".*")
+		case '?':
+			expr.WriteString(".")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	expr.WriteString("$")
+	return regexp.MustCompile(expr.String()).MatchString
+}
+
+// ParseRule parses a rule, noting where it came from. Version patterns
+// are parsed by ranges, or are globs when ranges is nil.
+func ParseRule(text string, exclude bool, source string, ranges RangeParser) (Rule, error) {
+	rule := Rule{Text: text, Source: source, Exclude: exclude, field: FieldName}
+	pattern := strings.TrimSpace(text)
+	for _, field := range fields {
+		if strings.HasPrefix(pattern, field+":") {
+			rule.field = field
+			pattern = strings.TrimPrefix(pattern, field+":")
+			break
+		}
+	}
+	if pattern == "" {
+		return rule, fmt.Errorf("Empty filter rule: %s", text)
+	}
+
+	if len(pattern) > 1 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+		rgx, err := regexp.Compile(pattern[1 : len(pattern)-1])
+		if err != nil {
+			return rule, fmt.Errorf("Malformed filter regexp %s: %s", text, err)
+		}
+		rule.match = rgx.MatchString
+		return rule, nil
+	}
+
+	switch rule.field {
+	case FieldVersion:
+		if ranges == nil {
+			if strings.ContainsAny(pattern, rangeOperators) {
+				return rule, fmt.Errorf(
+					"Version ranges aren't supported for this platform's versions: %s (use a version, a glob or a /regexp/)",
+					text,
+				)
+			}
+			rule.match = globMatcher(pattern)
+			break
+		}
+		match, err := ranges(pattern)
+		if err != nil {
+			return rule, fmt.Errorf("Malformed filter version range %s: %s", text, err)
+		}
+		rule.match = match
+	case FieldScope:
+		if !strings.HasPrefix(pattern, "@") && !strings.HasPrefix(pattern, "*") {
+			pattern = "@" + pattern
+		}
+		rule.match = globMatcher(pattern)
+	default:
+		rule.match = globMatcher(pattern)
+	}
+	return rule, nil
+}
+
+// String describes the rule and where it came from
+func (r Rule) String() string {
+	kind := "include"
+	if r.Exclude {
+		kind = "exclude"
+	}
+	return fmt.Sprintf("%s %s (%s)", kind, r.Text, r.Source)
+}
+
+// Matches reports whether the rule's pattern matches dep
+func (r Rule) Matches(dep Dependency) bool {
+	switch r.field {
+	case FieldVersion:
+		return r.match(dep.Version)
+	case FieldScope:
+		return r.match(dep.Scope)
+	case FieldHost:
+		return r.match(dep.Host)
+	}
+	return r.match(dep.Name)
+}
+
+// URLHost returns the host of a download URL, or "" when it has none
+func URLHost(rawURL string) string {
+	urlObj, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return urlObj.Hostname()
+}
+
+// Filter selects dependencies with include and exclude rules. When there
+// are include rules a dependency must match one of them, and it must
+// match no exclude rule.
+type Filter struct {
+	ranges   RangeParser
+	includes []Rule
+	excludes []Rule
+}
+
+// New creates a Filter which keeps everything, parsing the version ranges
+// of the rules added to it with ranges, which is nil for platforms whose
+// versions have no ranges the filter understands
+func New(ranges RangeParser) *Filter {
+	return &Filter{ranges: ranges}
+}
+
+// ParseRule parses a rule with the filter's version ranges
+func (f *Filter) ParseRule(text string, exclude bool, source string) (Rule, error) {
+	return ParseRule(text, exclude, source, f.ranges)
+}
+
+// Add adds a rule to the filter
+func (f *Filter) Add(rule Rule) {
+	if rule.Exclude {
+		f.excludes = append(f.excludes, rule)
+	} else {
+		f.includes = append(f.includes, rule)
+	}
+}
+
+// AddRules adds the rules in a rules file read from source. Each line is
+// include RULE or exclude RULE; blank lines and lines starting with # are
+// ignored.
+func (f *Filter) AddRules(contents []byte, source string) error {
+	for i, line := range strings.Split(string(contents), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		lineSource := fmt.Sprintf("%s:%d", source, i+1)
+
+		fields := strings.SplitN(line, " ", 2)
+		if len(fields) != 2 || (fields[0] != "include" && fields[0] != "exclude") {
+			return fmt.Errorf("%s: expected include RULE or exclude RULE: %s", lineSource, line)
+		}
+		rule, err := f.ParseRule(fields[1], fields[0] == "exclude", lineSource)
+		if err != nil {
+			return fmt.Errorf("%s: %s", lineSource, err)
+		}
+		f.Add(rule)
+	}
+	return nil
+}
+
+// Excludes reports whether the filter leaves dep out and why
+func (f *Filter) Excludes(dep Dependency) (bool, string) {
+	if len(f.includes) > 0 {
+		included := false
+		for _, rule := range f.includes {
+			if rule.Matches(dep) {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return true, "matches no include rule"
+		}
+	}
+	for _, rule := range f.excludes {
+		if rule.Matches(dep) {
+			return true, "matches " + rule.String()
+		}
+	}
+	return false, ""
+}
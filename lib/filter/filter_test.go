@@ -0,0 +1,179 @@
+package filter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var testDependencies = []Dependency{
+	{Name: "@babel/core", Version: "7.0.0", Scope: "@babel", Host: "registry.npmjs.org"},
+	{Name: "lodash", Version: "4.17.20", Host: "registry.npmjs.org"},
+	{Name: "lodash.get", Version: "4.4.2", Host: "npm.internal"},
+	{Name: "my-fork", Version: "1.0.0", Host: "github.com"},
+	{Name: "shared", Version: "2.0.0"},
+}
+
+// parseVersion splits a version of dotted numbers, such as 4.17.20
+func parseVersion(version string) ([]int, error) {
+	var parts []int
+	for _, part := range strings.Split(version, ".") {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, n)
+	}
+	return parts, nil
+}
+
+// lessThanRanges parses ranges of the form <VERSION, which is all the
+// tests need a platform's ranges for
+func lessThanRanges(versionRange string) (func(string) bool, error) {
+	if !strings.HasPrefix(versionRange, "<") {
+		return nil, fmt.Errorf("Expected <VERSION")
+	}
+	bound, err := parseVersion(versionRange[1:])
+	if err != nil {
+		return nil, err
+	}
+	return func(version string) bool {
+		parts, err := parseVersion(version)
+		if err != nil {
+			return false
+		}
+		for i := 0; i < len(parts) && i < len(bound); i++ {
+			if parts[i] != bound[i] {
+				return parts[i] < bound[i]
+			}
+		}
+		return len(parts) < len(bound)
+	}, nil
+}
+
+func matchingNames(t *testing.T, text string, ranges RangeParser) string {
+	rule, err := ParseRule(text, false, "test", ranges)
+	if err != nil {
+		t.Fatalf("%s: %s", text, err)
+	}
+	var names []string
+	for _, dep := range testDependencies {
+		if rule.Matches(dep) {
+			names = append(names, dep.Name)
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+func TestRuleMatches(t *testing.T) {
+	cases := []struct {
+		rule     string
+		expected string
+	}{
+		{"lodash", "lodash"},
+		{"lodash*", "lodash,lodash.get"},
+		{"name:/^lodash\\./", "lodash.get"},
+		{"/babel/", "@babel/core"},
+		{"version:<4.17.21", "lodash,lodash.get,my-fork,shared"},
+		{"version:/^7\\./", "@babel/core"},
+		{"scope:babel", "@babel/core"},
+		{"scope:@babel", "@babel/core"},
+		{"host:registry.npmjs.org", "@babel/core,lodash"},
+		{"host:*.internal", "lodash.get"},
+		{"host:github.com", "my-fork"},
+	}
+	for _, c := range cases {
+		if names := matchingNames(t, c.rule, lessThanRanges); names != c.expected {
+			t.Errorf("Expected %s to match %q, got %q", c.rule, c.expected, names)
+		}
+	}
+
+	if _, err := ParseRule("scope:", false, "test", lessThanRanges); err == nil {
+		t.Errorf("Expected an error for an empty rule")
+	}
+	if _, err := ParseRule("version:^4.0.0", false, "test", lessThanRanges); err == nil {
+		t.Errorf("Expected an error for a range the platform can't parse")
+	}
+}
+
+func TestRuleVersionsWithoutRanges(t *testing.T) {
+	cases := []struct {
+		rule     string
+		expected string
+	}{
+		{"version:4.17.20", "lodash"},
+		{"version:4.*", "lodash,lodash.get"},
+		{"version:/^[12]\\./", "my-fork,shared"},
+	}
+	for _, c := range cases {
+		if names := matchingNames(t, c.rule, nil); names != c.expected {
+			t.Errorf("Expected %s to match %q, got %q", c.rule, c.expected, names)
+		}
+	}
+
+	for _, text := range []string{"version:<1.0.0", "version:~=1.4", "version:>= 1.0, < 2.0"} {
+		_, err := ParseRule(text, false, "test", nil)
+		if err == nil || !strings.Contains(err.Error(), "Version ranges aren't supported") {
+			t.Errorf("Expected %s to be refused, got %v", text, err)
+		}
+	}
+}
+
+func TestFilterExcludes(t *testing.T) {
+	filter := New(lessThanRanges)
+	err := filter.AddRules([]byte(`# Only our mirror and registry packages
+include host:*.npmjs.org
+include host:npm.internal
+
+exclude version:<4.17.21
+`), "rules.txt")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	reasons := make(map[string]string)
+	for _, dep := range testDependencies {
+		if excluded, reason := filter.Excludes(dep); excluded {
+			reasons[dep.Name] = reason
+		}
+	}
+	expected := map[string]string{
+		"lodash":     "matches exclude version:<4.17.21 (rules.txt:5)",
+		"lodash.get": "matches exclude version:<4.17.21 (rules.txt:5)",
+		"my-fork":    "matches no include rule",
+		"shared":     "matches no include rule",
+	}
+	if len(reasons) != len(expected) {
+		t.Errorf("Expected %v, got %v", expected, reasons)
+	}
+	for name, reason := range expected {
+		if reasons[name] != reason {
+			t.Errorf("Expected %s excluded because it %s, got %q", name, reason, reasons[name])
+		}
+	}
+}
+
+func TestFilterMalformedRules(t *testing.T) {
+	for _, contents := range []string{"lodash\n", "include\n", "exclude name:/(/\n"} {
+		if err := New(nil).AddRules([]byte(contents), "rules.txt"); err == nil {
+			t.Errorf("Expected an error for %q", contents)
+		}
+	}
+}
+
+func TestURLHost(t *testing.T) {
+	cases := []struct {
+		url      string
+		expected string
+	}{
+		{"https://files.pythonhosted.org/packages/requests-2.31.0.tar.gz", "files.pythonhosted.org"},
+		{"https://proxy.golang.org:443/", "proxy.golang.org"},
+		{"", ""},
+	}
+	for _, c := range cases {
+		if host := URLHost(c.url); host != c.expected {
+			t.Errorf("Expected %s, got %s", c.expected, host)
+		}
+	}
+}
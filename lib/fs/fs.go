@@ -3,6 +3,8 @@ package fs
 import (
 	"io"
 	"os"
+	"path"
+	"strings"
 )
 
 // FileSystem abstracts a filesystem, real or mock
@@ -13,6 +15,11 @@ type FileSystem interface {
 	Getwd() (string, error)
 	ReadFile(filename string) ([]byte, error)
 	ReadDir(dirpath string) ([]os.FileInfo, error)
+	Remove(name string) error
+	RemoveAll(path string) error
+	Rename(oldpath, newpath string) error
+	MkdirAll(path string, perm os.FileMode) error
+	TempFile(dir, prefix string, perm os.FileMode) (File, error)
 }
 
 // File represents file-based interactions
@@ -22,5 +29,35 @@ type File interface {
 	io.ReaderAt
 	io.Seeker
 	io.Writer
+	Name() string
 	Stat() (os.FileInfo, error)
 }
+
+// Walk lists the files under dirPath, recursing into subdirectories, as
+// paths relative to it in lexical order. Hidden files and directories,
+// whose names start with a dot, are left out.
+func Walk(fileSystem FileSystem, dirPath string) ([]string, error) {
+	var names []string
+	var walk func(relPath string) error
+	walk = func(relPath string) error {
+		fileInfos, err := fileSystem.ReadDir(path.Join(dirPath, relPath))
+		if err != nil {
+			return err
+		}
+		for _, fileInfo := range fileInfos {
+			if strings.HasPrefix(fileInfo.Name(), ".") {
+				continue
+			}
+			name := path.Join(relPath, fileInfo.Name())
+			if fileInfo.IsDir() {
+				if err = walk(name); err != nil {
+					return err
+				}
+				continue
+			}
+			names = append(names, name)
+		}
+		return nil
+	}
+	return names, walk("")
+}
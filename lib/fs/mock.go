@@ -16,6 +16,14 @@ type MockFS struct {
 	GetwdError     error
 	ReadFileResult []byte
 	ReadFileError  error
+	ReadDirResult  []os.FileInfo
+	ReadDirError   error
+	RemoveError    error
+	RemoveAllError error
+	RenameError    error
+	MkdirAllError  error
+	TempFileResult File
+	TempFileError  error
 }
 
 // Open opens a file
@@ -42,3 +50,33 @@ func (m *MockFS) Getwd() (string, error) {
 func (m *MockFS) ReadFile(filename string) ([]byte, error) {
 	return m.ReadFileResult, m.ReadFileError
 }
+
+// ReadDir reads a directory's contents
+func (m *MockFS) ReadDir(dirpath string) ([]os.FileInfo, error) {
+	return m.ReadDirResult, m.ReadDirError
+}
+
+// Remove removes a file
+func (m *MockFS) Remove(name string) error {
+	return m.RemoveError
+}
+
+// RemoveAll removes a path and anything it contains
+func (m *MockFS) RemoveAll(path string) error {
+	return m.RemoveAllError
+}
+
+// Rename renames a file
+func (m *MockFS) Rename(oldpath, newpath string) error {
+	return m.RenameError
+}
+
+// MkdirAll creates a directory along with any missing parents
+func (m *MockFS) MkdirAll(path string, perm os.FileMode) error {
+	return m.MkdirAllError
+}
+
+// TempFile creates a new uniquely named file in dir
+func (m *MockFS) TempFile(dir, prefix string, perm os.FileMode) (File, error) {
+	return m.TempFileResult, m.TempFileError
+}
@@ -37,3 +37,39 @@ func (f *OSFS) ReadFile(filename string) ([]byte, error) {
 func (f *OSFS) ReadDir(dirpath string) ([]os.FileInfo, error) {
 	return ioutil.ReadDir(dirpath)
 }
+
+// Remove removes a file
+func (f *OSFS) Remove(name string) error {
+	return os.Remove(name)
+}
+
+// RemoveAll removes a path and anything it contains
+func (f *OSFS) RemoveAll(path string) error {
+	return os.RemoveAll(path)
+}
+
+// Rename renames a file
+func (f *OSFS) Rename(oldpath, newpath string) error {
+	return os.Rename(oldpath, newpath)
+}
+
+// MkdirAll creates a directory along with any missing parents
+func (f *OSFS) MkdirAll(path string, perm os.FileMode) error {
+	return os.MkdirAll(path, perm)
+}
+
+// TempFile creates a new uniquely named file in dir with mode perm,
+// rather than the owner-only mode temporary files get, since callers
+// rename it into place
+func (f *OSFS) TempFile(dir, prefix string, perm os.FileMode) (File, error) {
+	file, err := ioutil.TempFile(dir, prefix)
+	if err != nil {
+		return nil, err
+	}
+	if err = file.Chmod(perm); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return nil, err
+	}
+	return file, nil
+}
@@ -0,0 +1,200 @@
+package integrity
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"strings"
+)
+
+// algorithms lists the supported hash algorithms from weakest to strongest
+var algorithms = []string{"sha1", "sha256", "sha384", "sha512"}
+
+func newHash(algorithm string) hash.Hash {
+	switch algorithm {
+	case "sha1":
+		return sha1.New()
+	case "sha256":
+		return sha256.New()
+	case "sha384":
+		return sha512.New384()
+	case "sha512":
+		return sha512.New()
+	}
+	return nil
+}
+
+func strength(algorithm string) int {
+	for i, a := range algorithms {
+		if a == algorithm {
+			return i
+		}
+	}
+	return -1
+}
+
+// Digest is one expected hash of some content
+type Digest struct {
+	Algorithm string
+	Sum       []byte
+}
+
+// String formats the digest as a subresource integrity hash
+func (d Digest) String() string {
+	return d.Algorithm + "-" + base64.StdEncoding.EncodeToString(d.Sum)
+}
+
+// ParseSRI parses a subresource integrity string such as
+// "sha512-<base64> sha1-<base64>". Hashes using unsupported
+// algorithms are ignored.
+func ParseSRI(sri string) ([]Digest, error) {
+	var digests []Digest
+	for _, field := range strings.Fields(sri) {
+		// Options such as sha512-<base64>?foo carry no meaning for us
+		if i := strings.Index(field, "?"); i >= 0 {
+			field = field[:i]
+		}
+		i := strings.Index(field, "-")
+		if i < 0 {
+			return nil, fmt.Errorf("Malformed integrity hash: %s", field)
+		}
+		algorithm := field[:i]
+		if strength(algorithm) < 0 {
+			continue
+		}
+		sum, err := base64.StdEncoding.DecodeString(field[i+1:])
+		if err != nil {
+			return nil, fmt.Errorf("Malformed integrity hash: %s", field)
+		}
+		digests = append(digests, Digest{Algorithm: algorithm, Sum: sum})
+	}
+	return digests, nil
+}
+
+// ParseShasum parses a legacy hex encoded sha1 sum
+func ParseShasum(shasum string) (Digest, error) {
+	sum, err := hex.DecodeString(shasum)
+	if err != nil || len(sum) != sha1.Size {
+		return Digest{}, fmt.Errorf("Malformed shasum: %s", shasum)
+	}
+	return Digest{Algorithm: "sha1", Sum: sum}, nil
+}
+
+// ParseHexDigest parses a hex encoded sum made with the given algorithm,
+// such as the sha256 sums python and ruby lockfiles record
+func ParseHexDigest(algorithm string, sum string) (Digest, error) {
+	h := newHash(algorithm)
+	if h == nil {
+		return Digest{}, fmt.Errorf("Unsupported hash algorithm: %s", algorithm)
+	}
+	decoded, err := hex.DecodeString(sum)
+	if err != nil || len(decoded) != h.Size() {
+		return Digest{}, fmt.Errorf("Malformed %s sum: %s", algorithm, sum)
+	}
+	return Digest{Algorithm: algorithm, Sum: decoded}, nil
+}
+
+// MismatchError reports content which doesn't match its expected digests
+type MismatchError struct {
+	Expected []Digest
+	Actual   Digest
+}
+
+func (e *MismatchError) Error() string {
+	var expected []string
+	for _, d := range e.Expected {
+		expected = append(expected, d.String())
+	}
+	return fmt.Sprintf(
+		"integrity mismatch: expected %s, got %s",
+		strings.Join(expected, " or "),
+		e.Actual,
+	)
+}
+
+// check groups the expected digests sharing one algorithm, any
+// of which the content may match
+type check struct {
+	algorithm string
+	hash      hash.Hash
+	expected  []Digest
+}
+
+// Verifier hashes content as it's written and checks it
+// against the expected digests
+type Verifier struct {
+	checks []*check
+}
+
+// NewVerifier creates a Verifier for the strongest hashes of the given
+// subresource integrity string and, separately, a legacy sha1 shasum.
+// Either may be empty.
+func NewVerifier(sri string, shasum string) (*Verifier, error) {
+	v := &Verifier{}
+
+	digests, err := ParseSRI(sri)
+	if err != nil {
+		return nil, err
+	}
+	var strongest *check
+	for _, d := range digests {
+		if strongest == nil || strength(d.Algorithm) > strength(strongest.algorithm) {
+			strongest = &check{algorithm: d.Algorithm, hash: newHash(d.Algorithm)}
+		}
+		if d.Algorithm == strongest.algorithm {
+			strongest.expected = append(strongest.expected, d)
+		}
+	}
+	if strongest != nil {
+		v.checks = append(v.checks, strongest)
+	}
+
+	if shasum != "" {
+		d, err := ParseShasum(shasum)
+		if err != nil {
+			return nil, err
+		}
+		v.checks = append(v.checks, &check{
+			algorithm: d.Algorithm,
+			hash:      newHash(d.Algorithm),
+			expected:  []Digest{d},
+		})
+	}
+
+	return v, nil
+}
+
+// Empty reports whether there is nothing to verify against
+func (v *Verifier) Empty() bool {
+	return len(v.checks) == 0
+}
+
+// Write hashes more of the content
+func (v *Verifier) Write(p []byte) (int, error) {
+	for _, c := range v.checks {
+		c.hash.Write(p)
+	}
+	return len(p), nil
+}
+
+// Verify checks the content written so far against the expected digests
+func (v *Verifier) Verify() error {
+	for _, c := range v.checks {
+		actual := Digest{Algorithm: c.algorithm, Sum: c.hash.Sum(nil)}
+		matched := false
+		for _, d := range c.expected {
+			if string(d.Sum) == string(actual.Sum) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return &MismatchError{Expected: c.expected, Actual: actual}
+		}
+	}
+	return nil
+}
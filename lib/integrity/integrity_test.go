@@ -0,0 +1,132 @@
+package integrity
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"strings"
+	"testing"
+)
+
+var content = "package contents"
+
+func sri(algorithm string, sum []byte) string {
+	return algorithm + "-" + base64.StdEncoding.EncodeToString(sum)
+}
+
+func sha1Sum(s string) []byte {
+	sum := sha1.Sum([]byte(s))
+	return sum[:]
+}
+
+func sha512Sum(s string) []byte {
+	sum := sha512.Sum512([]byte(s))
+	return sum[:]
+}
+
+func verify(t *testing.T, integrity string, shasum string) error {
+	v, err := NewVerifier(integrity, shasum)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err = io.Copy(v, strings.NewReader(content)); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	return v.Verify()
+}
+
+func TestParseSRI(t *testing.T) {
+	digests, err := ParseSRI(sri("sha512", sha512Sum(content)) + "?opt " + sri("md5", []byte("x")) + " " + sri("sha1", sha1Sum(content)))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(digests) != 2 {
+		t.Fatalf("Expected two supported digests, found %d", len(digests))
+	}
+	if digests[0].Algorithm != "sha512" || digests[1].Algorithm != "sha1" {
+		t.Errorf("Unexpected digests: %v", digests)
+	}
+
+	if _, err = ParseSRI("sha512"); err == nil {
+		t.Errorf("Expected an error for a hash without a digest")
+	}
+	if _, err = ParseSRI("sha512-not_base64!"); err == nil {
+		t.Errorf("Expected an error for a malformed digest")
+	}
+}
+
+func TestParseHexDigest(t *testing.T) {
+	sum := sha256.Sum256([]byte(content))
+	d, err := ParseHexDigest("sha256", hex.EncodeToString(sum[:]))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if d.String() != sri("sha256", sum[:]) {
+		t.Errorf("Unexpected digest: %s", d)
+	}
+
+	if _, err = ParseHexDigest("sha256", hex.EncodeToString(sha1Sum(content))); err == nil {
+		t.Errorf("Expected an error for a sum of the wrong length")
+	}
+	if _, err = ParseHexDigest("md5", "00"); err == nil {
+		t.Errorf("Expected an error for an unsupported algorithm")
+	}
+}
+
+func TestVerifierEmpty(t *testing.T) {
+	v, err := NewVerifier("", "")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !v.Empty() {
+		t.Errorf("Expected verifier to be empty")
+	}
+	if err = verify(t, "", ""); err != nil {
+		t.Errorf("Expected nothing to verify, got %s", err)
+	}
+}
+
+func TestVerifierMatches(t *testing.T) {
+	if err := verify(t, sri("sha512", sha512Sum(content)), ""); err != nil {
+		t.Errorf("err: %s", err)
+	}
+	if err := verify(t, "", hex.EncodeToString(sha1Sum(content))); err != nil {
+		t.Errorf("err: %s", err)
+	}
+	if err := verify(t, sri("sha1", sha1Sum(content)), hex.EncodeToString(sha1Sum(content))); err != nil {
+		t.Errorf("err: %s", err)
+	}
+}
+
+func TestVerifierMultipleHashes(t *testing.T) {
+	// Any hash of the strongest algorithm may match
+	integrity := sri("sha512", sha512Sum("other contents")) + " " + sri("sha512", sha512Sum(content))
+	if err := verify(t, integrity, ""); err != nil {
+		t.Errorf("err: %s", err)
+	}
+
+	// Weaker algorithms are ignored when a stronger one is present
+	integrity = sri("sha1", sha1Sum("other contents")) + " " + sri("sha512", sha512Sum(content))
+	if err := verify(t, integrity, ""); err != nil {
+		t.Errorf("err: %s", err)
+	}
+}
+
+func TestVerifierMismatch(t *testing.T) {
+	err := verify(t, sri("sha512", sha512Sum("other contents")), "")
+	if _, ok := err.(*MismatchError); !ok {
+		t.Errorf("Expected a MismatchError, got %v", err)
+	}
+
+	err = verify(t, sri("sha512", sha512Sum(content)), hex.EncodeToString(sha1Sum("other contents")))
+	if _, ok := err.(*MismatchError); !ok {
+		t.Errorf("Expected a MismatchError, got %v", err)
+	}
+
+	if _, err = NewVerifier("", "abc"); err == nil {
+		t.Errorf("Expected an error for a malformed shasum")
+	}
+}
@@ -0,0 +1,135 @@
+package manifest
+
+import (
+	"bitbucket.org/bosgood/dep-get/lib/fs"
+	"encoding/json"
+	"os"
+	"path"
+	"sync"
+)
+
+// FileName is the sidecar file fetch leaves in its destination directory,
+// recording the digest and origin of every tarball fetched into it. It's
+// hidden so that archive and install pass over it as a dependency.
+const FileName = ".dep-get-manifest.json"
+
+// Entry records where a tarball came from and the sha512 subresource
+// integrity of its contents. Resolved is the URL from the lockfile, which
+// URL differs from when a registry rewrite applied. The remaining fields
+// carry the lockfile's markers for packages only some installs need.
+type Entry struct {
+	Integrity   string `json:"integrity"`
+	Resolved    string `json:"resolved,omitempty"`
+	URL         string `json:"url,omitempty"`
+	Dev         bool   `json:"dev,omitempty"`
+	Optional    bool   `json:"optional,omitempty"`
+	DevOptional bool   `json:"devOptional,omitempty"`
+	Peer        bool   `json:"peer,omitempty"`
+}
+
+// Manifest maps tarball paths, relative to the directory holding them,
+// to where they came from and what they contain
+type Manifest struct {
+	Files map[string]Entry `json:"files"`
+
+	lock    sync.Mutex
+	changed bool
+}
+
+// New creates an empty Manifest
+func New() *Manifest {
+	return &Manifest{Files: make(map[string]Entry)}
+}
+
+// Get returns the entry for the tarball at name
+func (m *Manifest) Get(name string) Entry {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.Files[name]
+}
+
+// Set records the entry for the tarball at name
+func (m *Manifest) Set(name string, entry Entry) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.Files[name] != entry {
+		m.Files[name] = entry
+		m.changed = true
+	}
+}
+
+// Omitter decides which dependencies to leave out by their markers, as
+// nodejs.Omit does
+type Omitter interface {
+	Omits(dev, optional, devOptional, peer bool) bool
+}
+
+// Omitted reports whether the tarball name is a dependency of a type omit
+// leaves out, according to its entry's markers. Files without an entry
+// are kept.
+func (m *Manifest) Omitted(name string, omit Omitter) bool {
+	m.lock.Lock()
+	entry, ok := m.Files[name]
+	m.lock.Unlock()
+	if !ok {
+		return false
+	}
+	return omit.Omits(entry.Dev, entry.Optional, entry.DevOptional, entry.Peer)
+}
+
+// Read loads the manifest in dirPath, or an empty one if there is none
+func Read(fileSystem fs.FileSystem, dirPath string) (*Manifest, error) {
+	manifest := New()
+	contents, err := fileSystem.ReadFile(path.Join(dirPath, FileName))
+	if os.IsNotExist(err) {
+		return manifest, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(contents, manifest); err != nil {
+		return nil, err
+	}
+	if manifest.Files == nil {
+		manifest.Files = make(map[string]Entry)
+	}
+	return manifest, nil
+}
+
+// Write replaces the manifest in dirPath if any entries changed
+func (m *Manifest) Write(fileSystem fs.FileSystem, dirPath string) error {
+	m.lock.Lock()
+	if !m.changed {
+		m.lock.Unlock()
+		return nil
+	}
+	contents, err := json.MarshalIndent(m, "", "  ")
+	m.lock.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if err = fileSystem.MkdirAll(dirPath, 0755); err != nil {
+		return err
+	}
+	manifestFile, err := fileSystem.TempFile(dirPath, FileName+".", 0644)
+	if err != nil {
+		return err
+	}
+	_, err = manifestFile.Write(contents)
+	if ferr := manifestFile.Close(); ferr != nil && err == nil {
+		err = ferr
+	}
+	if err == nil {
+		err = fileSystem.Rename(manifestFile.Name(), path.Join(dirPath, FileName))
+	}
+	if err != nil {
+		fileSystem.Remove(manifestFile.Name())
+		return err
+	}
+
+	m.lock.Lock()
+	m.changed = false
+	m.lock.Unlock()
+	return nil
+}
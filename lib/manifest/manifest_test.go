@@ -0,0 +1,96 @@
+package manifest
+
+import (
+	"bitbucket.org/bosgood/dep-get/lib/fs"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestReadMissing(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dep-get-manifest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	m, err := Read(&fs.OSFS{}, dir)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(m.Files) != 0 {
+		t.Errorf("Expected an empty manifest, got %v", m.Files)
+	}
+}
+
+func TestWriteRead(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dep-get-manifest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Nothing is written until an entry changes
+	m := New()
+	if err := m.Write(&fs.OSFS{}, dir); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err := os.Stat(path.Join(dir, FileName)); !os.IsNotExist(err) {
+		t.Errorf("Expected no manifest to be written, got %v", err)
+	}
+
+	entry := Entry{
+		Integrity: "sha512-AAAA",
+		Resolved:  "https://registry.npmjs.org/a/-/a-1.0.0.tgz",
+		Dev:       true,
+	}
+	m.Set("a@1.0.0.tgz", entry)
+	if err := m.Write(&fs.OSFS{}, dir); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	read, err := Read(&fs.OSFS{}, dir)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if got := read.Get("a@1.0.0.tgz"); got != entry {
+		t.Errorf("Expected %+v, got %+v", entry, got)
+	}
+	info, err := os.Stat(path.Join(dir, FileName))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if info.Mode().Perm() != 0644 {
+		t.Errorf("Expected mode 0644, got %o", info.Mode().Perm())
+	}
+}
+
+// omitDev leaves out dev dependencies, including those dev and optional
+type omitDev struct{}
+
+func (omitDev) Omits(dev, optional, devOptional, peer bool) bool {
+	return dev || devOptional
+}
+
+func TestOmitted(t *testing.T) {
+	m := New()
+	m.Set("a@1.0.0.tgz", Entry{Integrity: "sha512-AAAA", Dev: true})
+	m.Set("b@1.0.0.tgz", Entry{Integrity: "sha512-BBBB", Optional: true})
+	m.Set("c@1.0.0.tgz", Entry{Integrity: "sha512-CCCC", DevOptional: true})
+
+	tests := []struct {
+		name     string
+		expected bool
+	}{
+		{"a@1.0.0.tgz", true},
+		{"b@1.0.0.tgz", false},
+		{"c@1.0.0.tgz", true},
+		{"unknown@1.0.0.tgz", false},
+	}
+	for _, test := range tests {
+		if m.Omitted(test.name, omitDev{}) != test.expected {
+			t.Errorf("Expected %s omitted to be %t", test.name, test.expected)
+		}
+	}
+}
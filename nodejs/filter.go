@@ -0,0 +1,29 @@
+package nodejs
+
+import (
+	"bitbucket.org/bosgood/dep-get/lib/filter"
+)
+
+// ParseFilterRange parses the npm semver ranges of version filter rules
+func ParseFilterRange(versionRange string) (func(string) bool, error) {
+	semverRange, err := ParseSemverRange(versionRange)
+	if err != nil {
+		return nil, err
+	}
+	return semverRange.Contains, nil
+}
+
+// FilterDependency describes the package to include and exclude rules,
+// whose host: rules match the host it's downloaded from
+func (d *NodeDependency) FilterDependency() filter.Dependency {
+	host := filter.URLHost(d.PackageURL)
+	if repo, ok := ParseGitURL(d.PackageURL); ok {
+		host = repo.Host
+	}
+	return filter.Dependency{
+		Name:    d.Name,
+		Version: d.Version,
+		Scope:   packageScope(d.Name),
+		Host:    host,
+	}
+}
@@ -0,0 +1,53 @@
+package nodejs
+
+import (
+	"bitbucket.org/bosgood/dep-get/lib/filter"
+	"testing"
+)
+
+func TestFilterDependency(t *testing.T) {
+	cases := []struct {
+		dep      NodeDependency
+		expected filter.Dependency
+	}{
+		{
+			NodeDependency{Name: "@babel/core", Version: "7.0.0", PackageURL: "https://registry.npmjs.org/@babel/core/-/core-7.0.0.tgz"},
+			filter.Dependency{Name: "@babel/core", Version: "7.0.0", Scope: "@babel", Host: "registry.npmjs.org"},
+		},
+		{
+			NodeDependency{Name: "my-fork", Version: "1.0.0", PackageURL: "git+ssh://git@github.com/bosgood/my-fork.git#abc123"},
+			filter.Dependency{Name: "my-fork", Version: "1.0.0", Host: "github.com"},
+		},
+		{
+			// Not a scope without a package name after it
+			NodeDependency{Name: "@foo", Version: "1.0.0", PackageURL: "https://registry.npmjs.org/@foo/-/@foo-1.0.0.tgz"},
+			filter.Dependency{Name: "@foo", Version: "1.0.0", Host: "registry.npmjs.org"},
+		},
+		{
+			NodeDependency{Name: "shared", Version: "2.0.0", LocalPath: "../shared"},
+			filter.Dependency{Name: "shared", Version: "2.0.0"},
+		},
+	}
+	for _, c := range cases {
+		if dep := c.dep.FilterDependency(); dep != c.expected {
+			t.Errorf("Expected %+v, got %+v", c.expected, dep)
+		}
+	}
+}
+
+func TestParseFilterRange(t *testing.T) {
+	f := filter.New(ParseFilterRange)
+	rule, err := f.ParseRule("version:^4.0.0", true, "test")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !rule.Matches(filter.Dependency{Name: "lodash", Version: "4.17.20"}) {
+		t.Errorf("Expected ^4.0.0 to match 4.17.20")
+	}
+	if rule.Matches(filter.Dependency{Name: "lodash", Version: "3.10.1"}) {
+		t.Errorf("Expected ^4.0.0 not to match 3.10.1")
+	}
+	if _, err = f.ParseRule("version:^a.b", true, "test"); err == nil {
+		t.Errorf("Expected an error for a malformed range")
+	}
+}
@@ -0,0 +1,73 @@
+package nodejs
+
+import (
+	"bitbucket.org/bosgood/dep-get/lib/fs"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// GitCommand is the git client used to clone repositories from hosts
+// without a tarball endpoint
+var GitCommand = "git"
+
+// runGit runs git with args, returning its output, and its stderr in any
+// error
+func runGit(dir string, args ...string) (string, error) {
+	cmd := exec.Command(GitCommand, args...)
+	cmd.Dir = dir
+	// Fail rather than wait for credentials nobody will type
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "GIT_ASKPASS=")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf(
+			"git %s: %s: %s",
+			args[0],
+			err,
+			strings.TrimSpace(stderr.String()),
+		)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// CloneGitRepo clones repo into dir, which must not exist yet, and
+// checks out its commit, or the default branch when it names none. The
+// commit comes from the lockfile, so it's resolved to an object id first
+// rather than passed to checkout where it could be read as an option.
+func CloneGitRepo(repo GitRepo, dir string) error {
+	if _, err := runGit("", "clone", "--quiet", "--no-checkout", "--", repo.CloneURL, dir); err != nil {
+		return err
+	}
+	commit := repo.Commit
+	if commit == "" {
+		commit = "HEAD"
+	}
+	sha, err := runGit(dir, "rev-parse", "--verify", "--quiet", "--end-of-options", commit+"^{commit}")
+	if err != nil {
+		return fmt.Errorf("Unknown commit %s: %s", commit, err)
+	}
+	_, err = runGit(dir, "-c", "advice.detachedHead=false", "checkout", "--quiet", "--force", sha, "--")
+	return err
+}
+
+// PackGitRepo clones repo at its commit into a temporary directory and
+// writes a tarball of it to w as PackDirectory does
+func PackGitRepo(repo GitRepo, w io.Writer) error {
+	tempDir, err := ioutil.TempDir("", "dep-get-git-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tempDir)
+
+	cloneDir := tempDir + "/repo"
+	if err := CloneGitRepo(repo, cloneDir); err != nil {
+		return err
+	}
+	return PackDirectory(&fs.OSFS{}, cloneDir, w)
+}
@@ -0,0 +1,139 @@
+package nodejs
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"testing"
+)
+
+// gitTest runs git in dir, failing the test on error
+func gitTest(t *testing.T, dir string, args ...string) string {
+	args = append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %s: %s", strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// newTestGitRepo creates a bare repository holding two commits of a
+// package, returning the directory holding it, its path and the first commit
+func newTestGitRepo(t *testing.T) (string, string, string) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir, err := ioutil.TempDir("", "dep-get-git")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	work := path.Join(dir, "work")
+	bare := path.Join(dir, "repo.git")
+	gitTest(t, dir, "init", "--quiet", work)
+	writeTestFiles(t, work, map[string]string{
+		"package.json": `{"name": "pkg", "version": "1.0.0"}`,
+		"index.js":     "module.exports = 1\n",
+		".npmignore":   "test/\n",
+		"test/a.js":    "",
+	})
+	gitTest(t, work, "add", "-A")
+	gitTest(t, work, "commit", "--quiet", "-m", "first")
+	first := gitTest(t, work, "rev-parse", "HEAD")
+
+	writeTestFiles(t, work, map[string]string{"second.js": ""})
+	gitTest(t, work, "add", "-A")
+	gitTest(t, work, "commit", "--quiet", "-m", "second")
+	gitTest(t, dir, "clone", "--quiet", "--bare", work, bare)
+
+	return dir, bare, first
+}
+
+func tarballNames(t *testing.T, tarball []byte) []string {
+	gz, err := gzip.NewReader(bytes.NewReader(tarball))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return names
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, header.Name)
+	}
+}
+
+func TestPackGitRepo(t *testing.T) {
+	dir, bare, first := newTestGitRepo(t)
+	defer os.RemoveAll(dir)
+
+	repo, ok := ParseGitURL("git+file://" + bare + "#" + first)
+	if !ok {
+		t.Fatalf("Expected a git URL")
+	}
+
+	var tarball bytes.Buffer
+	if err := PackGitRepo(repo, &tarball); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	names := strings.Join(tarballNames(t, tarball.Bytes()), ",")
+	if names != "package/index.js,package/package.json" {
+		t.Errorf("Expected the first commit packed, got %s", names)
+	}
+
+	// Packing the same commit again gives the same tarball
+	var again bytes.Buffer
+	if err := PackGitRepo(repo, &again); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !bytes.Equal(tarball.Bytes(), again.Bytes()) {
+		t.Error("Expected identical tarballs for the same commit")
+	}
+
+	// Without a commit the default branch is packed
+	repo.Commit = ""
+	tarball.Reset()
+	if err := PackGitRepo(repo, &tarball); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	names = strings.Join(tarballNames(t, tarball.Bytes()), ",")
+	if names != "package/index.js,package/package.json,package/second.js" {
+		t.Errorf("Expected the latest commit packed, got %s", names)
+	}
+}
+
+func TestPackGitRepoUnknownCommit(t *testing.T) {
+	dir, bare, _ := newTestGitRepo(t)
+	defer os.RemoveAll(dir)
+
+	repo, _ := ParseGitURL("git+file://" + bare + "#0000000000000000000000000000000000000000")
+	if err := PackGitRepo(repo, ioutil.Discard); err == nil {
+		t.Error("Expected an error for a missing commit")
+	}
+}
+
+func TestPackGitRepoOptionCommit(t *testing.T) {
+	dir, bare, _ := newTestGitRepo(t)
+	defer os.RemoveAll(dir)
+
+	for _, commit := range []string{"--orphan=evil", "-bevil"} {
+		repo, _ := ParseGitURL("git+file://" + bare + "#" + commit)
+		err := PackGitRepo(repo, ioutil.Discard)
+		if err == nil || !strings.Contains(err.Error(), "Unknown commit") {
+			t.Errorf("%s: expected an unknown commit error, got %v", commit, err)
+		}
+	}
+}
@@ -0,0 +1,295 @@
+package nodejs
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// gitShortcuts maps the host shortcuts npm accepts, such as
+// github:owner/repo, to the hosts they stand for
+var gitShortcuts = map[string]string{
+	"github:":    "github.com",
+	"gitlab:":    "gitlab.com",
+	"bitbucket:": "bitbucket.org",
+}
+
+// gitSchemes lists the URL schemes which name git repositories
+var gitSchemes = []string{
+	"git+ssh://",
+	"git+https://",
+	"git+http://",
+	"git+file://",
+	"git://",
+	"ssh://",
+}
+
+// GitRepo identifies a commit in a git repository
+type GitRepo struct {
+	// Host is the hostname of the git server, without any port
+	Host string
+	// Path is the repository path without a .git suffix, such as
+	// owner/repo or group/subgroup/repo
+	Path string
+	// Commit is the commit, tag or branch, if any
+	Commit string
+	// CloneURL is a URL the git client can clone the repository from
+	CloneURL string
+}
+
+func isAllDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// parseGitLocation splits [user@]host[:port]/path and the scp-style
+// [user@]host:path into a host and a repository path. scp reports
+// whether the second form was used.
+func parseGitLocation(location string) (host string, repoPath string, scp bool) {
+	slash := strings.Index(location, "/")
+	if at := strings.LastIndex(location, "@"); at >= 0 && (slash < 0 || at < slash) {
+		location = location[at+1:]
+	}
+
+	end := strings.IndexAny(location, ":/")
+	if end < 0 {
+		return location, "", false
+	}
+	host = location[:end]
+	rest := location[end:]
+	if rest[0] == ':' {
+		rest = rest[1:]
+		if i := strings.Index(rest, "/"); i > 0 && isAllDigits(rest[:i]) {
+			rest = rest[i:]
+		} else {
+			scp = true
+		}
+	}
+	repoPath = strings.TrimSuffix(strings.Trim(rest, "/"), ".git")
+	return strings.ToLower(host), repoPath, scp
+}
+
+// ParseGitURL recognizes the forms npm records git dependencies in:
+// git+ssh://, git+https://, git://, scp-style git@host:owner/repo,
+// host shortcuts such as github:owner/repo and the bare owner/repo
+// GitHub shorthand. Anything after # is the commit. It reports false
+// for URLs which do not name a git repository.
+func ParseGitURL(depURL string) (GitRepo, bool) {
+	location := depURL
+	var repo GitRepo
+	if i := strings.Index(location, "#"); i >= 0 {
+		repo.Commit = strings.TrimPrefix(location[i+1:], "commit=")
+		location = location[:i]
+	}
+
+	for shortcut, host := range gitShortcuts {
+		if strings.HasPrefix(location, shortcut) {
+			repo.Host = host
+			repo.Path = strings.TrimSuffix(strings.TrimPrefix(location, shortcut), ".git")
+			repo.CloneURL = fmt.Sprintf("https://%s/%s.git", host, repo.Path)
+			return repo, repo.Path != ""
+		}
+	}
+
+	for _, scheme := range gitSchemes {
+		if !strings.HasPrefix(location, scheme) {
+			continue
+		}
+		var scp bool
+		repo.Host, repo.Path, scp = parseGitLocation(strings.TrimPrefix(location, scheme))
+		repo.CloneURL = strings.TrimPrefix(location, "git+")
+		if scp {
+			// git does not accept scp-style paths inside ssh:// URLs
+			repo.CloneURL = strings.TrimPrefix(repo.CloneURL, "ssh://")
+		}
+		return repo, repo.Path != ""
+	}
+
+	if strings.Contains(location, ":") {
+		// scp-style git@host:owner/repo.git
+		if at := strings.Index(location, "@"); at > 0 && !strings.Contains(location[:at], "/") {
+			repo.Host, repo.Path, _ = parseGitLocation(location)
+			repo.CloneURL = location
+			return repo, repo.Path != ""
+		}
+		return repo, false
+	}
+
+	// GitHub shorthand owner/repo
+	parts := strings.Split(location, "/")
+	if len(parts) == 2 && parts[0] != "" && parts[1] != "" &&
+		!strings.HasPrefix(parts[0], ".") && !strings.HasPrefix(parts[0], "@") {
+		repo.Host = "github.com"
+		repo.Path = location
+		repo.CloneURL = fmt.Sprintf("https://github.com/%s.git", location)
+		return repo, true
+	}
+	return repo, false
+}
+
+// Tarball URL templates for the git hosts with a known archive endpoint.
+// {base} is the host's base URL, {path} the repository path below it,
+// {owner} everything but the last path segment, {repo} the last segment
+// and {commit} the commit.
+const (
+	GitHubTarballTemplate    = "{base}{path}/archive/{commit}.tar.gz"
+	GitLabTarballTemplate    = "{base}{path}/-/archive/{commit}/{repo}-{commit}.tar.gz"
+	BitbucketTarballTemplate = "{base}{path}/get/{commit}.tar.gz"
+)
+
+// gitHostTemplates maps the host types accepted by GitHostResolver.AddRule
+// to their tarball URL templates
+var gitHostTemplates = map[string]string{
+	"github":    GitHubTarballTemplate,
+	"gitlab":    GitLabTarballTemplate,
+	"bitbucket": BitbucketTarballTemplate,
+}
+
+// GitHost describes how to download a commit of a repository on one git
+// host as a tarball
+type GitHost struct {
+	// BaseURL is the web root repositories are found under, such as
+	// https://gitlab.com/ or https://example.com/gitlab/
+	BaseURL string
+	// TarballTemplate builds the tarball URL; see GitHubTarballTemplate
+	TarballTemplate string
+}
+
+// DefaultGitHosts are the public git hosts npm knows about
+var DefaultGitHosts = []GitHost{
+	{"https://github.com/", "https://codeload.github.com/{path}/tar.gz/{commit}"},
+	{"https://gitlab.com/", GitLabTarballTemplate},
+	{"https://bitbucket.org/", BitbucketTarballTemplate},
+}
+
+// DefaultGitCommit is downloaded when a git dependency names no commit
+const DefaultGitCommit = "master"
+
+type gitHost struct {
+	base     *url.URL
+	template string
+}
+
+// repoPath returns the path of repo below the host's base URL
+func (h gitHost) repoPath(repo GitRepo) (string, bool) {
+	if !strings.EqualFold(h.base.Hostname(), repo.Host) {
+		return "", false
+	}
+	repoPath := repo.Path
+	if basePath := strings.Trim(h.base.Path, "/"); basePath != "" {
+		if !strings.HasPrefix(repoPath, basePath+"/") {
+			return "", false
+		}
+		repoPath = strings.TrimPrefix(repoPath, basePath+"/")
+	}
+	if !strings.Contains(repoPath, "/") {
+		return "", false
+	}
+	return repoPath, true
+}
+
+func (h gitHost) tarballURL(repo GitRepo) (string, bool) {
+	repoPath, ok := h.repoPath(repo)
+	if !ok {
+		return "", false
+	}
+	commit := repo.Commit
+	if commit == "" {
+		commit = DefaultGitCommit
+	}
+	split := strings.LastIndex(repoPath, "/")
+	replacer := strings.NewReplacer(
+		"{base}", withTrailingSlash(h.base.String()),
+		"{path}", repoPath,
+		"{owner}", repoPath[:split],
+		"{repo}", repoPath[split+1:],
+		"{commit}", url.PathEscape(commit),
+	)
+	return replacer.Replace(h.template), true
+}
+
+// GitHostResolver translates git dependencies into tarball URLs using a
+// table of git hosts, so that they can be downloaded without a git client
+type GitHostResolver struct {
+	hosts []gitHost
+}
+
+// compileGitHost checks host's base URL and tarball template
+func compileGitHost(host GitHost) (gitHost, error) {
+	base, err := url.Parse(withTrailingSlash(host.BaseURL))
+	if err != nil || base.Host == "" {
+		return gitHost{}, fmt.Errorf("Invalid git host base URL: %s", host.BaseURL)
+	}
+	if !strings.Contains(host.TarballTemplate, "{commit}") {
+		return gitHost{}, fmt.Errorf("Git host tarball template has no {commit}: %s", host.TarballTemplate)
+	}
+	return gitHost{base: base, template: host.TarballTemplate}, nil
+}
+
+// mustGitHost compiles one of the static DefaultGitHosts, which the
+// tests check are valid
+func mustGitHost(host GitHost) gitHost {
+	compiled, err := compileGitHost(host)
+	if err != nil {
+		panic(err)
+	}
+	return compiled
+}
+
+// defaultGitHosts are the compiled DefaultGitHosts, last first
+var defaultGitHosts = func() []gitHost {
+	var hosts []gitHost
+	for _, host := range DefaultGitHosts {
+		hosts = append([]gitHost{mustGitHost(host)}, hosts...)
+	}
+	return hosts
+}()
+
+// NewGitHostResolver creates a GitHostResolver for DefaultGitHosts
+func NewGitHostResolver() *GitHostResolver {
+	return &GitHostResolver{
+		hosts: append([]gitHost(nil), defaultGitHosts...),
+	}
+}
+
+// Add registers a git host. Hosts added later take precedence, so
+// self-hosted instances can override the defaults.
+func (r *GitHostResolver) Add(host GitHost) error {
+	compiled, err := compileGitHost(host)
+	if err != nil {
+		return err
+	}
+	r.hosts = append([]gitHost{compiled}, r.hosts...)
+	return nil
+}
+
+// AddRule registers a git host from a rule of the form TYPE=BASEURL,
+// where TYPE is github, gitlab or bitbucket, for self-hosted instances;
+// or HOST=TEMPLATE for any other host serving tarballs at TEMPLATE.
+func (r *GitHostResolver) AddRule(rule string) error {
+	parts := strings.SplitN(rule, "=", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return fmt.Errorf("Invalid git host rule: %s", rule)
+	}
+	key := strings.TrimSpace(parts[0])
+	value := strings.TrimSpace(parts[1])
+	if template, ok := gitHostTemplates[key]; ok {
+		return r.Add(GitHost{BaseURL: value, TarballTemplate: template})
+	}
+	return r.Add(GitHost{BaseURL: "https://" + key + "/", TarballTemplate: value})
+}
+
+// TarballURL returns the tarball URL for repo, reporting false when no
+// registered host serves it
+func (r *GitHostResolver) TarballURL(repo GitRepo) (string, bool) {
+	for _, host := range r.hosts {
+		if tarballURL, ok := host.tarballURL(repo); ok {
+			return tarballURL, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,464 @@
+package nodejs
+
+import (
+	"container/heap"
+	"path"
+	"sort"
+	"strings"
+)
+
+// DependencyNode is one installed copy of a package in a DependencyGraph
+type DependencyNode struct {
+	NodeDependency
+	// Path is where the lockfile puts the package: its install path, such
+	// as node_modules/a/node_modules/b, for npm lockfiles, which nest
+	// packages, and its lockfile key for yarn and pnpm, which don't. The
+	// root project's Path is "".
+	Path string
+	// Parents are the nodes depending on this one, which is the graph's
+	// Root for the project's own dependencies
+	Parents []*DependencyNode
+	// Dependencies are the nodes this one depends on
+	Dependencies []*DependencyNode
+}
+
+// DependencyGraph is the tree of packages a lockfile installs, with the
+// edges between each package and the packages it depends on
+type DependencyGraph struct {
+	// Root is the project itself, which isn't one of the Nodes
+	Root *DependencyNode
+	// Nodes are the installed packages, sorted by Path and then by Name
+	// so that every parse of a lockfile lists them in the same order.
+	// Paths are compared a directory at a time, so packages nested in
+	// another follow it directly.
+	Nodes  []*DependencyNode
+	byPath map[string]*DependencyNode
+	// resolveProject finds the package a dependency of the project's
+	// package.json resolves to, for lockfiles which don't record the
+	// project's own dependencies
+	resolveProject func(name, spec string) *DependencyNode
+	// guessedRoots are the nodes finish linked to the root because
+	// nothing the lockfile records reaches them
+	guessedRoots []*DependencyNode
+	// devEdges and optionalEdges are the edges of dev and optional
+	// dependencies
+	devEdges      map[dependencyEdge]bool
+	optionalEdges map[dependencyEdge]bool
+	// flagsFromEdges is set for lockfiles which don't mark dev and
+	// optional packages, whose flags markFlags works out from the edges
+	// instead, and typedRoot once the root's edges tell dev and
+	// optional dependencies apart
+	flagsFromEdges bool
+	typedRoot      bool
+}
+
+// dependencyEdge is the edge from a package to one it depends on
+type dependencyEdge struct {
+	parent, child *DependencyNode
+}
+
+// NewDependencyGraph creates an empty graph for the project with the
+// given name and version
+func NewDependencyGraph(name, version string) *DependencyGraph {
+	return &DependencyGraph{
+		Root: &DependencyNode{
+			NodeDependency: NodeDependency{Name: name, Version: version},
+		},
+		byPath:        make(map[string]*DependencyNode),
+		devEdges:      make(map[dependencyEdge]bool),
+		optionalEdges: make(map[dependencyEdge]bool),
+	}
+}
+
+// Add adds a package installed at installPath, returning its node
+func (g *DependencyGraph) Add(dep NodeDependency, installPath string) *DependencyNode {
+	node := &DependencyNode{NodeDependency: dep, Path: installPath}
+	g.Nodes = append(g.Nodes, node)
+	g.byPath[installPath] = node
+	return node
+}
+
+// Node returns the node installed at installPath, or nil
+func (g *DependencyGraph) Node(installPath string) *DependencyNode {
+	return g.byPath[installPath]
+}
+
+// unlink removes the edge from parent to child
+func (g *DependencyGraph) unlink(parent, child *DependencyNode) {
+	parent.Dependencies = removeNode(parent.Dependencies, child)
+	child.Parents = removeNode(child.Parents, parent)
+}
+
+// removeNode returns nodes without node
+func removeNode(nodes []*DependencyNode, node *DependencyNode) []*DependencyNode {
+	var kept []*DependencyNode
+	for _, n := range nodes {
+		if n != node {
+			kept = append(kept, n)
+		}
+	}
+	return kept
+}
+
+// Link records that parent depends on child
+func (g *DependencyGraph) Link(parent, child *DependencyNode) {
+	if parent == nil || child == nil || parent == child {
+		return
+	}
+	for _, existing := range parent.Dependencies {
+		if existing == child {
+			return
+		}
+	}
+	parent.Dependencies = append(parent.Dependencies, child)
+	child.Parents = append(child.Parents, parent)
+}
+
+// linkAs links parent to child, marking the edge in edges, which are
+// the graph's devEdges or optionalEdges, unless parent already depends on
+// child
+func (g *DependencyGraph) linkAs(parent, child *DependencyNode, edges map[dependencyEdge]bool) {
+	if parent == nil || child == nil || parent == child {
+		return
+	}
+	for _, existing := range parent.Dependencies {
+		if existing == child {
+			return
+		}
+	}
+	g.Link(parent, child)
+	edges[dependencyEdge{parent, child}] = true
+}
+
+// KnowsFlags reports whether the graph tells dev and optional packages
+// apart. yarn lockfiles don't, so their graphs only do once LinkProject
+// has read the project's package.json.
+func (g *DependencyGraph) KnowsFlags() bool {
+	return !g.flagsFromEdges || g.typedRoot
+}
+
+// markFlags works out the flags of each node from the edges reaching it,
+// as npm does writing package-lock.json: a package is dev when every path
+// from the root to it goes through a dev dependency, optional when every
+// path goes through an optional one, and devOptional when it is neither
+// but no path avoids both
+func (g *DependencyGraph) markFlags() {
+	reach := func(skipDev, skipOptional bool) map[*DependencyNode]bool {
+		reached := map[*DependencyNode]bool{g.Root: true}
+		queue := []*DependencyNode{g.Root}
+		for len(queue) > 0 {
+			node := queue[0]
+			queue = queue[1:]
+			for _, child := range node.Dependencies {
+				edge := dependencyEdge{node, child}
+				if reached[child] || (skipDev && g.devEdges[edge]) || (skipOptional && g.optionalEdges[edge]) {
+					continue
+				}
+				reached[child] = true
+				queue = append(queue, child)
+			}
+		}
+		return reached
+	}
+	withoutDev, withoutOptional, prod := reach(true, false), reach(false, true), reach(true, true)
+	for _, node := range g.Nodes {
+		node.Dev = !withoutDev[node]
+		node.Optional = !withoutOptional[node]
+		node.DevOptional = !prod[node] && !node.Dev && !node.Optional
+	}
+}
+
+// finish sorts the nodes and links the root to whatever it can't reach
+// yet, so that every node is reachable from it. Builders link the root
+// to the project's dependencies first where the lockfile records them,
+// and call it once every node is linked.
+func (g *DependencyGraph) finish() {
+	sort.SliceStable(g.Nodes, func(i, j int) bool {
+		if c := comparePaths(g.Nodes[i].Path, g.Nodes[j].Path); c != 0 {
+			return c < 0
+		}
+		return g.Nodes[i].Name < g.Nodes[j].Name
+	})
+	g.linkUnreachable()
+	if g.flagsFromEdges {
+		g.markFlags()
+	}
+}
+
+// linkUnreachable makes the root depend on the packages it can't reach:
+// first those nothing depends on, then a package on each dependency cycle
+// nothing outside the cycle depends on. These are guesses at the
+// project's own dependencies, which LinkProject replaces.
+func (g *DependencyGraph) linkUnreachable() {
+	reachable := make(map[*DependencyNode]bool)
+	var reach func(node *DependencyNode)
+	reach = func(node *DependencyNode) {
+		if reachable[node] {
+			return
+		}
+		reachable[node] = true
+		for _, child := range node.Dependencies {
+			reach(child)
+		}
+	}
+	reach(g.Root)
+	guess := func(node *DependencyNode) {
+		g.Link(g.Root, node)
+		g.guessedRoots = append(g.guessedRoots, node)
+		reach(node)
+	}
+
+	for _, node := range g.Nodes {
+		if !reachable[node] && len(node.Parents) == 0 {
+			guess(node)
+		}
+	}
+	for _, node := range g.Nodes {
+		if reachable[node] {
+			continue
+		}
+		// Every package above node is unreachable and has parents, so
+		// climbing them ends up going round a cycle
+		climbed := make(map[*DependencyNode]bool)
+		for !climbed[node] {
+			climbed[node] = true
+			node = node.Parents[0]
+		}
+		guess(node)
+	}
+}
+
+// LinkProject makes the root depend on the packages the project's
+// package.json requires, replacing the root edges finish guessed, for
+// lockfiles which don't record the project's own dependencies: version 1
+// package-lock.json and npm-shrinkwrap.json files and yarn classic's
+// yarn.lock. yarn berry lockfiles record them, but not which are dev or
+// optional dependencies, so LinkProject marks those of its graphs. Graphs
+// of other lockfiles are left as they are.
+func (g *DependencyGraph) LinkProject(packageJSON PackageJSON) {
+	if g.resolveProject == nil {
+		return
+	}
+	for _, node := range g.guessedRoots {
+		g.unlink(g.Root, node)
+	}
+	g.guessedRoots = nil
+
+	if g.Root.Name == "" {
+		g.Root.Name, g.Root.Version = packageJSON.Name, packageJSON.Version
+	}
+	blocks := []struct {
+		deps  map[string]string
+		edges map[dependencyEdge]bool
+	}{
+		{packageJSON.Dependencies, nil},
+		{packageJSON.OptionalDependencies, g.optionalEdges},
+		{packageJSON.DevDependencies, g.devEdges},
+	}
+	typed := make(map[*DependencyNode]bool)
+	for _, block := range blocks {
+		for _, name := range sortedKeys(block.deps) {
+			node := g.resolveProject(name, block.deps[name])
+			if node == nil || typed[node] {
+				continue
+			}
+			// A package listed in several blocks takes the first type
+			typed[node] = true
+			g.Link(g.Root, node)
+			if block.edges != nil {
+				block.edges[dependencyEdge{g.Root, node}] = true
+			}
+		}
+	}
+	g.linkUnreachable()
+	if g.flagsFromEdges {
+		g.typedRoot = true
+		g.markFlags()
+	}
+}
+
+// Find returns the nodes of every installed copy of the named package
+func (g *DependencyGraph) Find(name string) []*DependencyNode {
+	var nodes []*DependencyNode
+	for _, node := range g.Nodes {
+		if node.Name == name {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+// Dependencies flattens the graph into a list of packages to download,
+// merging the installed copies of each package version. They're in the
+// order of the nodes, each package version where it's first installed.
+func (g *DependencyGraph) Dependencies() []NodeDependency {
+	var deps []NodeDependency
+	for _, node := range g.Nodes {
+		deps = append(deps, node.NodeDependency)
+	}
+	return dedupeDependencies(deps)
+}
+
+// resolveInstallPath finds the package name required from the directory
+// fromPath the way node does, looking in fromPath's node_modules and then
+// in those of each directory above it
+func (g *DependencyGraph) resolveInstallPath(fromPath, name string) *DependencyNode {
+	dir := fromPath
+	for {
+		if node := g.byPath[path.Join(dir, nodeModulesDir, name)]; node != nil {
+			return node
+		}
+		if dir == "" {
+			return nil
+		}
+		dir = path.Dir(dir)
+		if dir == "." {
+			dir = ""
+		}
+	}
+}
+
+// comparePaths orders slash separated paths a directory at a time, so
+// that a/b comes before a-b as it would in a tree
+func comparePaths(a, b string) int {
+	aParts, bParts := strings.Split(a, "/"), strings.Split(b, "/")
+	for i := 0; i < len(aParts) && i < len(bParts); i++ {
+		if c := strings.Compare(aParts[i], bParts[i]); c != 0 {
+			return c
+		}
+	}
+	return compareInts(len(aParts), len(bParts))
+}
+
+// sortedKeys returns the keys of a dependencies block in order
+func sortedKeys(deps map[string]string) []string {
+	var keys []string
+	for key := range deps {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// linkInstalled links from to each named package it requires
+func (g *DependencyGraph) linkInstalled(from *DependencyNode, fromPath string, blocks ...map[string]string) {
+	for _, deps := range blocks {
+		for _, name := range sortedKeys(deps) {
+			g.Link(from, g.resolveInstallPath(fromPath, name))
+		}
+	}
+}
+
+// MaxPaths caps the paths PathsTo returns, since packages many others
+// depend on can be reached along exponentially many of them
+const MaxPaths = 100
+
+// maxPathSearch caps the partial paths PathsTo extends looking for them
+const maxPathSearch = 100 * MaxPaths
+
+// pathTrail is a path from a node down to the one PathsTo looks for,
+// sharing its tail with the other trails it was extended from
+type pathTrail struct {
+	node   *DependencyNode
+	below  *pathTrail
+	length int
+	// cost is the length of the shortest path through the trail
+	cost int
+	seq  int
+}
+
+// contains reports whether node is on the trail already
+func (t *pathTrail) contains(node *DependencyNode) bool {
+	for ; t != nil; t = t.below {
+		if t.node == node {
+			return true
+		}
+	}
+	return false
+}
+
+// trailQueue orders trails by their cost, then the longest first, which
+// finishes paths before starting others as long, and then by when they
+// were found, so the search is deterministic
+type trailQueue []*pathTrail
+
+func (q trailQueue) Len() int { return len(q) }
+func (q trailQueue) Less(i, j int) bool {
+	if q[i].cost != q[j].cost {
+		return q[i].cost < q[j].cost
+	}
+	if q[i].length != q[j].length {
+		return q[i].length > q[j].length
+	}
+	return q[i].seq < q[j].seq
+}
+func (q trailQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *trailQueue) Push(x interface{}) { *q = append(*q, x.(*pathTrail)) }
+func (q *trailQueue) Pop() interface{} {
+	old := *q
+	t := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return t
+}
+
+// depths returns the length of the shortest path from the root to each
+// node it reaches
+func (g *DependencyGraph) depths() map[*DependencyNode]int {
+	depth := map[*DependencyNode]int{g.Root: 0}
+	queue := []*DependencyNode{g.Root}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		for _, child := range node.Dependencies {
+			if _, ok := depth[child]; !ok {
+				depth[child] = depth[node] + 1
+				queue = append(queue, child)
+			}
+		}
+	}
+	return depth
+}
+
+// PathsTo returns the paths from the root to node, each starting with
+// the root and ending with node, shortest first, up to MaxPaths of them,
+// and whether it gave up looking for more, having found MaxPaths or
+// extended as many trails as it will. Paths never go round a dependency
+// cycle. Trails up from node are extended cheapest first, the cost of
+// each being its length plus the depth of its top node, so that paths
+// come out in order of length without walking every one.
+func (g *DependencyGraph) PathsTo(node *DependencyNode) ([][]*DependencyNode, bool) {
+	depth := g.depths()
+	if _, ok := depth[node]; !ok {
+		return nil, false
+	}
+
+	var paths [][]*DependencyNode
+	seq := 0
+	queue := &trailQueue{{node: node, length: 1, cost: depth[node] + 1}}
+	for searched := 0; queue.Len() > 0 && len(paths) < MaxPaths && searched < maxPathSearch; searched++ {
+		trail := heap.Pop(queue).(*pathTrail)
+		if trail.node == g.Root {
+			path := make([]*DependencyNode, 0, trail.length)
+			for t := trail; t != nil; t = t.below {
+				path = append(path, t.node)
+			}
+			paths = append(paths, path)
+			continue
+		}
+		for _, parent := range trail.node.Parents {
+			parentDepth, ok := depth[parent]
+			if !ok || trail.contains(parent) {
+				continue
+			}
+			seq++
+			heap.Push(queue, &pathTrail{
+				node:   parent,
+				below:  trail,
+				length: trail.length + 1,
+				cost:   trail.length + 1 + parentDepth,
+				seq:    seq,
+			})
+		}
+	}
+	return paths, queue.Len() > 0
+}
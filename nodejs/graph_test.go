@@ -0,0 +1,573 @@
+package nodejs
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// graphEdges describes each edge of g as parent > child, naming nodes by
+// path and the root as "."
+func graphEdges(g *DependencyGraph) []string {
+	pathName := func(node *DependencyNode) string {
+		if node == g.Root {
+			return "."
+		}
+		return node.Path
+	}
+	var edges []string
+	for _, node := range append([]*DependencyNode{g.Root}, g.Nodes...) {
+		for _, child := range node.Dependencies {
+			edges = append(edges, pathName(node)+" > "+pathName(child))
+		}
+	}
+	sort.Strings(edges)
+	return edges
+}
+
+func assertGraphEdges(t *testing.T, g *DependencyGraph, expected []string) {
+	if edges := graphEdges(g); !reflect.DeepEqual(edges, expected) {
+		t.Errorf("Expected edges:\n%q\ngot:\n%q", expected, edges)
+	}
+}
+
+func TestNPMDependencyGraphV1(t *testing.T) {
+	g, err := ParseDependencyGraph(PackageLockFileName, []byte(`{
+  "name": "my-app",
+  "version": "1.0.0",
+  "lockfileVersion": 1,
+  "requires": true,
+  "dependencies": {
+    "ee-first": {
+      "version": "1.1.1",
+      "resolved": "https://registry.npmjs.org/ee-first/-/ee-first-1.1.1.tgz"
+    },
+    "on-finished": {
+      "version": "2.3.0",
+      "resolved": "https://registry.npmjs.org/on-finished/-/on-finished-2.3.0.tgz",
+      "requires": {
+        "ee-first": "1.1.1"
+      }
+    },
+    "old-finished": {
+      "version": "1.0.0",
+      "resolved": "https://registry.npmjs.org/old-finished/-/old-finished-1.0.0.tgz",
+      "requires": {
+        "ee-first": "1.0.0"
+      },
+      "dependencies": {
+        "ee-first": {
+          "version": "1.0.0",
+          "resolved": "https://registry.npmjs.org/ee-first/-/ee-first-1.0.0.tgz"
+        }
+      }
+    }
+  }
+}`))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if g.Root.Name != "my-app" || g.Root.Version != "1.0.0" {
+		t.Errorf("Expected root my-app@1.0.0, got %s", g.Root.GetCanonicalName())
+	}
+	var paths []string
+	for _, node := range g.Nodes {
+		paths = append(paths, node.Path)
+	}
+	expectedPaths := []string{
+		"node_modules/ee-first",
+		"node_modules/old-finished",
+		"node_modules/old-finished/node_modules/ee-first",
+		"node_modules/on-finished",
+	}
+	if !reflect.DeepEqual(paths, expectedPaths) {
+		t.Errorf("Expected paths %q, got %q", expectedPaths, paths)
+	}
+	assertGraphEdges(t, g, []string{
+		". > node_modules/old-finished",
+		". > node_modules/on-finished",
+		"node_modules/old-finished > node_modules/old-finished/node_modules/ee-first",
+		"node_modules/on-finished > node_modules/ee-first",
+	})
+
+	if nodes := g.Find("ee-first"); len(nodes) != 2 || nodes[1].Version != "1.0.0" {
+		t.Errorf("Expected both copies of ee-first, got %+v", nodes)
+	}
+	if node := g.Node("node_modules/ee-first"); node == nil || node.Parents[0].Name != "on-finished" {
+		t.Errorf("Expected on-finished to depend on node_modules/ee-first")
+	}
+}
+
+func TestNPMDependencyGraphV1LinkProject(t *testing.T) {
+	// ee-first is also a direct dependency, which only package.json says
+	g, err := ParseDependencyGraph(PackageLockFileName, []byte(`{
+  "name": "my-app",
+  "version": "1.0.0",
+  "lockfileVersion": 1,
+  "dependencies": {
+    "ee-first": {"version": "1.1.1"},
+    "on-finished": {"version": "2.3.0", "requires": {"ee-first": "1.1.1"}},
+    "extraneous": {"version": "1.0.0"}
+  }
+}`))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	g.LinkProject(PackageJSON{
+		Dependencies:    map[string]string{"on-finished": "^2.3.0"},
+		DevDependencies: map[string]string{"ee-first": "^1.1.0"},
+	})
+	assertGraphEdges(t, g, []string{
+		". > node_modules/ee-first",
+		". > node_modules/extraneous",
+		". > node_modules/on-finished",
+		"node_modules/on-finished > node_modules/ee-first",
+	})
+	if paths, _ := g.PathsTo(g.Node("node_modules/ee-first")); len(paths) != 2 {
+		t.Errorf("Expected ee-first to be required directly and by on-finished")
+	}
+}
+
+func TestDependencyGraphCycleReachable(t *testing.T) {
+	// a and b only require each other, so nothing is parentless
+	g, err := ParseDependencyGraph(YarnLockfileName, []byte(`a@^1.0.0:
+  version "1.0.0"
+  dependencies:
+    b "^1.0.0"
+
+b@^1.0.0:
+  version "1.0.0"
+  dependencies:
+    a "^1.0.0"
+    c "^1.0.0"
+
+c@^1.0.0:
+  version "1.0.0"
+`))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	assertGraphEdges(t, g, []string{
+		". > a@^1.0.0",
+		"a@^1.0.0 > b@^1.0.0",
+		"b@^1.0.0 > a@^1.0.0",
+		"b@^1.0.0 > c@^1.0.0",
+	})
+
+	g.LinkProject(PackageJSON{
+		Name:         "my-app",
+		Dependencies: map[string]string{"b": "^1.0.0"},
+	})
+	if g.Root.Name != "my-app" {
+		t.Errorf("Expected the root named after package.json, got %s", g.Root.Name)
+	}
+	assertGraphEdges(t, g, []string{
+		". > b@^1.0.0",
+		"a@^1.0.0 > b@^1.0.0",
+		"b@^1.0.0 > a@^1.0.0",
+		"b@^1.0.0 > c@^1.0.0",
+	})
+}
+
+func TestNPMDependencyGraphWorkspaces(t *testing.T) {
+	g, err := ParseDependencyGraph(PackageLockFileName, []byte(`{
+  "name": "my-app",
+  "lockfileVersion": 3,
+  "packages": {
+    "": {
+      "name": "my-app",
+      "workspaces": ["packages/*"],
+      "dependencies": {
+        "ee-first": "^1.1.1"
+      },
+      "devDependencies": {
+        "missing-peer-host": "^1.0.0"
+      }
+    },
+    "node_modules/app": {
+      "resolved": "packages/app",
+      "link": true
+    },
+    "node_modules/ee-first": {
+      "version": "1.1.1",
+      "resolved": "https://registry.npmjs.org/ee-first/-/ee-first-1.1.1.tgz"
+    },
+    "packages/app": {
+      "version": "1.0.0",
+      "dependencies": {
+        "on-finished": "~2.3.0"
+      }
+    },
+    "packages/app/node_modules/on-finished": {
+      "version": "2.3.0",
+      "resolved": "https://registry.npmjs.org/on-finished/-/on-finished-2.3.0.tgz",
+      "dependencies": {
+        "ee-first": "1.1.1"
+      }
+    }
+  }
+}`))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	assertGraphEdges(t, g, []string{
+		". > node_modules/app",
+		". > node_modules/ee-first",
+		"node_modules/app > packages/app/node_modules/on-finished",
+		"packages/app/node_modules/on-finished > node_modules/ee-first",
+	})
+	if len(g.Dependencies()) != 3 {
+		t.Errorf("Expected 3 dependencies, got %+v", g.Dependencies())
+	}
+}
+
+func TestYarnDependencyGraph(t *testing.T) {
+	g, err := ParseDependencyGraph(YarnLockfileName, yarnLockV1)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	assertGraphEdges(t, g, []string{
+		". > @babel/code-frame@^7.0.0, @babel/code-frame@^7.10.4",
+		". > my-fork@git+https://github.com/bosgood/my-fork.git#v1.0.0",
+		". > on-finished@^2.3.0, on-finished@~2.3.0",
+		"on-finished@^2.3.0, on-finished@~2.3.0 > ee-first@1.1.1",
+	})
+}
+
+func TestYarnBerryDependencyGraph(t *testing.T) {
+	lockfile, err := ParseYarnBerryLockfile([]byte(`__metadata:
+  version: 6
+
+"ee-first@npm:1.1.1":
+  version: 1.1.1
+  resolution: "ee-first@npm:1.1.1"
+
+"my-app@workspace:.":
+  version: 0.0.0-use.local
+  resolution: "my-app@workspace:."
+  dependencies:
+    on-finished: ^2.3.0
+
+"on-finished@npm:^2.3.0":
+  version: 2.3.0
+  resolution: "on-finished@npm:2.3.0"
+  dependencies:
+    ee-first: 1.1.1
+`))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	g := YarnBerryDependencyGraph(lockfile)
+	if g.Root.Name != "my-app" {
+		t.Errorf("Expected the root workspace as the root, got %s", g.Root.Name)
+	}
+	assertGraphEdges(t, g, []string{
+		". > on-finished@npm:^2.3.0",
+		"on-finished@npm:^2.3.0 > ee-first@npm:1.1.1",
+	})
+}
+
+func TestPNPMDependencyGraph(t *testing.T) {
+	tests := []struct {
+		lockfile []byte
+		expected []string
+	}{
+		{
+			pnpmLockV5,
+			[]string{
+				". > /@babel/code-frame/7.10.4",
+				". > /on-finished/2.3.0_ee-first@1.1.1",
+				". > github.com/bosgood/my-fork/0123456789abcdef0123456789abcdef01234567",
+				"/on-finished/2.3.0_ee-first@1.1.1 > /ee-first/1.1.1",
+			},
+		},
+		{
+			pnpmLockV9,
+			[]string{
+				". > @babel/code-frame@7.10.4",
+				". > my-fork@https://codeload.github.com/bosgood/my-fork/tar.gz/0123456789abcdef0123456789abcdef01234567",
+				". > on-finished@2.3.0",
+				". > shared@file:../shared",
+				"on-finished@2.3.0 > ee-first@1.1.1",
+			},
+		},
+	}
+	for _, test := range tests {
+		g, err := ParseDependencyGraph(PNPMLockfileName, test.lockfile)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		assertGraphEdges(t, g, test.expected)
+	}
+}
+
+func TestDependencyGraphPathsTo(t *testing.T) {
+	g := NewDependencyGraph("my-app", "1.0.0")
+	mkdirp := g.Add(NodeDependency{Name: "mkdirp", Version: "0.5.1"}, "node_modules/mkdirp")
+	optimist := g.Add(NodeDependency{Name: "optimist", Version: "0.6.1"}, "node_modules/optimist")
+	minimist := g.Add(NodeDependency{Name: "minimist", Version: "0.0.8"}, "node_modules/minimist")
+	g.Link(g.Root, mkdirp)
+	g.Link(g.Root, optimist)
+	g.Link(optimist, mkdirp)
+	g.Link(mkdirp, minimist)
+	// A cycle back up the tree
+	g.Link(minimist, optimist)
+
+	paths, truncated := g.PathsTo(minimist)
+	if truncated {
+		t.Errorf("Expected every path to be found")
+	}
+	var described []string
+	for _, path := range paths {
+		var names []string
+		for _, node := range path {
+			names = append(names, node.GetCanonicalName())
+		}
+		described = append(described, strings.Join(names, " > "))
+	}
+	expected := []string{
+		"my-app@1.0.0 > mkdirp@0.5.1 > minimist@0.0.8",
+		"my-app@1.0.0 > optimist@0.6.1 > mkdirp@0.5.1 > minimist@0.0.8",
+	}
+	if !reflect.DeepEqual(described, expected) {
+		t.Errorf("Expected %q, got %q", expected, described)
+	}
+}
+
+func TestDependencyGraphPathsToDiamonds(t *testing.T) {
+	// Twenty layers of two packages, each depending on both packages of
+	// the next layer, reach the bottom along 2^20 paths
+	g := NewDependencyGraph("my-app", "1.0.0")
+	above := []*DependencyNode{g.Root}
+	for layer := 0; layer < 20; layer++ {
+		var nodes []*DependencyNode
+		for _, side := range []string{"a", "b"} {
+			name := fmt.Sprintf("%s%d", side, layer)
+			node := g.Add(NodeDependency{Name: name, Version: "1.0.0"}, "node_modules/"+name)
+			for _, parent := range above {
+				g.Link(parent, node)
+			}
+			nodes = append(nodes, node)
+		}
+		above = nodes
+	}
+	bottom := g.Add(NodeDependency{Name: "tslib", Version: "2.6.2"}, "node_modules/tslib")
+	g.Link(above[0], bottom)
+	g.Link(above[1], bottom)
+	// A shortcut straight from the project
+	g.Link(g.Root, bottom)
+
+	paths, truncated := g.PathsTo(bottom)
+	if len(paths) != MaxPaths || !truncated {
+		t.Fatalf("Expected %d paths and more left out, got %d (%t)", MaxPaths, len(paths), truncated)
+	}
+	if len(paths[0]) != 2 {
+		t.Errorf("Expected the direct dependency first, got %d steps", len(paths[0]))
+	}
+	for i, path := range paths[1:] {
+		if len(path) != 22 || path[0] != g.Root || path[21] != bottom {
+			t.Fatalf("Unexpected path %d of %d steps", i+1, len(path))
+		}
+	}
+}
+
+func TestDependencyGraphPathsToSearchLimit(t *testing.T) {
+	// A chain longer than the trails PathsTo extends
+	g := NewDependencyGraph("my-app", "1.0.0")
+	above := g.Root
+	for i := 0; i <= maxPathSearch; i++ {
+		name := fmt.Sprintf("p%d", i)
+		node := g.Add(NodeDependency{Name: name, Version: "1.0.0"}, "node_modules/"+name)
+		g.Link(above, node)
+		above = node
+	}
+
+	paths, truncated := g.PathsTo(above)
+	if len(paths) != 0 || !truncated {
+		t.Errorf("Expected the search to give up, got %d paths (%t)", len(paths), truncated)
+	}
+}
+
+// graphFlags describes the flags of each node in g by package name
+func graphFlags(g *DependencyGraph) map[string]string {
+	flags := make(map[string]string)
+	for _, node := range g.Nodes {
+		var names []string
+		if node.Dev {
+			names = append(names, "dev")
+		}
+		if node.Optional {
+			names = append(names, "optional")
+		}
+		if node.DevOptional {
+			names = append(names, "devOptional")
+		}
+		flags[node.Name] = strings.Join(names, ",")
+	}
+	return flags
+}
+
+func TestDependencyGraphFlagsFromEdges(t *testing.T) {
+	yarnLock := []byte(`
+chalk@^2.0.0:
+  version "2.4.2"
+  resolved "https://registry.yarnpkg.com/chalk/-/chalk-2.4.2.tgz"
+  dependencies:
+    ee-first "1.1.1"
+
+ee-first@1.1.1:
+  version "1.1.1"
+  resolved "https://registry.yarnpkg.com/ee-first/-/ee-first-1.1.1.tgz"
+
+fsevents@^1.2.7:
+  version "1.2.13"
+  resolved "https://registry.yarnpkg.com/fsevents/-/fsevents-1.2.13.tgz"
+
+jest@^26.0.0:
+  version "26.6.3"
+  resolved "https://registry.yarnpkg.com/jest/-/jest-26.6.3.tgz"
+  dependencies:
+    chalk "^2.0.0"
+  optionalDependencies:
+    fsevents "^1.2.7"
+
+on-finished@^2.3.0:
+  version "2.3.0"
+  resolved "https://registry.yarnpkg.com/on-finished/-/on-finished-2.3.0.tgz"
+  dependencies:
+    ee-first "1.1.1"
+`)
+	yarnBerryLock := []byte(`__metadata:
+  version: 6
+
+"chalk@npm:^2.0.0":
+  version: 2.4.2
+  resolution: "chalk@npm:2.4.2"
+  dependencies:
+    ee-first: 1.1.1
+
+"ee-first@npm:1.1.1":
+  version: 1.1.1
+  resolution: "ee-first@npm:1.1.1"
+
+"fsevents@npm:^1.2.7":
+  version: 1.2.13
+  resolution: "fsevents@npm:1.2.13"
+
+"jest@npm:^26.0.0":
+  version: 26.6.3
+  resolution: "jest@npm:26.6.3"
+  dependencies:
+    chalk: ^2.0.0
+    fsevents: ^1.2.7
+  dependenciesMeta:
+    fsevents:
+      optional: true
+
+"my-app@workspace:.":
+  version: 0.0.0-use.local
+  resolution: "my-app@workspace:."
+  dependencies:
+    jest: ^26.0.0
+    on-finished: ^2.3.0
+
+"on-finished@npm:^2.3.0":
+  version: 2.3.0
+  resolution: "on-finished@npm:2.3.0"
+  dependencies:
+    ee-first: 1.1.1
+`)
+	pnpmLock := []byte(`lockfileVersion: '9.0'
+
+importers:
+
+  .:
+    dependencies:
+      on-finished:
+        specifier: ^2.3.0
+        version: 2.3.0
+    devDependencies:
+      jest:
+        specifier: ^26.0.0
+        version: 26.6.3
+
+packages:
+
+  chalk@2.4.2:
+    resolution: {integrity: sha512-chalk}
+
+  ee-first@1.1.1:
+    resolution: {integrity: sha1-WQxhFWsK4vTwJVcyoViyZrxWsh0=}
+
+  fsevents@1.2.13:
+    resolution: {integrity: sha512-fsevents}
+
+  jest@26.6.3:
+    resolution: {integrity: sha512-jest}
+
+  on-finished@2.3.0:
+    resolution: {integrity: sha1-IPEzZIGwg811M3mSoWlxqi2QaUc=}
+
+snapshots:
+
+  chalk@2.4.2:
+    dependencies:
+      ee-first: 1.1.1
+
+  jest@26.6.3:
+    dependencies:
+      chalk: 2.4.2
+    optionalDependencies:
+      fsevents: 1.2.13
+
+  on-finished@2.3.0:
+    dependencies:
+      ee-first: 1.1.1
+`)
+	packageJSON := PackageJSON{
+		Name:            "my-app",
+		Dependencies:    map[string]string{"on-finished": "^2.3.0"},
+		DevDependencies: map[string]string{"jest": "^26.0.0"},
+	}
+	expected := map[string]string{
+		"chalk":       "dev",
+		"ee-first":    "",
+		"fsevents":    "dev,optional",
+		"jest":        "dev",
+		"on-finished": "",
+	}
+
+	tests := []struct {
+		name        string
+		fileName    string
+		lockfile    []byte
+		linkProject bool
+	}{
+		{"yarn", YarnLockfileName, yarnLock, true},
+		{"yarn berry", YarnLockfileName, yarnBerryLock, true},
+		{"pnpm", PNPMLockfileName, pnpmLock, false},
+	}
+	for _, test := range tests {
+		g, err := ParseDependencyGraph(test.fileName, test.lockfile)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if test.linkProject {
+			if g.KnowsFlags() {
+				t.Errorf("Expected %s not to know dev dependencies without package.json", test.name)
+			}
+			g.LinkProject(packageJSON)
+		}
+		if !g.KnowsFlags() {
+			t.Errorf("Expected %s to know dev dependencies", test.name)
+		}
+		if flags := graphFlags(g); !reflect.DeepEqual(flags, expected) {
+			t.Errorf("Expected %s flags %v, got %v", test.name, expected, flags)
+		}
+	}
+}
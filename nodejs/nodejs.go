@@ -1,23 +1,56 @@
 package nodejs
 
 import (
+	"bitbucket.org/bosgood/dep-get/lib/fs"
+	"encoding/json"
 	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
 )
 
-// DependenciesFileName is the name of the nodejs dependencies lock file
-const DependenciesFileName = "npm-shrinkwrap.json"
+const (
+	// NPMShrinkwrapFileName is the name of the npm shrinkwrap lock file
+	NPMShrinkwrapFileName = "npm-shrinkwrap.json"
+	// PackageLockFileName is the name of the npm package lock file
+	PackageLockFileName = "package-lock.json"
+)
+
+// DependenciesFileNames lists the nodejs dependencies lock files
+// in the order they are looked for
+var DependenciesFileNames = []string{
+	NPMShrinkwrapFileName,
+	PackageLockFileName,
+	YarnLockfileName,
+	PNPMLockfileName,
+}
+
+// DefaultRegistryURL is the npm registry used to reconstruct tarball URLs
+// for lockfiles which only record package names and versions
+const DefaultRegistryURL = "https://registry.npmjs.org/"
+
+// nodeModulesDir separates package names in lockfile install paths
+const nodeModulesDir = "node_modules/"
 
 // PackageJSON represents a nodejs package.json file
 type PackageJSON struct {
-	Dependencies    map[string]string `json:"dependencies"`
-	DevDependencies map[string]string `json:"devDependencies"`
+	Name                 string            `json:"name"`
+	Version              string            `json:"version"`
+	Dependencies         map[string]string `json:"dependencies"`
+	OptionalDependencies map[string]string `json:"optionalDependencies"`
+	DevDependencies      map[string]string `json:"devDependencies"`
 }
 
-// NPMShrinkwrap represents a npm-shrinkwrap.json file
+// NPMShrinkwrap represents a npm-shrinkwrap.json or package-lock.json file.
+// Lockfile version 1 only has the nested Dependencies tree, version 2 has
+// both and version 3 only has the flat Packages map.
 type NPMShrinkwrap struct {
-	Name         string                              `json:"name"`
-	Version      string                              `json:"version"`
-	Dependencies map[string]*NPMShrinkwrapDependency `json:"dependencies"`
+	Name            string                              `json:"name"`
+	Version         string                              `json:"version"`
+	LockfileVersion int                                 `json:"lockfileVersion"`
+	Dependencies    map[string]*NPMShrinkwrapDependency `json:"dependencies"`
+	Packages        map[string]*NPMLockfilePackage      `json:"packages"`
 }
 
 // NPMShrinkwrapDependency represents a dependencies
@@ -26,14 +59,60 @@ type NPMShrinkwrapDependency struct {
 	Version      string                              `json:"version"`
 	From         string                              `json:"from"`
 	Resolved     string                              `json:"resolved"`
+	Integrity    string                              `json:"integrity"`
+	Dev          bool                                `json:"dev"`
+	Optional     bool                                `json:"optional"`
+	Bundled      bool                                `json:"bundled"`
+	Requires     map[string]string                   `json:"requires"`
 	Dependencies map[string]*NPMShrinkwrapDependency `json:"dependencies"`
 }
 
-// NodeDependency declares a node dependency and a way to download it
+// NPMLockfilePackage represents an entry of the packages block
+// from a version 2 or 3 package-lock.json file, keyed by install path
+type NPMLockfilePackage struct {
+	Name      string `json:"name"`
+	Version   string `json:"version"`
+	Resolved  string `json:"resolved"`
+	Integrity string `json:"integrity"`
+	// Link marks workspaces and file: dependencies on directories,
+	// which are symlinked to the path in Resolved
+	Link        bool `json:"link"`
+	Dev         bool `json:"dev"`
+	Optional    bool `json:"optional"`
+	DevOptional bool `json:"devOptional"`
+	Peer        bool `json:"peer"`
+	InBundle    bool `json:"inBundle"`
+
+	Dependencies         map[string]string `json:"dependencies"`
+	OptionalDependencies map[string]string `json:"optionalDependencies"`
+	PeerDependencies     map[string]string `json:"peerDependencies"`
+	// DevDependencies are only recorded for the root project and
+	// workspace folders
+	DevDependencies map[string]string `json:"devDependencies"`
+}
+
+// NodeDependency declares a node dependency and a way to download it.
+// Integrity is a subresource integrity string and Shasum a legacy hex
+// sha1 sum of the package tarball, when the lockfile records them.
+// Packages on disk, such as workspaces and file: dependencies, have a
+// LocalPath relative to the project directory instead of a PackageURL.
+//
+// Dev, Optional and Peer mark packages only needed by dev, optional or
+// peer dependencies, and DevOptional those needed by both dev and
+// optional dependencies but nothing else. Bundled packages ship inside
+// the tarball of the package depending on them.
 type NodeDependency struct {
-	Name       string
-	Version    string
-	PackageURL string
+	Name        string
+	Version     string
+	PackageURL  string
+	LocalPath   string
+	Integrity   string
+	Shasum      string
+	Dev         bool
+	Optional    bool
+	DevOptional bool
+	Peer        bool
+	Bundled     bool
 }
 
 // GetCanonicalName returns a unique name for the package at this version
@@ -41,35 +120,352 @@ func (d *NodeDependency) GetCanonicalName() string {
 	return fmt.Sprintf("%s@%s", d.Name, d.Version)
 }
 
-func collectDependencies(
-	memo []NodeDependency,
+// IsLocal reports whether the package is on disk rather than downloaded
+func (d *NodeDependency) IsLocal() bool {
+	return d.LocalPath != ""
+}
+
+// Omit selects the types of dependencies to leave out of an install,
+// as npm's --omit option does
+type Omit struct {
+	Dev      bool
+	Optional bool
+	Peer     bool
+}
+
+// ParseOmit parses the comma separated values of --omit options, such as
+// dev,optional
+func ParseOmit(values []string) (Omit, error) {
+	var omit Omit
+	for _, value := range values {
+		for _, depType := range strings.Split(value, ",") {
+			switch strings.TrimSpace(depType) {
+			case "dev":
+				omit.Dev = true
+			case "optional":
+				omit.Optional = true
+			case "peer":
+				omit.Peer = true
+			case "":
+			default:
+				return omit, fmt.Errorf("Unknown dependency type to omit: %s", depType)
+			}
+		}
+	}
+	return omit, nil
+}
+
+// Any reports whether any type of dependency is omitted
+func (o Omit) Any() bool {
+	return o.Dev || o.Optional || o.Peer
+}
+
+// Omits reports whether omit leaves out a package marked with the given
+// flags, which it does when it omits every type of dependency needing it
+func (o Omit) Omits(dev, optional, devOptional, peer bool) bool {
+	return (dev && o.Dev) ||
+		(optional && o.Optional) ||
+		(devOptional && o.Dev && o.Optional) ||
+		(peer && o.Peer)
+}
+
+// IsOmitted reports whether omit leaves the package out
+func (d *NodeDependency) IsOmitted(omit Omit) bool {
+	return omit.Omits(d.Dev, d.Optional, d.DevOptional, d.Peer)
+}
+
+// localProtocols prefix the specifiers and resolutions of packages on disk
+var localProtocols = []string{"file:", "link:", "portal:", "workspace:"}
+
+// LocalSpecPath returns the path named by a file:, link:, portal: or
+// workspace: specifier, reporting false for any other specifier
+func LocalSpecPath(spec string) (string, bool) {
+	for _, protocol := range localProtocols {
+		if strings.HasPrefix(spec, protocol) {
+			return strings.TrimPrefix(spec, protocol), true
+		}
+	}
+	return "", false
+}
+
+// ReadPackageJSON reads the package.json in dirPath
+func ReadPackageJSON(fileSystem fs.FileSystem, dirPath string) (PackageJSON, error) {
+	var packageJSON PackageJSON
+	contents, err := fileSystem.ReadFile(path.Join(dirPath, packageJSONFileName))
+	if err != nil {
+		return packageJSON, err
+	}
+	err = json.Unmarshal(contents, &packageJSON)
+	return packageJSON, err
+}
+
+// addShrinkwrapDependencies adds the nested dependencies of a version 1
+// lockfile installed under parentPath to g, noting what each requires
+func addShrinkwrapDependencies(
+	g *DependencyGraph,
+	requires map[*DependencyNode]map[string]string,
+	parentPath string,
 	deps map[string]*NPMShrinkwrapDependency,
-) []NodeDependency {
-	for k, v := range deps {
-		memo = append(memo, NodeDependency{
-			Name:       k,
+) {
+	var names []string
+	for name := range deps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		v := deps[name]
+		dep := NodeDependency{
+			Name:       name,
 			Version:    v.Version,
 			PackageURL: v.Resolved,
-		})
-		memo = collectDependencies(memo, v.Dependencies)
+			Integrity:  v.Integrity,
+			Dev:        v.Dev,
+			Optional:   v.Optional,
+			Bundled:    v.Bundled,
+		}
+		// Version 1 lockfiles record file: dependencies as the version
+		if localPath, ok := LocalSpecPath(v.Version); ok {
+			dep.Version = ""
+			dep.PackageURL = ""
+			dep.LocalPath = localPath
+		}
+		installPath := path.Join(parentPath, nodeModulesDir, name)
+		requires[g.Add(dep, installPath)] = v.Requires
+		addShrinkwrapDependencies(g, requires, installPath, v.Dependencies)
 	}
-	return memo
 }
 
-// CollectDependencies flattens all given node dependencies into one list
+// RegistryTarballURL reconstructs the URL the registry serves a
+// package version's tarball from, such as
+// https://registry.npmjs.org/@babel/core/-/core-7.0.0.tgz
+func RegistryTarballURL(registryURL, name, version string) string {
+	baseName := name
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		baseName = name[i+1:]
+	}
+	return fmt.Sprintf(
+		"%s/%s/-/%s-%s.tgz",
+		strings.TrimSuffix(registryURL, "/"),
+		name,
+		baseName,
+		version,
+	)
+}
+
+// PackageNameFromPath returns the name of the package installed at
+// a lockfile install path such as node_modules/a/node_modules/@b/c
+func PackageNameFromPath(installPath string) string {
+	i := strings.LastIndex(installPath, nodeModulesDir)
+	if i < 0 {
+		return ""
+	}
+	return installPath[i+len(nodeModulesDir):]
+}
+
+// addLockfilePackages adds the packages of a version 2 or 3 lockfile to
+// g in install path order, linking each to what it requires
+func addLockfilePackages(g *DependencyGraph, packages map[string]*NPMLockfilePackage) {
+	var installPaths []string
+	for installPath := range packages {
+		installPaths = append(installPaths, installPath)
+	}
+	sort.Strings(installPaths)
+
+	for _, installPath := range installPaths {
+		name := PackageNameFromPath(installPath)
+		// The root project and workspace folders aren't dependencies
+		if name == "" {
+			continue
+		}
+		pkg := packages[installPath]
+		dep := NodeDependency{
+			Name:        name,
+			Version:     pkg.Version,
+			PackageURL:  pkg.Resolved,
+			Integrity:   pkg.Integrity,
+			Dev:         pkg.Dev,
+			Optional:    pkg.Optional,
+			DevOptional: pkg.DevOptional,
+			Peer:        pkg.Peer,
+			Bundled:     pkg.InBundle,
+		}
+		if pkg.Link {
+			// Workspaces and linked directories are described by the
+			// entry for the folder they link to
+			dep.PackageURL = ""
+			dep.LocalPath = pkg.Resolved
+			if target := packages[pkg.Resolved]; target != nil {
+				dep.Version = target.Version
+			}
+		} else if localPath, ok := LocalSpecPath(pkg.Resolved); ok {
+			dep.PackageURL = ""
+			dep.LocalPath = localPath
+		}
+		g.Add(dep, installPath)
+	}
+
+	if root := packages[""]; root != nil {
+		g.linkInstalled(
+			g.Root,
+			"",
+			root.Dependencies,
+			root.OptionalDependencies,
+			root.PeerDependencies,
+			root.DevDependencies,
+		)
+	}
+	for _, node := range g.Nodes {
+		pkg, fromPath := packages[node.Path], node.Path
+		// Linked packages require what their folder's package.json does,
+		// resolved from the folder
+		if pkg.Link && packages[pkg.Resolved] != nil {
+			pkg, fromPath = packages[pkg.Resolved], pkg.Resolved
+			g.linkInstalled(node, fromPath, pkg.DevDependencies)
+		}
+		g.linkInstalled(
+			node,
+			fromPath,
+			pkg.Dependencies,
+			pkg.OptionalDependencies,
+			pkg.PeerDependencies,
+		)
+	}
+}
+
+// NPMDependencyGraph builds the dependency graph of a npm-shrinkwrap.json
+// or package-lock.json file, with nodes ordered by install path
+func NPMDependencyGraph(npmShrinkwrap NPMShrinkwrap) *DependencyGraph {
+	g := NewDependencyGraph(npmShrinkwrap.Name, npmShrinkwrap.Version)
+	if npmShrinkwrap.LockfileVersion >= 2 && npmShrinkwrap.Packages != nil {
+		addLockfilePackages(g, npmShrinkwrap.Packages)
+	} else {
+		requires := make(map[*DependencyNode]map[string]string)
+		addShrinkwrapDependencies(g, requires, "", npmShrinkwrap.Dependencies)
+		for _, node := range g.Nodes {
+			g.linkInstalled(node, node.Path, requires[node])
+		}
+		// Version 1 lockfiles don't record the project's own dependencies
+		g.resolveProject = func(name, spec string) *DependencyNode {
+			return g.resolveInstallPath("", name)
+		}
+	}
+	g.finish()
+	return g
+}
+
+// CollectDependencies flattens all given node dependencies into one list,
+// ordered by install path
 func CollectDependencies(npmShrinkwrap NPMShrinkwrap) []NodeDependency {
-	var deps []NodeDependency
-	deps = collectDependencies(deps, npmShrinkwrap.Dependencies)
+	return NPMDependencyGraph(npmShrinkwrap).Dependencies()
+}
 
+// mergeDependencyFlags combines the flags of two occurrences of the same
+// package, which is only left out of an install when both would be
+func mergeDependencyFlags(a, b NodeDependency) NodeDependency {
+	aNonProd := a.Dev || a.Optional || a.DevOptional
+	bNonProd := b.Dev || b.Optional || b.DevOptional
+	a.Dev = a.Dev && b.Dev
+	a.Optional = a.Optional && b.Optional
+	a.DevOptional = !a.Dev && !a.Optional && aNonProd && bNonProd
+	a.Peer = a.Peer && b.Peer
+	a.Bundled = a.Bundled && b.Bundled
+	return a
+}
+
+func dedupeDependencies(deps []NodeDependency) []NodeDependency {
 	var dedupedDeps []NodeDependency
-	var depSet = make(map[string]bool)
+	var depIndex = make(map[string]int)
 	for _, dep := range deps {
 		depStr := dep.GetCanonicalName()
-		if dupeDep := depSet[depStr]; !dupeDep {
+		if i, dupeDep := depIndex[depStr]; dupeDep {
+			dedupedDeps[i] = mergeDependencyFlags(dedupedDeps[i], dep)
+		} else {
+			depIndex[depStr] = len(dedupedDeps)
 			dedupedDeps = append(dedupedDeps, dep)
-			depSet[depStr] = true
 		}
 	}
 
 	return dedupedDeps
 }
+
+// ParseNPMLockfile decodes a npm-shrinkwrap.json or package-lock.json file
+func ParseNPMLockfile(contents []byte) (NPMShrinkwrap, error) {
+	var npmShrinkwrap NPMShrinkwrap
+	if err := json.Unmarshal(contents, &npmShrinkwrap); err != nil {
+		return npmShrinkwrap, err
+	}
+	if npmShrinkwrap.LockfileVersion > 3 {
+		return npmShrinkwrap, fmt.Errorf(
+			"Unsupported lockfileVersion: %d",
+			npmShrinkwrap.LockfileVersion,
+		)
+	}
+	return npmShrinkwrap, nil
+}
+
+// ParseDependencyGraph builds the dependency graph of the contents of
+// the lockfile with the given file name
+func ParseDependencyGraph(fileName string, contents []byte) (*DependencyGraph, error) {
+	switch fileName {
+	case NPMShrinkwrapFileName, PackageLockFileName:
+		npmShrinkwrap, err := ParseNPMLockfile(contents)
+		if err != nil {
+			return nil, err
+		}
+		return NPMDependencyGraph(npmShrinkwrap), nil
+	case YarnLockfileName:
+		if IsYarnBerryLockfile(contents) {
+			lockfile, err := ParseYarnBerryLockfile(contents)
+			if err != nil {
+				return nil, err
+			}
+			return YarnBerryDependencyGraph(lockfile), nil
+		}
+		lockfile, err := ParseYarnLockfile(contents)
+		if err != nil {
+			return nil, err
+		}
+		return YarnDependencyGraph(lockfile), nil
+	case PNPMLockfileName:
+		lockfile, err := ParsePNPMLockfile(contents)
+		if err != nil {
+			return nil, err
+		}
+		return PNPMDependencyGraph(lockfile), nil
+	}
+	return nil, fmt.Errorf("Unknown dependencies file: %s", fileName)
+}
+
+// ParseDependencies collects the dependencies from the contents
+// of the lockfile with the given file name. Whatever the lockfile, they
+// are ordered as the nodes of its DependencyGraph are, by path and then
+// by name, so the same lockfile always yields the same list.
+func ParseDependencies(fileName string, contents []byte) ([]NodeDependency, error) {
+	g, err := ParseDependencyGraph(fileName, contents)
+	if err != nil {
+		return nil, err
+	}
+	return g.Dependencies(), nil
+}
+
+// FindDependenciesFile returns the path and contents of the first
+// dependencies lock file found in dirPath
+func FindDependenciesFile(fileSystem fs.FileSystem, dirPath string) (string, []byte, error) {
+	for _, fileName := range DependenciesFileNames {
+		filePath := path.Join(dirPath, fileName)
+		contents, err := fileSystem.ReadFile(filePath)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return filePath, nil, err
+		}
+		return filePath, contents, nil
+	}
+	return "", nil, fmt.Errorf(
+		"No dependencies file found in %s (looked for %s)",
+		dirPath,
+		strings.Join(DependenciesFileNames, ", "),
+	)
+}
@@ -1,6 +1,11 @@
 package nodejs
 
 import (
+	"bitbucket.org/bosgood/dep-get/lib/fs"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
 	"testing"
 )
 
@@ -129,3 +134,543 @@ func TestCollectDependenciesNoDuplicates(t *testing.T) {
 		t.Errorf("Expected on-finished dep to exist")
 	}
 }
+
+var packageLockV1 = []byte(`{
+  "name": "my-app",
+  "version": "0.0.1",
+  "lockfileVersion": 1,
+  "dependencies": {
+    "on-finished": {
+      "version": "2.3.0",
+      "resolved": "https://registry.npmjs.org/on-finished/-/on-finished-2.3.0.tgz",
+      "requires": {
+        "ee-first": "1.1.1"
+      },
+      "dependencies": {
+        "ee-first": {
+          "version": "1.1.1",
+          "resolved": "https://registry.npmjs.org/ee-first/-/ee-first-1.1.1.tgz"
+        }
+      }
+    }
+  }
+}`)
+
+var packageLockV2 = []byte(`{
+  "name": "my-app",
+  "version": "0.0.2",
+  "lockfileVersion": 2,
+  "packages": {
+    "": {
+      "name": "my-app",
+      "version": "0.0.2"
+    },
+    "node_modules/@types/node": {
+      "version": "16.0.0",
+      "resolved": "https://registry.npmjs.org/@types/node/-/node-16.0.0.tgz",
+      "integrity": "sha512-TMM3Y/bbJ/dz5kxOrN0y6eoeFcXrkuTP1G8Dd+o4UDi1WzWJtYiwdMJOzLsErw+gUg0oV5/ZuItb4A7GFe9obg=="
+    },
+    "node_modules/on-finished": {
+      "version": "2.3.0",
+      "resolved": "https://registry.npmjs.org/on-finished/-/on-finished-2.3.0.tgz"
+    },
+    "node_modules/on-finished/node_modules/ee-first": {
+      "version": "1.1.1",
+      "resolved": "https://registry.npmjs.org/ee-first/-/ee-first-1.1.1.tgz"
+    }
+  },
+  "dependencies": {
+    "on-finished": {
+      "version": "2.3.0",
+      "resolved": "https://registry.npmjs.org/on-finished/-/on-finished-2.3.0.tgz"
+    }
+  }
+}`)
+
+var packageLockV3 = []byte(`{
+  "name": "my-app",
+  "version": "0.0.3",
+  "lockfileVersion": 3,
+  "packages": {
+    "": {
+      "name": "my-app",
+      "version": "0.0.3"
+    },
+    "node_modules/ee-first": {
+      "version": "1.1.1",
+      "resolved": "https://registry.npmjs.org/ee-first/-/ee-first-1.1.1.tgz"
+    },
+    "node_modules/on-finished": {
+      "version": "2.3.0",
+      "resolved": "https://registry.npmjs.org/on-finished/-/on-finished-2.3.0.tgz"
+    },
+    "node_modules/on-finished/node_modules/ee-first": {
+      "version": "1.1.1",
+      "resolved": "https://registry.npmjs.org/ee-first/-/ee-first-1.1.1.tgz"
+    }
+  }
+}`)
+
+func TestPackageNameFromPath(t *testing.T) {
+	cases := map[string]string{
+		"":                                   "",
+		"packages/app":                       "",
+		"node_modules/bluebird":              "bluebird",
+		"node_modules/@types/node":           "@types/node",
+		"node_modules/a/node_modules/b":      "b",
+		"node_modules/a/node_modules/@b/c":   "@b/c",
+		"packages/app/node_modules/ee-first": "ee-first",
+	}
+	for installPath, expected := range cases {
+		if name := PackageNameFromPath(installPath); name != expected {
+			t.Errorf("Expected %q for %q, got %q", expected, installPath, name)
+		}
+	}
+}
+
+func TestParseDependenciesPackageLockV1(t *testing.T) {
+	deps, err := ParseDependencies(PackageLockFileName, packageLockV1)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(deps) != 2 {
+		t.Fatalf("Expected to find two dependencies, found %d", len(deps))
+	}
+	if deps[0].GetCanonicalName() != "on-finished@2.3.0" {
+		t.Errorf("Expected on-finished dep to exist")
+	}
+	if deps[1].GetCanonicalName() != "ee-first@1.1.1" {
+		t.Errorf("Expected ee-first dep to exist")
+	}
+}
+
+func TestParseDependenciesPackageLockV2(t *testing.T) {
+	deps, err := ParseDependencies(PackageLockFileName, packageLockV2)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(deps) != 3 {
+		t.Fatalf("Expected to find three dependencies, found %d", len(deps))
+	}
+
+	expected := []NodeDependency{
+		{
+			Name:       "@types/node",
+			Version:    "16.0.0",
+			PackageURL: "https://registry.npmjs.org/@types/node/-/node-16.0.0.tgz",
+			Integrity:  "sha512-TMM3Y/bbJ/dz5kxOrN0y6eoeFcXrkuTP1G8Dd+o4UDi1WzWJtYiwdMJOzLsErw+gUg0oV5/ZuItb4A7GFe9obg==",
+		},
+		{
+			Name:       "on-finished",
+			Version:    "2.3.0",
+			PackageURL: "https://registry.npmjs.org/on-finished/-/on-finished-2.3.0.tgz",
+		},
+		{
+			Name:       "ee-first",
+			Version:    "1.1.1",
+			PackageURL: "https://registry.npmjs.org/ee-first/-/ee-first-1.1.1.tgz",
+		},
+	}
+	for i, dep := range expected {
+		if deps[i] != dep {
+			t.Errorf("Expected %+v at %d, got %+v", dep, i, deps[i])
+		}
+	}
+}
+
+func TestParseDependenciesPackageLockV3(t *testing.T) {
+	deps, err := ParseDependencies(NPMShrinkwrapFileName, packageLockV3)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	// The nested ee-first is the same package as the hoisted one
+	if len(deps) != 2 {
+		t.Fatalf("Expected to find two dependencies, found %d", len(deps))
+	}
+	if deps[0].GetCanonicalName() != "ee-first@1.1.1" {
+		t.Errorf("Expected ee-first dep to exist")
+	}
+	if deps[1].GetCanonicalName() != "on-finished@2.3.0" {
+		t.Errorf("Expected on-finished dep to exist")
+	}
+}
+
+func TestParseDependenciesUnsupportedVersion(t *testing.T) {
+	_, err := ParseDependencies(PackageLockFileName, []byte(`{"lockfileVersion": 4}`))
+	if err == nil {
+		t.Errorf("Expected an error for lockfileVersion 4")
+	}
+}
+
+func TestParseDependenciesUnknownFile(t *testing.T) {
+	_, err := ParseDependencies("Gemfile.lock", []byte{})
+	if err == nil {
+		t.Errorf("Expected an error for an unknown dependencies file")
+	}
+}
+
+func TestFindDependenciesFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dep-get-nodejs")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	osFS := &fs.OSFS{}
+	if _, _, err = FindDependenciesFile(osFS, dir); err == nil {
+		t.Errorf("Expected an error for a directory without a lockfile")
+	}
+
+	err = ioutil.WriteFile(path.Join(dir, PackageLockFileName), packageLockV3, 0644)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	filePath, _, err := FindDependenciesFile(osFS, dir)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if path.Base(filePath) != PackageLockFileName {
+		t.Errorf("Expected to find %s, found %s", PackageLockFileName, filePath)
+	}
+
+	// npm-shrinkwrap.json takes precedence over package-lock.json
+	err = ioutil.WriteFile(path.Join(dir, NPMShrinkwrapFileName), packageLockV1, 0644)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	filePath, _, err = FindDependenciesFile(osFS, dir)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if path.Base(filePath) != NPMShrinkwrapFileName {
+		t.Errorf("Expected to find %s, found %s", NPMShrinkwrapFileName, filePath)
+	}
+}
+
+var packageLockWorkspaces = []byte(`{
+  "name": "monorepo",
+  "lockfileVersion": 3,
+  "packages": {
+    "": {
+      "name": "monorepo",
+      "workspaces": ["packages/*"]
+    },
+    "../shared": {
+      "name": "shared",
+      "version": "2.0.0"
+    },
+    "node_modules/app": {
+      "resolved": "packages/app",
+      "link": true
+    },
+    "node_modules/ee-first": {
+      "version": "1.1.1",
+      "resolved": "https://registry.npmjs.org/ee-first/-/ee-first-1.1.1.tgz"
+    },
+    "node_modules/shared": {
+      "resolved": "../shared",
+      "link": true
+    },
+    "node_modules/vendored": {
+      "version": "0.1.0",
+      "resolved": "file:vendor/vendored-0.1.0.tgz"
+    },
+    "packages/app": {
+      "name": "app",
+      "version": "1.0.0"
+    },
+    "packages/app/node_modules/on-finished": {
+      "version": "2.3.0",
+      "resolved": "https://registry.npmjs.org/on-finished/-/on-finished-2.3.0.tgz"
+    }
+  }
+}`)
+
+func TestParseDependenciesPackageLockWorkspaces(t *testing.T) {
+	deps, err := ParseDependencies(PackageLockFileName, packageLockWorkspaces)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := []NodeDependency{
+		{Name: "app", Version: "1.0.0", LocalPath: "packages/app"},
+		{
+			Name:       "ee-first",
+			Version:    "1.1.1",
+			PackageURL: "https://registry.npmjs.org/ee-first/-/ee-first-1.1.1.tgz",
+		},
+		{Name: "shared", Version: "2.0.0", LocalPath: "../shared"},
+		{Name: "vendored", Version: "0.1.0", LocalPath: "vendor/vendored-0.1.0.tgz"},
+		{
+			Name:       "on-finished",
+			Version:    "2.3.0",
+			PackageURL: "https://registry.npmjs.org/on-finished/-/on-finished-2.3.0.tgz",
+		},
+	}
+	if len(deps) != len(expected) {
+		t.Fatalf("Expected %d dependencies, found %d: %+v", len(expected), len(deps), deps)
+	}
+	for i, dep := range expected {
+		if deps[i] != dep {
+			t.Errorf("Expected %+v at %d, got %+v", dep, i, deps[i])
+		}
+	}
+}
+
+func TestParseDependenciesPackageLockV1FileDependency(t *testing.T) {
+	deps, err := ParseDependencies(PackageLockFileName, []byte(`{
+  "lockfileVersion": 1,
+  "dependencies": {
+    "shared": {
+      "version": "file:../shared"
+    }
+  }
+}`))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	expected := NodeDependency{Name: "shared", LocalPath: "../shared"}
+	if len(deps) != 1 || deps[0] != expected {
+		t.Errorf("Expected [%+v], got %+v", expected, deps)
+	}
+}
+
+func TestLocalSpecPath(t *testing.T) {
+	cases := []struct {
+		spec  string
+		path  string
+		local bool
+	}{
+		{"file:../shared", "../shared", true},
+		{"link:packages/a", "packages/a", true},
+		{"workspace:packages/a", "packages/a", true},
+		{"portal:../b", "../b", true},
+		{"https://registry.npmjs.org/a/-/a-1.0.0.tgz", "", false},
+		{"1.0.0", "", false},
+	}
+	for _, c := range cases {
+		localPath, local := LocalSpecPath(c.spec)
+		if localPath != c.path || local != c.local {
+			t.Errorf("Expected %q, %t for %s, got %q, %t", c.path, c.local, c.spec, localPath, local)
+		}
+	}
+}
+
+var packageLockDependencyTypes = []byte(`{
+  "lockfileVersion": 3,
+  "packages": {
+    "": {"name": "my-app"},
+    "node_modules/bundled-dep": {"version": "1.0.0", "inBundle": true},
+    "node_modules/dev-dep": {"version": "1.0.0", "dev": true},
+    "node_modules/dev-optional-dep": {"version": "1.0.0", "devOptional": true},
+    "node_modules/optional-dep": {"version": "1.0.0", "optional": true},
+    "node_modules/peer-dep": {"version": "1.0.0", "peer": true},
+    "node_modules/prod-dep": {"version": "1.0.0"}
+  }
+}`)
+
+func TestParseDependenciesDependencyTypes(t *testing.T) {
+	deps, err := ParseDependencies(PackageLockFileName, packageLockDependencyTypes)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := []NodeDependency{
+		{Name: "bundled-dep", Version: "1.0.0", Bundled: true},
+		{Name: "dev-dep", Version: "1.0.0", Dev: true},
+		{Name: "dev-optional-dep", Version: "1.0.0", DevOptional: true},
+		{Name: "optional-dep", Version: "1.0.0", Optional: true},
+		{Name: "peer-dep", Version: "1.0.0", Peer: true},
+		{Name: "prod-dep", Version: "1.0.0"},
+	}
+	if len(deps) != len(expected) {
+		t.Fatalf("Expected %d dependencies, found %d: %+v", len(expected), len(deps), deps)
+	}
+	for i, dep := range expected {
+		if deps[i] != dep {
+			t.Errorf("Expected %+v at %d, got %+v", dep, i, deps[i])
+		}
+	}
+
+	omitted := func(omit Omit) []string {
+		var names []string
+		for _, dep := range deps {
+			if dep.IsOmitted(omit) {
+				names = append(names, dep.Name)
+			}
+		}
+		return names
+	}
+	cases := []struct {
+		omit     Omit
+		expected string
+	}{
+		{Omit{}, ""},
+		{Omit{Dev: true}, "dev-dep"},
+		{Omit{Optional: true}, "optional-dep"},
+		{Omit{Dev: true, Optional: true}, "dev-dep,dev-optional-dep,optional-dep"},
+		{Omit{Peer: true}, "peer-dep"},
+	}
+	for _, c := range cases {
+		if names := strings.Join(omitted(c.omit), ","); names != c.expected {
+			t.Errorf("Expected %+v to omit %q, got %q", c.omit, c.expected, names)
+		}
+	}
+}
+
+func TestParseDependenciesDependencyTypesV1(t *testing.T) {
+	deps, err := ParseDependencies(PackageLockFileName, []byte(`{
+  "lockfileVersion": 1,
+  "dependencies": {
+    "dev-dep": {
+      "version": "1.0.0",
+      "dev": true,
+      "dependencies": {
+        "bundled-dep": {"version": "1.0.0", "bundled": true}
+      }
+    }
+  }
+}`))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(deps) != 2 {
+		t.Fatalf("Expected to find two dependencies, found %d", len(deps))
+	}
+	if !deps[0].Dev || !deps[1].Bundled {
+		t.Errorf("Expected dev and bundled flags, got %+v", deps)
+	}
+}
+
+func TestDedupeDependenciesMergesFlags(t *testing.T) {
+	deps := dedupeDependencies([]NodeDependency{
+		{Name: "a", Version: "1.0.0", Dev: true},
+		{Name: "a", Version: "1.0.0", Optional: true},
+		{Name: "b", Version: "1.0.0", Dev: true},
+		{Name: "b", Version: "1.0.0", Dev: true},
+		{Name: "c", Version: "1.0.0", Dev: true, Bundled: true},
+		{Name: "c", Version: "1.0.0"},
+	})
+
+	expected := []NodeDependency{
+		{Name: "a", Version: "1.0.0", DevOptional: true},
+		{Name: "b", Version: "1.0.0", Dev: true},
+		{Name: "c", Version: "1.0.0"},
+	}
+	if len(deps) != len(expected) {
+		t.Fatalf("Expected %d dependencies, found %d: %+v", len(expected), len(deps), deps)
+	}
+	for i, dep := range expected {
+		if deps[i] != dep {
+			t.Errorf("Expected %+v at %d, got %+v", dep, i, deps[i])
+		}
+	}
+}
+
+func TestParseOmit(t *testing.T) {
+	omit, err := ParseOmit([]string{"dev,optional", "peer"})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if omit != (Omit{Dev: true, Optional: true, Peer: true}) {
+		t.Errorf("Expected every type omitted, got %+v", omit)
+	}
+
+	if _, err := ParseOmit([]string{"dev,test"}); err == nil {
+		t.Errorf("Expected an error for an unknown type")
+	}
+}
+
+var packageLockV1Unordered = []byte(`{
+  "lockfileVersion": 1,
+  "dependencies": {
+    "zeta": {"version": "1.0.0", "requires": {"ee-first": "1.1.1"}},
+    "on-finished": {
+      "version": "2.3.0",
+      "dependencies": {
+        "ee-first": {"version": "1.0.0"},
+        "depd": {"version": "2.0.0"}
+      }
+    },
+    "on-finished-x": {"version": "1.0.0"},
+    "@types/node": {"version": "16.0.0"},
+    "ee-first": {"version": "1.1.1"},
+    "bluebird": {
+      "version": "3.3.4",
+      "dependencies": {
+        "ee-first": {"version": "1.1.1"}
+      }
+    }
+  }
+}`)
+
+var yarnLockUnordered = []byte(`# yarn lockfile v1
+
+
+zeta@^1.0.0:
+  version "1.0.0"
+
+"@types/node@^16.0.0":
+  version "16.0.0"
+
+ee-first@1.1.1, ee-first@^1.1.0:
+  version "1.1.1"
+
+ee-first@1.0.0:
+  version "1.0.0"
+`)
+
+func TestParseDependenciesOrder(t *testing.T) {
+	tests := []struct {
+		fileName string
+		contents []byte
+		expected []string
+	}{
+		{
+			PackageLockFileName,
+			packageLockV1Unordered,
+			[]string{
+				"@types/node@16.0.0",
+				"bluebird@3.3.4",
+				"ee-first@1.1.1",
+				"on-finished@2.3.0",
+				"depd@2.0.0",
+				"ee-first@1.0.0",
+				"on-finished-x@1.0.0",
+				"zeta@1.0.0",
+			},
+		},
+		{
+			PackageLockFileName,
+			packageLockV3,
+			[]string{"ee-first@1.1.1", "on-finished@2.3.0"},
+		},
+		{
+			YarnLockfileName,
+			yarnLockUnordered,
+			[]string{"@types/node@16.0.0", "ee-first@1.0.0", "ee-first@1.1.1", "zeta@1.0.0"},
+		},
+		{
+			PNPMLockfileName,
+			pnpmLockV9,
+			[]string{"@babel/code-frame@7.10.4", "ee-first@1.1.1", "my-fork@1.0.0", "on-finished@2.3.0", "shared@"},
+		},
+	}
+
+	for _, test := range tests {
+		// Go randomizes map iteration, so parse repeatedly
+		for i := 0; i < 20; i++ {
+			deps, err := ParseDependencies(test.fileName, test.contents)
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+			var names []string
+			for _, dep := range deps {
+				names = append(names, dep.GetCanonicalName())
+			}
+			if strings.Join(names, " ") != strings.Join(test.expected, " ") {
+				t.Fatalf("%s: expected %q, got %q", test.fileName, test.expected, names)
+			}
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package nodejs
+
+import (
+	"regexp"
+	"strings"
+)
+
+// NPMIgnoreFileName lists files npm leaves out of a package tarball
+const NPMIgnoreFileName = ".npmignore"
+
+// gitIgnoreFileName stands in for .npmignore in directories without one
+const gitIgnoreFileName = ".gitignore"
+
+// ignoreRule is one line of a .gitignore style file
+type ignoreRule struct {
+	// base is the directory the rule was read from, relative to the
+	// package root, and is "" for the root itself
+	base    string
+	pattern *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// ignoreRules are matched in order, the last matching rule winning
+type ignoreRules []ignoreRule
+
+// globRegexp translates a .gitignore glob into a regexp matching paths
+// relative to the directory the pattern was read from. Patterns without
+// a slash, other than a trailing one, match at any depth.
+func globRegexp(glob string) (*regexp.Regexp, error) {
+	anchored := strings.Contains(glob, "/")
+	glob = strings.TrimPrefix(glob, "/")
+
+	var expr strings.Builder
+	expr.WriteString("^")
+	if !anchored {
+		expr.WriteString("(?:.*/)?")
+	}
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*':
+			if strings.HasPrefix(glob[i:], "**/") {
+				expr.WriteString("(?:.*/)?")
+				i += 2
+			} else if strings.HasPrefix(glob[i:], "**") {
+				expr.WriteString(".*")
+				i++
+			} else {
+				expr.WriteString("[^/]*")
+			}
+		case '?':
+			expr.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(glob[i:], ']')
+			if end < 0 {
+				expr.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			expr.WriteString("[" + class + "]")
+			i += end
+		case '\\':
+			if i+1 < len(glob) {
+				i++
+				expr.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+			}
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	expr.WriteString("$")
+	return regexp.Compile(expr.String())
+}
+
+// parseIgnoreRule parses one line of a .gitignore style file read from
+// base, reporting false for blank lines, comments and invalid globs
+func parseIgnoreRule(base, line string) (ignoreRule, bool) {
+	line = strings.TrimRight(line, "\r")
+	if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+		return ignoreRule{}, false
+	}
+	if !strings.HasSuffix(line, `\ `) {
+		line = strings.TrimRight(line, " ")
+	}
+
+	rule := ignoreRule{base: base}
+	if strings.HasPrefix(line, "!") {
+		rule.negate = true
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return ignoreRule{}, false
+	}
+
+	pattern, err := globRegexp(line)
+	if err != nil {
+		return ignoreRule{}, false
+	}
+	rule.pattern = pattern
+	return rule, true
+}
+
+// parseIgnoreFile parses the contents of a .gitignore style file found
+// in the directory base
+func parseIgnoreFile(base string, contents []byte) ignoreRules {
+	var rules ignoreRules
+	for _, line := range strings.Split(string(contents), "\n") {
+		if rule, ok := parseIgnoreRule(base, line); ok {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// matches reports whether the rule applies to relPath, a path relative
+// to the package root
+func (r ignoreRule) matches(relPath string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+	if r.base != "" {
+		if !strings.HasPrefix(relPath, r.base+"/") {
+			return false
+		}
+		relPath = strings.TrimPrefix(relPath, r.base+"/")
+	}
+	return r.pattern.MatchString(relPath)
+}
+
+// match reports whether any rule applies to relPath and, if so, whether
+// the last one to apply excludes it
+func (rules ignoreRules) match(relPath string, isDir bool) (matched bool, ignored bool) {
+	for _, rule := range rules {
+		if rule.matches(relPath, isDir) {
+			matched = true
+			ignored = !rule.negate
+		}
+	}
+	return matched, ignored
+}
+
+// ignored reports whether relPath is excluded by the rules
+func (rules ignoreRules) ignored(relPath string, isDir bool) bool {
+	_, ignored := rules.match(relPath, isDir)
+	return ignored
+}
+
+// included reports whether relPath is listed by rules read from a
+// package.json files field, either itself or through the directories
+// it is in. Rules for deeper paths override those for their parents.
+func (rules ignoreRules) included(relPath string) bool {
+	included := false
+	parts := strings.Split(relPath, "/")
+	for i := range parts {
+		matched, listed := rules.match(strings.Join(parts[:i+1], "/"), i < len(parts)-1)
+		if matched {
+			included = listed
+		}
+	}
+	return included
+}
+
+// alwaysIgnoredFiles are never packed, whatever .npmignore or files say
+var alwaysIgnoredFiles = parseIgnoreFile("", []byte(`
+.git
+CVS
+.svn
+.hg
+.lock-wscript
+.wafpickle-*
+.*.swp
+.DS_Store
+._*
+npm-debug.log
+.npmrc
+config.gypi
+*.orig
+.npmignore
+.gitignore
+/node_modules/
+/package-lock.json
+/yarn.lock
+/pnpm-lock.yaml
+`))
+
+// alwaysIncludedFiles are always packed from the package root
+var alwaysIncludedFiles = regexp.MustCompile(`(?i)^(package\.json|readme(\..*)?|copying(\..*)?|licen[cs]e(\..*)?)$`)
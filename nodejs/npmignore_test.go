@@ -0,0 +1,68 @@
+package nodejs
+
+import (
+	"testing"
+)
+
+func TestIgnoreRules(t *testing.T) {
+	rules := parseIgnoreFile("", []byte(`
+# comment
+*.log
+/build
+docs/
+test/**/*.snap
+!keep.log
+`))
+	rules = append(rules, parseIgnoreFile("lib", []byte("fixtures\n"))...)
+
+	tests := []struct {
+		path    string
+		isDir   bool
+		ignored bool
+	}{
+		{"error.log", false, true},
+		{"src/debug.log", false, true},
+		{"keep.log", false, false},
+		{"build", true, true},
+		{"src/build", true, false},
+		{"docs", true, true},
+		{"docs", false, false},
+		{"test/a/b/c.snap", false, true},
+		{"test/c.snap", false, true},
+		{"src/c.snap", false, false},
+		{"lib/fixtures", true, true},
+		{"fixtures", true, false},
+		{"index.js", false, false},
+	}
+	for _, test := range tests {
+		if ignored := rules.ignored(test.path, test.isDir); ignored != test.ignored {
+			t.Errorf("%s: expected ignored %t, got %t", test.path, test.ignored, ignored)
+		}
+	}
+}
+
+func TestIgnoreRulesIncluded(t *testing.T) {
+	var files ignoreRules
+	for _, entry := range []string{"/lib", "/bin/*.js", "!/lib/test"} {
+		rule, _ := parseIgnoreRule("", entry)
+		files = append(files, rule)
+	}
+
+	tests := []struct {
+		path     string
+		included bool
+	}{
+		{"lib/index.js", true},
+		{"lib/util/strings.js", true},
+		{"lib/test/index.js", false},
+		{"bin/cli.js", true},
+		{"bin/cli.sh", false},
+		{"src/lib/index.js", false},
+		{"index.js", false},
+	}
+	for _, test := range tests {
+		if included := files.included(test.path); included != test.included {
+			t.Errorf("%s: expected included %t, got %t", test.path, test.included, included)
+		}
+	}
+}
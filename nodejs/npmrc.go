@@ -0,0 +1,228 @@
+package nodejs
+
+import (
+	"bitbucket.org/bosgood/dep-get/lib/fs"
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// NPMRCFileName is the name of npm's configuration file
+const NPMRCFileName = ".npmrc"
+
+// npmrcEnvPattern matches the ${VAR} references npm expands in .npmrc values
+var npmrcEnvPattern = regexp.MustCompile(`\$\{([^}]+)\}`)
+
+// NPMRC holds npm configuration read from .npmrc files. Registry
+// credentials are keyed by the registry URL without its scheme, such as
+// //npm.example.com/:_authToken.
+type NPMRC struct {
+	values map[string]string
+}
+
+// NewNPMRC creates an empty npm configuration
+func NewNPMRC() *NPMRC {
+	return &NPMRC{values: make(map[string]string)}
+}
+
+// ParseNPMRC decodes the contents of a .npmrc file, expanding
+// ${VAR} references from the environment
+func ParseNPMRC(contents []byte) *NPMRC {
+	npmrc := NewNPMRC()
+	scanner := bufio.NewScanner(bytes.NewReader(contents))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		i := strings.Index(line, "=")
+		if i < 0 {
+			continue
+		}
+		key := strings.TrimSpace(line[:i])
+		value := strings.TrimSpace(line[i+1:])
+		if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+			value = value[1 : len(value)-1]
+		}
+		value = npmrcEnvPattern.ReplaceAllStringFunc(value, func(ref string) string {
+			return os.Getenv(ref[2 : len(ref)-1])
+		})
+		npmrc.values[key] = value
+	}
+	return npmrc
+}
+
+// Get returns a configuration value
+func (n *NPMRC) Get(key string) string {
+	return n.values[key]
+}
+
+// Merge copies the values of other over those already set
+func (n *NPMRC) Merge(other *NPMRC) {
+	for key, value := range other.values {
+		n.values[key] = value
+	}
+}
+
+// UserNPMRCPath returns the path of the user's .npmrc, which
+// NPM_CONFIG_USERCONFIG overrides
+func UserNPMRCPath() string {
+	if userConfig := os.Getenv("NPM_CONFIG_USERCONFIG"); userConfig != "" {
+		return userConfig
+	}
+	if home := os.Getenv("HOME"); home != "" {
+		return path.Join(home, NPMRCFileName)
+	}
+	return ""
+}
+
+// ReadNPMRC reads the user's .npmrc and then the project's from
+// projectDir, whose values take precedence. Missing files are skipped.
+func ReadNPMRC(fileSystem fs.FileSystem, projectDir string) (*NPMRC, error) {
+	npmrc := NewNPMRC()
+	for _, filePath := range []string{UserNPMRCPath(), path.Join(projectDir, NPMRCFileName)} {
+		if filePath == "" {
+			continue
+		}
+		contents, err := fileSystem.ReadFile(filePath)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		npmrc.Merge(ParseNPMRC(contents))
+	}
+	return npmrc, nil
+}
+
+// nerfDart strips the scheme, query and last path segment from a URL
+// as npm does to key credentials, leaving //host/path/
+func nerfDart(rawURL string) string {
+	urlObj, err := url.Parse(rawURL)
+	if err != nil || urlObj.Host == "" {
+		return ""
+	}
+	dir := urlObj.Path
+	if dir == "" {
+		dir = "/"
+	}
+	if !strings.HasSuffix(dir, "/") {
+		dir = path.Dir(dir)
+		if !strings.HasSuffix(dir, "/") {
+			dir += "/"
+		}
+	}
+	return "//" + urlObj.Host + dir
+}
+
+// authHeader builds the Authorization header from the credentials
+// configured under prefix, which is a nerf dart or empty for the
+// legacy top level settings
+func (n *NPMRC) authHeader(prefix string) string {
+	if token := n.values[prefix+"_authToken"]; token != "" {
+		return "Bearer " + token
+	}
+	if auth := n.values[prefix+"_auth"]; auth != "" {
+		return "Basic " + auth
+	}
+	username := n.values[prefix+"username"]
+	password := n.values[prefix+"_password"]
+	if username != "" && password != "" {
+		decoded, err := base64.StdEncoding.DecodeString(password)
+		if err != nil {
+			return ""
+		}
+		credentials := username + ":" + string(decoded)
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(credentials))
+	}
+	return ""
+}
+
+func (n *NPMRC) alwaysAuth(prefix string) bool {
+	if value, ok := n.values[prefix+"always-auth"]; ok {
+		return value == "true"
+	}
+	return n.values["always-auth"] == "true"
+}
+
+// registryPrefixes lists the nerf darts which have settings, longest first
+func (n *NPMRC) registryPrefixes() []string {
+	var prefixes []string
+	seen := make(map[string]bool)
+	for key := range n.values {
+		if !strings.HasPrefix(key, "//") {
+			continue
+		}
+		i := strings.LastIndex(key, ":")
+		if i < 0 || seen[key[:i]] {
+			continue
+		}
+		seen[key[:i]] = true
+		prefixes = append(prefixes, key[:i])
+	}
+	// Longest prefixes are the most specific
+	sort.Slice(prefixes, func(i, j int) bool {
+		if len(prefixes[i]) != len(prefixes[j]) {
+			return len(prefixes[i]) > len(prefixes[j])
+		}
+		return prefixes[i] < prefixes[j]
+	})
+	return prefixes
+}
+
+// underNerfDart reports whether dart falls under prefix, ending prefix at
+// a path segment so that //npm.ourco.com doesn't match
+// //npm.ourco.com.evil.net
+func underNerfDart(dart, prefix string) bool {
+	if !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	return strings.HasPrefix(dart, prefix)
+}
+
+// AuthHeader returns the Authorization header to send when downloading
+// from tarballURL, or an empty string if no credentials apply. Credentials
+// are used when the URL falls under the registry they're configured for
+// or, with always-auth set, when it is on the same host.
+func (n *NPMRC) AuthHeader(tarballURL string) string {
+	dart := nerfDart(tarballURL)
+	if dart == "" {
+		return ""
+	}
+
+	prefixes := n.registryPrefixes()
+	for _, prefix := range prefixes {
+		if underNerfDart(dart, prefix) {
+			if header := n.authHeader(prefix + ":"); header != "" {
+				return header
+			}
+		}
+	}
+
+	host := strings.SplitN(strings.TrimPrefix(dart, "//"), "/", 2)[0]
+	for _, prefix := range prefixes {
+		prefixHost := strings.SplitN(strings.TrimPrefix(prefix, "//"), "/", 2)[0]
+		if prefixHost == host && n.alwaysAuth(prefix+":") {
+			if header := n.authHeader(prefix + ":"); header != "" {
+				return header
+			}
+		}
+	}
+
+	// Legacy top level credentials belong to the default registry
+	registry := n.values["registry"]
+	if registry == "" {
+		registry = DefaultRegistryURL
+	}
+	if registryDart := nerfDart(registry); registryDart != "" && underNerfDart(dart, registryDart) {
+		return n.authHeader("")
+	}
+	return ""
+}
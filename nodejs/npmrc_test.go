@@ -0,0 +1,129 @@
+package nodejs
+
+import (
+	"bitbucket.org/bosgood/dep-get/lib/fs"
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestParseNPMRC(t *testing.T) {
+	os.Setenv("DEP_GET_TEST_TOKEN", "s3cr3t")
+	defer os.Unsetenv("DEP_GET_TEST_TOKEN")
+
+	npmrc := ParseNPMRC([]byte(`# comment
+; another comment
+registry=https://registry.npmjs.org/
+@ourco:registry = https://npm.ourco.com/
+//npm.ourco.com/:_authToken=${DEP_GET_TEST_TOKEN}
+always-auth="true"
+`))
+
+	cases := map[string]string{
+		"registry":                    "https://registry.npmjs.org/",
+		"@ourco:registry":             "https://npm.ourco.com/",
+		"//npm.ourco.com/:_authToken": "s3cr3t",
+		"always-auth":                 "true",
+		"//npm.ourco.com/:_auth":      "",
+	}
+	for key, expected := range cases {
+		if value := npmrc.Get(key); value != expected {
+			t.Errorf("Expected %q for %s, got %q", expected, key, value)
+		}
+	}
+}
+
+func TestNPMRCAuthHeader(t *testing.T) {
+	password := base64.StdEncoding.EncodeToString([]byte("hunter2"))
+	npmrc := ParseNPMRC([]byte(`
+//npm.ourco.com/:_authToken=token
+//npm.ourco.com/private/:_auth=YWxpY2U6aHVudGVyMg==
+//art.ourco.com/api/npm/npm-repo/:username=bob
+//art.ourco.com/api/npm/npm-repo/:_password=` + password + `
+//art.ourco.com/api/npm/npm-repo/:always-auth=true
+//other.ourco.com/npm/:_authToken=other
+`))
+
+	bobAuth := "Basic " + base64.StdEncoding.EncodeToString([]byte("bob:hunter2"))
+	cases := map[string]string{
+		"https://npm.ourco.com/@ourco/pkg/-/pkg-1.0.0.tgz":                "Bearer token",
+		"https://npm.ourco.com/private/pkg/-/pkg-1.0.0.tgz":               "Basic YWxpY2U6aHVudGVyMg==",
+		"https://art.ourco.com/api/npm/npm-repo/pkg/-/pkg-1.0.0.tgz":      bobAuth,
+		"https://art.ourco.com/api/npm/npm-remote/pkg/-/pkg-1.0.0.tgz":    bobAuth,
+		"https://other.ourco.com/elsewhere/pkg/-/pkg-1.0.0.tgz":           "",
+		"https://registry.npmjs.org/ee-first/-/ee-first-1.1.1.tgz":        "",
+		"git+https://github.com/bosgood/my-fork.git#0123456789abcdef0123": "",
+	}
+	for tarballURL, expected := range cases {
+		if header := npmrc.AuthHeader(tarballURL); header != expected {
+			t.Errorf("Expected %q for %s, got %q", expected, tarballURL, header)
+		}
+	}
+}
+
+func TestNPMRCAuthHeaderLegacy(t *testing.T) {
+	npmrc := ParseNPMRC([]byte(`
+registry=https://npm.ourco.com/
+_authToken=legacy
+`))
+	if header := npmrc.AuthHeader("https://npm.ourco.com/pkg/-/pkg-1.0.0.tgz"); header != "Bearer legacy" {
+		t.Errorf("Expected legacy token for the default registry, got %q", header)
+	}
+	if header := npmrc.AuthHeader("https://registry.npmjs.org/pkg/-/pkg-1.0.0.tgz"); header != "" {
+		t.Errorf("Expected no credentials for another registry, got %q", header)
+	}
+}
+
+func TestNPMRCAuthHeaderLookalikeHost(t *testing.T) {
+	npmrc := ParseNPMRC([]byte(`
+//registry.ourco.com:_authToken=token
+registry=https://npm.ourco.com
+_authToken=legacy
+`))
+	cases := map[string]string{
+		"https://registry.ourco.com/pkg/-/pkg-1.0.0.tgz":          "Bearer token",
+		"https://registry.ourco.com.evil.net/pkg/-/pkg-1.0.0.tgz": "",
+		"https://registry.ourco.community/pkg/-/pkg-1.0.0.tgz":    "",
+		"https://npm.ourco.com/pkg/-/pkg-1.0.0.tgz":               "Bearer legacy",
+		"https://npm.ourco.com.evil.net/pkg/-/pkg-1.0.0.tgz":      "",
+	}
+	for tarballURL, expected := range cases {
+		if header := npmrc.AuthHeader(tarballURL); header != expected {
+			t.Errorf("Expected %q for %s, got %q", expected, tarballURL, header)
+		}
+	}
+}
+
+func TestReadNPMRC(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dep-get-npmrc")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	userConfig := path.Join(dir, "user.npmrc")
+	err = ioutil.WriteFile(userConfig, []byte("//npm.ourco.com/:_authToken=user\nalways-auth=true\n"), 0644)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	err = ioutil.WriteFile(path.Join(dir, NPMRCFileName), []byte("//npm.ourco.com/:_authToken=project\n"), 0644)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	os.Setenv("NPM_CONFIG_USERCONFIG", userConfig)
+	defer os.Unsetenv("NPM_CONFIG_USERCONFIG")
+
+	npmrc, err := ReadNPMRC(&fs.OSFS{}, dir)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if npmrc.Get("//npm.ourco.com/:_authToken") != "project" {
+		t.Errorf("Expected the project .npmrc to take precedence")
+	}
+	if npmrc.Get("always-auth") != "true" {
+		t.Errorf("Expected values from the user .npmrc")
+	}
+}
@@ -0,0 +1,213 @@
+package nodejs
+
+import (
+	"archive/tar"
+	"bitbucket.org/bosgood/dep-get/lib/fs"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// PackMtime is the modification time npm stamps on every file it packs,
+// so that packing the same files always produces the same tarball
+var PackMtime = time.Date(1985, time.October, 26, 8, 15, 0, 0, time.UTC)
+
+// packPrefix is the top level directory of a packed tarball
+const packPrefix = "package/"
+
+// packageJSONFiles holds the package.json fields which decide what is packed
+type packageJSONFiles struct {
+	Files []string `json:"files"`
+	Main  string   `json:"main"`
+}
+
+// packFile is a file to be packed, relative to the package root
+type packFile struct {
+	relPath string
+	info    os.FileInfo
+}
+
+// packLister walks a package directory collecting the files npm would pack
+type packLister struct {
+	fileSystem fs.FileSystem
+	dir        string
+	files      ignoreRules
+	main       string
+	packed     []packFile
+}
+
+// readIgnoreRules reads .npmignore from relDir, falling back to
+// .gitignore when there isn't one
+func (l *packLister) readIgnoreRules(relDir string) (ignoreRules, error) {
+	for _, name := range []string{NPMIgnoreFileName, gitIgnoreFileName} {
+		contents, err := l.fileSystem.ReadFile(path.Join(l.dir, relDir, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return parseIgnoreFile(relDir, contents), nil
+	}
+	return nil, nil
+}
+
+func (l *packLister) walk(relDir string, rules ignoreRules) error {
+	// With a files field, npm disregards the root's ignore files
+	if relDir != "" || l.files == nil {
+		dirRules, err := l.readIgnoreRules(relDir)
+		if err != nil {
+			return err
+		}
+		rules = append(rules[:len(rules):len(rules)], dirRules...)
+	}
+
+	entries, err := l.fileSystem.ReadDir(path.Join(l.dir, relDir))
+	if err != nil {
+		return err
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+
+	for _, entry := range entries {
+		relPath := path.Join(relDir, entry.Name())
+		isDir := entry.IsDir()
+		if !isDir && !entry.Mode().IsRegular() {
+			continue
+		}
+		if alwaysIgnoredFiles.ignored(relPath, isDir) {
+			continue
+		}
+
+		if isDir {
+			if rules.ignored(relPath, true) {
+				continue
+			}
+			if err := l.walk(relPath, rules); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if relDir == "" && (alwaysIncludedFiles.MatchString(relPath) || relPath == l.main) {
+			l.packed = append(l.packed, packFile{relPath, entry})
+			continue
+		}
+		if l.files != nil && !l.files.included(relPath) {
+			continue
+		}
+		if rules.ignored(relPath, false) {
+			continue
+		}
+		l.packed = append(l.packed, packFile{relPath, entry})
+	}
+	return nil
+}
+
+// ListPackFiles returns the files, relative to dir, which npm pack would
+// put in a tarball of the package in dir. The package.json files field
+// is honored, as are .npmignore files, or .gitignore files in
+// directories without one. Lifecycle scripts such as prepare are not run.
+func ListPackFiles(fileSystem fs.FileSystem, dir string) ([]string, error) {
+	packed, err := listPackFiles(fileSystem, dir)
+	if err != nil {
+		return nil, err
+	}
+	var relPaths []string
+	for _, file := range packed {
+		relPaths = append(relPaths, file.relPath)
+	}
+	return relPaths, nil
+}
+
+func listPackFiles(fileSystem fs.FileSystem, dir string) ([]packFile, error) {
+	contents, err := fileSystem.ReadFile(path.Join(dir, packageJSONFileName))
+	if err != nil {
+		return nil, fmt.Errorf("Can't read %s: %s", packageJSONFileName, err)
+	}
+	var pkg packageJSONFiles
+	if err := json.Unmarshal(contents, &pkg); err != nil {
+		return nil, fmt.Errorf("Invalid %s: %s", packageJSONFileName, err)
+	}
+
+	lister := &packLister{
+		fileSystem: fileSystem,
+		dir:        dir,
+		main:       path.Clean(strings.TrimPrefix(pkg.Main, "./")),
+	}
+	if pkg.Files != nil {
+		lister.files = ignoreRules{}
+		for _, entry := range pkg.Files {
+			// Entries name paths from the package root
+			negate := strings.HasPrefix(entry, "!")
+			entry = strings.TrimPrefix(strings.TrimPrefix(entry, "!"), "./")
+			entry = "/" + strings.TrimPrefix(entry, "/")
+			if negate {
+				entry = "!" + entry
+			}
+			if rule, ok := parseIgnoreRule("", entry); ok {
+				lister.files = append(lister.files, rule)
+			}
+		}
+	}
+
+	if err := lister.walk("", nil); err != nil {
+		return nil, err
+	}
+	sort.Slice(lister.packed, func(i, j int) bool {
+		return lister.packed[i].relPath < lister.packed[j].relPath
+	})
+	return lister.packed, nil
+}
+
+// PackDirectory writes a gzipped tarball of the package in dir to w, laid
+// out as npm pack does with every file under package/. Files are written
+// in sorted order with PackMtime and normalized permissions and
+// ownership, so the tarball only changes when the packed files do.
+func PackDirectory(fileSystem fs.FileSystem, dir string, w io.Writer) error {
+	packed, err := listPackFiles(fileSystem, dir)
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	for _, file := range packed {
+		mode := int64(0644)
+		if file.info.Mode()&0111 != 0 {
+			mode = 0755
+		}
+		header := &tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     packPrefix + file.relPath,
+			Mode:     mode,
+			Size:     file.info.Size(),
+			ModTime:  PackMtime,
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+
+		f, err := fileSystem.Open(path.Join(dir, file.relPath))
+		if err != nil {
+			return err
+		}
+		_, err = io.CopyN(tw, f, file.info.Size())
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("Can't pack %s: %s", file.relPath, err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
@@ -0,0 +1,173 @@
+package nodejs
+
+import (
+	"archive/tar"
+	"bitbucket.org/bosgood/dep-get/lib/fs"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+)
+
+// writeTestFiles creates files, keyed by path relative to dir
+func writeTestFiles(t *testing.T, dir string, files map[string]string) {
+	for name, contents := range files {
+		filePath := path.Join(dir, name)
+		if err := os.MkdirAll(path.Dir(filePath), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filePath, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestListPackFilesNPMIgnore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dep-get-pack")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeTestFiles(t, dir, map[string]string{
+		"package.json":          `{"name": "pkg", "version": "1.0.0"}`,
+		"README.md":             "readme",
+		"index.js":              "",
+		".npmignore":            "test/\n*.log\n",
+		".gitignore":            "dist/\n",
+		"dist/index.js":         "",
+		"test/index.js":         "",
+		"debug.log":             "",
+		"lib/a.js":              "",
+		"lib/.gitignore":        "generated.js\n",
+		"lib/generated.js":      "",
+		"node_modules/dep/x.js": "",
+		"package-lock.json":     "{}",
+		".npmrc":                "",
+	})
+
+	files, err := ListPackFiles(&fs.OSFS{}, dir)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	expected := []string{"README.md", "dist/index.js", "index.js", "lib/a.js", "package.json"}
+	if !reflect.DeepEqual(files, expected) {
+		t.Errorf("Expected %v, got %v", expected, files)
+	}
+}
+
+func TestListPackFilesFilesField(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dep-get-pack")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeTestFiles(t, dir, map[string]string{
+		"package.json":       `{"name": "pkg", "main": "./main.js", "files": ["lib", "!lib/test", "types/*.d.ts"]}`,
+		"LICENSE":            "",
+		"main.js":            "",
+		"other.js":           "",
+		".npmignore":         "lib/\n",
+		"lib/a.js":           "",
+		"lib/test/a.js":      "",
+		"lib/sub/.npmignore": "b.js\n",
+		"lib/sub/b.js":       "",
+		"lib/sub/c.js":       "",
+		"types/a.d.ts":       "",
+		"types/a.js":         "",
+	})
+
+	files, err := ListPackFiles(&fs.OSFS{}, dir)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	expected := []string{"LICENSE", "lib/a.js", "lib/sub/c.js", "main.js", "package.json", "types/a.d.ts"}
+	if !reflect.DeepEqual(files, expected) {
+		t.Errorf("Expected %v, got %v", expected, files)
+	}
+}
+
+func TestListPackFilesNoPackageJSON(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dep-get-pack")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if _, err := ListPackFiles(&fs.OSFS{}, dir); err == nil {
+		t.Error("Expected an error without package.json")
+	}
+}
+
+func TestPackDirectory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dep-get-pack")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeTestFiles(t, dir, map[string]string{
+		"package.json": `{"name": "pkg", "version": "1.0.0"}`,
+		"index.js":     "module.exports = 1\n",
+	})
+	if err := os.MkdirAll(path.Join(dir, "bin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path.Join(dir, "bin/cli"), []byte("#!/bin/sh\n"), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	var first bytes.Buffer
+	if err := PackDirectory(&fs.OSFS{}, dir, &first); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := ValidateTarball(bytes.NewReader(first.Bytes())); err != nil {
+		t.Errorf("Expected a valid tarball, got %s", err)
+	}
+
+	// Touching files must not change the tarball
+	later := PackMtime.AddDate(30, 0, 0)
+	os.Chtimes(path.Join(dir, "index.js"), later, later)
+	var second bytes.Buffer
+	if err := PackDirectory(&fs.OSFS{}, dir, &second); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !bytes.Equal(first.Bytes(), second.Bytes()) {
+		t.Error("Expected packing twice to produce identical tarballs")
+	}
+
+	gz, err := gzip.NewReader(&first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gz)
+	modes := make(map[string]int64)
+	var names []string
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, header.Name)
+		modes[header.Name] = header.Mode
+		if !header.ModTime.Equal(PackMtime) {
+			t.Errorf("%s: expected mtime %s, got %s", header.Name, PackMtime, header.ModTime)
+		}
+		if header.Uid != 0 || header.Gid != 0 || header.Uname != "" {
+			t.Errorf("%s: expected no owner, got %d:%d %s", header.Name, header.Uid, header.Gid, header.Uname)
+		}
+	}
+
+	expected := []string{"package/bin/cli", "package/index.js", "package/package.json"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected %v, got %v", expected, names)
+	}
+	if modes["package/bin/cli"] != 0755 || modes["package/index.js"] != 0644 {
+		t.Errorf("Expected normalized modes, got %v", modes)
+	}
+}
@@ -0,0 +1,291 @@
+package nodejs
+
+import (
+	"gopkg.in/yaml.v2"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PNPMLockfileName is the name of the pnpm dependencies lock file
+const PNPMLockfileName = "pnpm-lock.yaml"
+
+// PNPMLockfile represents a pnpm-lock.yaml file. Before lockfile
+// version 9, the dependencies of a project without workspaces are at the
+// top level rather than under Importers.
+type PNPMLockfile struct {
+	LockfileVersion string                   `yaml:"lockfileVersion"`
+	Importers       map[string]*PNPMImporter `yaml:"importers"`
+	PNPMImporter    `yaml:",inline"`
+	Packages        map[string]*PNPMPackage `yaml:"packages"`
+	// Snapshots hold the dependencies of packages from lockfile version 9
+	Snapshots map[string]*PNPMSnapshot `yaml:"snapshots"`
+}
+
+// PNPMImporter represents the dependencies of the project or one of
+// its workspaces
+type PNPMImporter struct {
+	Dependencies         map[string]PNPMImporterDependency `yaml:"dependencies"`
+	DevDependencies      map[string]PNPMImporterDependency `yaml:"devDependencies"`
+	OptionalDependencies map[string]PNPMImporterDependency `yaml:"optionalDependencies"`
+}
+
+// PNPMImporterDependency is the version a project dependency resolved
+// to, which lockfile version 5 records on its own and later versions
+// alongside its specifier
+type PNPMImporterDependency struct {
+	Specifier string `yaml:"specifier"`
+	Version   string `yaml:"version"`
+}
+
+// UnmarshalYAML decodes either form of a project dependency
+func (d *PNPMImporterDependency) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	if err := unmarshal(&d.Version); err == nil {
+		return nil
+	}
+	type plain PNPMImporterDependency
+	return unmarshal((*plain)(d))
+}
+
+// PNPMSnapshot represents an entry of the snapshots block of a version 9
+// pnpm-lock.yaml file, keyed by package key and peer dependencies
+type PNPMSnapshot struct {
+	Dependencies         map[string]string `yaml:"dependencies"`
+	OptionalDependencies map[string]string `yaml:"optionalDependencies"`
+}
+
+// PNPMPackage represents an entry of the packages block of a
+// pnpm-lock.yaml file. Name and Version are only recorded when they
+// can't be derived from the package key.
+type PNPMPackage struct {
+	Name                 string            `yaml:"name"`
+	Version              string            `yaml:"version"`
+	Resolution           PNPMResolution    `yaml:"resolution"`
+	Dependencies         map[string]string `yaml:"dependencies"`
+	OptionalDependencies map[string]string `yaml:"optionalDependencies"`
+	// Dev and Optional are only recorded before lockfile version 9
+	Dev      bool `yaml:"dev"`
+	Optional bool `yaml:"optional"`
+}
+
+// PNPMResolution represents where pnpm fetched a package from.
+// Registry packages only record their integrity.
+type PNPMResolution struct {
+	Integrity string `yaml:"integrity"`
+	Tarball   string `yaml:"tarball"`
+	Directory string `yaml:"directory"`
+	Repo      string `yaml:"repo"`
+	Commit    string `yaml:"commit"`
+	Type      string `yaml:"type"`
+}
+
+// ParsePNPMLockfile decodes a pnpm-lock.yaml file
+func ParsePNPMLockfile(contents []byte) (PNPMLockfile, error) {
+	var lockfile PNPMLockfile
+	err := yaml.Unmarshal(contents, &lockfile)
+	return lockfile, err
+}
+
+// majorVersion returns the integer part of a lockfile version such as 5.4
+func (l *PNPMLockfile) majorVersion() int {
+	version, err := strconv.ParseFloat(l.LockfileVersion, 64)
+	if err != nil {
+		return 0
+	}
+	return int(version)
+}
+
+// parsePNPMPackageKey derives the package name and version from a
+// packages key, which is /name/version_peers in lockfile version 5,
+// /name@version(peers) in version 6 and name@version from version 9
+func parsePNPMPackageKey(key string, lockfileVersion int) (string, string) {
+	key = strings.TrimPrefix(key, "/")
+
+	if lockfileVersion >= 6 {
+		if i := strings.Index(key, "("); i >= 0 {
+			key = key[:i]
+		}
+		if key == "" {
+			return "", ""
+		}
+		i := strings.Index(key[1:], "@")
+		if i < 0 {
+			return key, ""
+		}
+		return key[:i+1], key[i+2:]
+	}
+
+	i := strings.LastIndex(key, "/")
+	if i < 0 {
+		return key, ""
+	}
+	version := key[i+1:]
+	if j := strings.Index(version, "_"); j >= 0 {
+		version = version[:j]
+	}
+	return key[:i], version
+}
+
+// pnpmGraph finds packages by the versions dependencies resolve to
+type pnpmGraph struct {
+	*DependencyGraph
+	lockfileVersion int
+	byNameVersion   map[string]*DependencyNode
+}
+
+// node returns the package the dependency name resolves to at version,
+// which may carry peer dependencies or be a whole package key
+func (g *pnpmGraph) node(name, version string) *DependencyNode {
+	if node := g.Node(version); node != nil {
+		return node
+	}
+	if node := g.Node("/" + version); node != nil {
+		return node
+	}
+	if strings.HasPrefix(version, "/") {
+		name, version = parsePNPMPackageKey(version, g.lockfileVersion)
+	}
+	separator := "_"
+	if g.lockfileVersion >= 6 {
+		separator = "("
+	}
+	version = strings.SplitN(version, separator, 2)[0]
+	return g.byNameVersion[name+"@"+version]
+}
+
+// link links from to the packages in dependency blocks, marking the
+// edges as optional dependencies if optional is set
+func (g *pnpmGraph) link(from *DependencyNode, optional bool, blocks ...map[string]string) {
+	for _, deps := range blocks {
+		for _, name := range sortedKeys(deps) {
+			if optional {
+				g.linkAs(from, g.node(name, deps[name]), g.optionalEdges)
+			} else {
+				g.Link(from, g.node(name, deps[name]))
+			}
+		}
+	}
+}
+
+// PNPMDependencyGraph builds the dependency graph of a pnpm-lock.yaml
+// file, with nodes ordered and keyed by package key, reconstructing
+// registry tarball URLs for packages which only record their integrity.
+// From lockfile version 9, which no longer marks dev and optional
+// packages, they're worked out from the project's dependency types.
+func PNPMDependencyGraph(lockfile PNPMLockfile) *DependencyGraph {
+	g := &pnpmGraph{
+		DependencyGraph: NewDependencyGraph("", ""),
+		lockfileVersion: lockfile.majorVersion(),
+		byNameVersion:   make(map[string]*DependencyNode),
+	}
+	g.flagsFromEdges = g.lockfileVersion >= 9
+	g.typedRoot = true
+
+	var keys []string
+	for key := range lockfile.Packages {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		pkg := lockfile.Packages[key]
+		keyName, keyVersion := parsePNPMPackageKey(key, g.lockfileVersion)
+		name, version := keyName, keyVersion
+		if pkg.Name != "" {
+			name = pkg.Name
+		}
+		if pkg.Version != "" {
+			version = pkg.Version
+		}
+
+		resolution := pkg.Resolution
+		var packageURL, localPath string
+		switch {
+		case resolution.Tarball != "":
+			var ok bool
+			if localPath, ok = LocalSpecPath(resolution.Tarball); !ok {
+				packageURL = resolution.Tarball
+			}
+		case resolution.Type == "git":
+			packageURL = "git+" + resolution.Repo + "#" + resolution.Commit
+		case resolution.Type == "directory":
+			localPath = resolution.Directory
+		default:
+			packageURL = RegistryTarballURL(DefaultRegistryURL, name, version)
+		}
+		// Local packages are keyed by their specifier, not a version
+		if _, ok := LocalSpecPath(version); ok {
+			version = ""
+		}
+
+		node := g.Add(NodeDependency{
+			Name:       name,
+			Version:    version,
+			PackageURL: packageURL,
+			LocalPath:  localPath,
+			Integrity:  resolution.Integrity,
+			Dev:        pkg.Dev,
+			Optional:   pkg.Optional,
+		}, key)
+		nameVersion := keyName + "@" + keyVersion
+		if g.byNameVersion[nameVersion] == nil {
+			g.byNameVersion[nameVersion] = node
+		}
+	}
+
+	root := &lockfile.PNPMImporter
+	if importer := lockfile.Importers["."]; importer != nil {
+		root = importer
+	}
+	blocks := []struct {
+		deps  map[string]PNPMImporterDependency
+		edges map[dependencyEdge]bool
+	}{
+		{root.Dependencies, nil},
+		{root.OptionalDependencies, g.optionalEdges},
+		{root.DevDependencies, g.devEdges},
+	}
+	for _, block := range blocks {
+		var names []string
+		for name := range block.deps {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			node := g.node(name, block.deps[name].Version)
+			if block.edges == nil {
+				g.Link(g.Root, node)
+			} else {
+				g.linkAs(g.Root, node, block.edges)
+			}
+		}
+	}
+
+	for _, node := range g.Nodes {
+		pkg := lockfile.Packages[node.Path]
+		g.link(node, false, pkg.Dependencies)
+		g.link(node, true, pkg.OptionalDependencies)
+	}
+	var snapshotKeys []string
+	for key := range lockfile.Snapshots {
+		snapshotKeys = append(snapshotKeys, key)
+	}
+	sort.Strings(snapshotKeys)
+	for _, key := range snapshotKeys {
+		name, version := parsePNPMPackageKey(key, g.lockfileVersion)
+		snapshot := lockfile.Snapshots[key]
+		from := g.node(name, version)
+		g.link(from, false, snapshot.Dependencies)
+		g.link(from, true, snapshot.OptionalDependencies)
+	}
+
+	g.finish()
+	return g.DependencyGraph
+}
+
+// CollectPNPMDependencies lists the dependencies of a pnpm-lock.yaml
+// file ordered by package key, reconstructing registry tarball URLs
+// for packages which only record their integrity
+func CollectPNPMDependencies(lockfile PNPMLockfile) []NodeDependency {
+	return PNPMDependencyGraph(lockfile).Dependencies()
+}
@@ -0,0 +1,176 @@
+package nodejs
+
+import (
+	"testing"
+)
+
+var pnpmLockV5 = []byte(`lockfileVersion: 5.4
+
+specifiers:
+  on-finished: ^2.3.0
+
+dependencies:
+  on-finished: 2.3.0
+
+packages:
+
+  /@babel/code-frame/7.10.4:
+    resolution: {integrity: sha512-vG6SvB6oYEhvgisZNFRmRCUkLz11c7rp+tbNTynGqc6mS1d5ATd/sGyV6W0KZZnXRKMTzZDRgQT3Ou9jhpAfUg==}
+    dev: true
+
+  /ee-first/1.1.1:
+    resolution: {integrity: sha1-WQxhFWsK4vTwJVcyoViyZrxWsh0=}
+    dev: false
+
+  /on-finished/2.3.0_ee-first@1.1.1:
+    resolution: {integrity: sha1-IPEzZIGwg811M3mSoWlxqi2QaUc=}
+    dependencies:
+      ee-first: 1.1.1
+    dev: false
+
+  github.com/bosgood/my-fork/0123456789abcdef0123456789abcdef01234567:
+    resolution: {tarball: https://codeload.github.com/bosgood/my-fork/tar.gz/0123456789abcdef0123456789abcdef01234567}
+    name: my-fork
+    version: 1.0.0
+    dev: false
+`)
+
+var pnpmLockV9 = []byte(`lockfileVersion: '9.0'
+
+importers:
+
+  .:
+    dependencies:
+      on-finished:
+        specifier: ^2.3.0
+        version: 2.3.0
+
+packages:
+
+  '@babel/code-frame@7.10.4':
+    resolution: {integrity: sha512-vG6SvB6oYEhvgisZNFRmRCUkLz11c7rp+tbNTynGqc6mS1d5ATd/sGyV6W0KZZnXRKMTzZDRgQT3Ou9jhpAfUg==}
+
+  ee-first@1.1.1:
+    resolution: {integrity: sha1-WQxhFWsK4vTwJVcyoViyZrxWsh0=}
+
+  my-fork@https://codeload.github.com/bosgood/my-fork/tar.gz/0123456789abcdef0123456789abcdef01234567:
+    resolution: {tarball: https://codeload.github.com/bosgood/my-fork/tar.gz/0123456789abcdef0123456789abcdef01234567}
+    version: 1.0.0
+
+  on-finished@2.3.0:
+    resolution: {integrity: sha1-IPEzZIGwg811M3mSoWlxqi2QaUc=}
+
+  shared@file:../shared:
+    resolution: {directory: ../shared, type: directory}
+
+snapshots:
+
+  on-finished@2.3.0:
+    dependencies:
+      ee-first: 1.1.1
+`)
+
+var pnpmExpectedDependencies = []NodeDependency{
+	{
+		Name:       "@babel/code-frame",
+		Version:    "7.10.4",
+		PackageURL: "https://registry.npmjs.org/@babel/code-frame/-/code-frame-7.10.4.tgz",
+		Integrity:  "sha512-vG6SvB6oYEhvgisZNFRmRCUkLz11c7rp+tbNTynGqc6mS1d5ATd/sGyV6W0KZZnXRKMTzZDRgQT3Ou9jhpAfUg==",
+	},
+	{
+		Name:       "ee-first",
+		Version:    "1.1.1",
+		PackageURL: "https://registry.npmjs.org/ee-first/-/ee-first-1.1.1.tgz",
+		Integrity:  "sha1-WQxhFWsK4vTwJVcyoViyZrxWsh0=",
+	},
+}
+
+func TestParsePNPMPackageKey(t *testing.T) {
+	cases := []struct {
+		key             string
+		lockfileVersion int
+		name            string
+		version         string
+	}{
+		{"/ee-first/1.1.1", 5, "ee-first", "1.1.1"},
+		{"/@babel/core/7.0.0", 5, "@babel/core", "7.0.0"},
+		{"/on-finished/2.3.0_ee-first@1.1.1", 5, "on-finished", "2.3.0"},
+		{"/ee-first@1.1.1", 6, "ee-first", "1.1.1"},
+		{"/@babel/core@7.0.0(supports-color@5.5.0)", 6, "@babel/core", "7.0.0"},
+		{"@babel/core@7.0.0", 9, "@babel/core", "7.0.0"},
+		{"", 9, "", ""},
+		{"/", 6, "", ""},
+		{"(peer@1.0.0)", 9, "", ""},
+		{"", 5, "", ""},
+	}
+	for _, c := range cases {
+		name, version := parsePNPMPackageKey(c.key, c.lockfileVersion)
+		if name != c.name || version != c.version {
+			t.Errorf("Expected %s@%s for %s, got %s@%s", c.name, c.version, c.key, name, version)
+		}
+	}
+}
+
+func TestCollectPNPMDependenciesV5(t *testing.T) {
+	deps, err := ParseDependencies(PNPMLockfileName, pnpmLockV5)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := append(pnpmExpectedDependencies, []NodeDependency{
+		{
+			Name:       "on-finished",
+			Version:    "2.3.0",
+			PackageURL: "https://registry.npmjs.org/on-finished/-/on-finished-2.3.0.tgz",
+			Integrity:  "sha1-IPEzZIGwg811M3mSoWlxqi2QaUc=",
+		},
+		{
+			Name:       "my-fork",
+			Version:    "1.0.0",
+			PackageURL: "https://codeload.github.com/bosgood/my-fork/tar.gz/0123456789abcdef0123456789abcdef01234567",
+		},
+	}...)
+	// Lockfile version 5 marks packages only dev dependencies need
+	expected[0].Dev = true
+	if len(deps) != len(expected) {
+		t.Fatalf("Expected %d dependencies, found %d: %+v", len(expected), len(deps), deps)
+	}
+	for i, dep := range expected {
+		if deps[i] != dep {
+			t.Errorf("Expected %+v at %d, got %+v", dep, i, deps[i])
+		}
+	}
+}
+
+func TestCollectPNPMDependenciesV9(t *testing.T) {
+	deps, err := ParseDependencies(PNPMLockfileName, pnpmLockV9)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := append(pnpmExpectedDependencies, []NodeDependency{
+		{
+			Name:       "my-fork",
+			Version:    "1.0.0",
+			PackageURL: "https://codeload.github.com/bosgood/my-fork/tar.gz/0123456789abcdef0123456789abcdef01234567",
+		},
+		{
+			Name:       "on-finished",
+			Version:    "2.3.0",
+			PackageURL: "https://registry.npmjs.org/on-finished/-/on-finished-2.3.0.tgz",
+			Integrity:  "sha1-IPEzZIGwg811M3mSoWlxqi2QaUc=",
+		},
+		{
+			Name:      "shared",
+			LocalPath: "../shared",
+		},
+	}...)
+	if len(deps) != len(expected) {
+		t.Fatalf("Expected %d dependencies, found %d: %+v", len(expected), len(deps), deps)
+	}
+	for i, dep := range expected {
+		if deps[i] != dep {
+			t.Errorf("Expected %+v at %d, got %+v", dep, i, deps[i])
+		}
+	}
+}
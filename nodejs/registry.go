@@ -0,0 +1,143 @@
+package nodejs
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// PublicRegistryURLs lists the registries lockfiles commonly resolve
+// packages to, which mirrors and scoped registries stand in for
+var PublicRegistryURLs = []string{
+	DefaultRegistryURL,
+	"https://registry.yarnpkg.com/",
+}
+
+// registryPrefix rewrites URLs starting with from to start with to
+type registryPrefix struct {
+	from string
+	to   string
+}
+
+// RegistryRewriter routes tarball URLs recorded in lockfiles to mirrors
+// and to the registries configured for scoped packages
+type RegistryRewriter struct {
+	prefixes []registryPrefix
+	scopes   map[string]string
+}
+
+// NewRegistryRewriter creates a RegistryRewriter which leaves URLs as they are
+func NewRegistryRewriter() *RegistryRewriter {
+	return &RegistryRewriter{scopes: make(map[string]string)}
+}
+
+func withTrailingSlash(s string) string {
+	if strings.HasSuffix(s, "/") {
+		return s
+	}
+	return s + "/"
+}
+
+// AddPrefix rewrites URLs starting with from to start with to instead,
+// replacing any rule for the same prefix. The longest matching prefix wins.
+func (r *RegistryRewriter) AddPrefix(from, to string) {
+	from = withTrailingSlash(from)
+	to = withTrailingSlash(to)
+	for i, prefix := range r.prefixes {
+		if prefix.from == from {
+			r.prefixes[i].to = to
+			return
+		}
+	}
+	r.prefixes = append(r.prefixes, registryPrefix{from: from, to: to})
+	sort.SliceStable(r.prefixes, func(i, j int) bool {
+		return len(r.prefixes[i].from) > len(r.prefixes[j].from)
+	})
+}
+
+// AddScope fetches packages in scope, such as @ourco, from registryURL
+// when the lockfile resolved them to a public registry
+func (r *RegistryRewriter) AddScope(scope, registryURL string) {
+	r.scopes[scope] = withTrailingSlash(registryURL)
+}
+
+// AddRule parses a rewrite rule, which is either a prefix map such as
+// "https://registry.npmjs.org/ -> https://npm.internal/" or a scoped
+// registry in .npmrc syntax such as "@ourco:registry=https://npm.ourco.com/"
+func (r *RegistryRewriter) AddRule(rule string) error {
+	if i := strings.Index(rule, "->"); i >= 0 {
+		from := strings.TrimSpace(rule[:i])
+		to := strings.TrimSpace(rule[i+2:])
+		if from == "" || to == "" {
+			return fmt.Errorf("Malformed registry rewrite: %s", rule)
+		}
+		r.AddPrefix(from, to)
+		return nil
+	}
+
+	if strings.HasPrefix(rule, "@") {
+		i := strings.Index(rule, ":registry=")
+		if i < 0 {
+			return fmt.Errorf("Malformed scoped registry: %s", rule)
+		}
+		scope := rule[:i]
+		registryURL := strings.TrimSpace(rule[i+len(":registry="):])
+		if registryURL == "" {
+			return fmt.Errorf("Malformed scoped registry: %s", rule)
+		}
+		r.AddScope(scope, registryURL)
+		return nil
+	}
+
+	return fmt.Errorf("Unknown registry rewrite: %s", rule)
+}
+
+// AddNPMRC applies the registries configured in .npmrc: scoped registries,
+// and the default registry standing in for the public ones as npm does
+func (r *RegistryRewriter) AddNPMRC(npmrc *NPMRC) {
+	for key, value := range npmrc.values {
+		if strings.HasPrefix(key, "@") && strings.HasSuffix(key, ":registry") && value != "" {
+			r.AddScope(strings.TrimSuffix(key, ":registry"), value)
+		}
+	}
+
+	registry := npmrc.Get("registry")
+	if registry == "" || withTrailingSlash(registry) == DefaultRegistryURL {
+		return
+	}
+	for _, publicRegistry := range PublicRegistryURLs {
+		r.AddPrefix(publicRegistry, registry)
+	}
+}
+
+// packageScope returns the scope of a package name such as @ourco/pkg
+func packageScope(name string) string {
+	if !strings.HasPrefix(name, "@") {
+		return ""
+	}
+	i := strings.Index(name, "/")
+	if i < 0 {
+		return ""
+	}
+	return name[:i]
+}
+
+// Rewrite returns the URL to download the package name from
+// in place of the tarball URL recorded in the lockfile
+func (r *RegistryRewriter) Rewrite(name, tarballURL string) string {
+	if scopeRegistry, ok := r.scopes[packageScope(name)]; ok {
+		for _, publicRegistry := range PublicRegistryURLs {
+			if strings.HasPrefix(tarballURL, publicRegistry) {
+				tarballURL = scopeRegistry + strings.TrimPrefix(tarballURL, publicRegistry)
+				break
+			}
+		}
+	}
+
+	for _, prefix := range r.prefixes {
+		if strings.HasPrefix(tarballURL, prefix.from) {
+			return prefix.to + strings.TrimPrefix(tarballURL, prefix.from)
+		}
+	}
+	return tarballURL
+}
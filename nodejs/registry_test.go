@@ -0,0 +1,87 @@
+package nodejs
+
+import (
+	"testing"
+)
+
+func TestRegistryRewriterPrefix(t *testing.T) {
+	r := NewRegistryRewriter()
+	if err := r.AddRule("https://registry.npmjs.org/ -> https://npm.internal/"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := r.AddRule("https://registry.npmjs.org/@types->https://types.internal/npm"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	cases := map[string]string{
+		"https://registry.npmjs.org/ee-first/-/ee-first-1.1.1.tgz":   "https://npm.internal/ee-first/-/ee-first-1.1.1.tgz",
+		"https://registry.npmjs.org/@types/node/-/node-16.0.0.tgz":   "https://types.internal/npm/node/-/node-16.0.0.tgz",
+		"https://registry.yarnpkg.com/ee-first/-/ee-first-1.1.1.tgz": "https://registry.yarnpkg.com/ee-first/-/ee-first-1.1.1.tgz",
+	}
+	for tarballURL, expected := range cases {
+		if rewritten := r.Rewrite("ee-first", tarballURL); rewritten != expected {
+			t.Errorf("Expected %s for %s, got %s", expected, tarballURL, rewritten)
+		}
+	}
+}
+
+func TestRegistryRewriterScope(t *testing.T) {
+	r := NewRegistryRewriter()
+	if err := r.AddRule("@ourco:registry=https://npm.ourco.com"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	rewritten := r.Rewrite("@ourco/pkg", "https://registry.yarnpkg.com/@ourco/pkg/-/pkg-1.0.0.tgz")
+	if rewritten != "https://npm.ourco.com/@ourco/pkg/-/pkg-1.0.0.tgz" {
+		t.Errorf("Expected scoped package to use the scope's registry, got %s", rewritten)
+	}
+
+	rewritten = r.Rewrite("@other/pkg", "https://registry.npmjs.org/@other/pkg/-/pkg-1.0.0.tgz")
+	if rewritten != "https://registry.npmjs.org/@other/pkg/-/pkg-1.0.0.tgz" {
+		t.Errorf("Expected other scopes to be left alone, got %s", rewritten)
+	}
+}
+
+func TestRegistryRewriterMalformed(t *testing.T) {
+	r := NewRegistryRewriter()
+	for _, rule := range []string{"https://registry.npmjs.org/", "-> https://npm.internal/", "@ourco=https://npm.ourco.com/"} {
+		if err := r.AddRule(rule); err == nil {
+			t.Errorf("Expected an error for %q", rule)
+		}
+	}
+}
+
+func TestRegistryRewriterNPMRC(t *testing.T) {
+	r := NewRegistryRewriter()
+	r.AddNPMRC(ParseNPMRC([]byte(`
+registry=https://npm.internal/
+@ourco:registry=https://npm.ourco.com/
+`)))
+
+	cases := []struct {
+		name       string
+		tarballURL string
+		expected   string
+	}{
+		{
+			"ee-first",
+			"https://registry.npmjs.org/ee-first/-/ee-first-1.1.1.tgz",
+			"https://npm.internal/ee-first/-/ee-first-1.1.1.tgz",
+		},
+		{
+			"ee-first",
+			"https://registry.yarnpkg.com/ee-first/-/ee-first-1.1.1.tgz",
+			"https://npm.internal/ee-first/-/ee-first-1.1.1.tgz",
+		},
+		{
+			"@ourco/pkg",
+			"https://registry.npmjs.org/@ourco/pkg/-/pkg-1.0.0.tgz",
+			"https://npm.ourco.com/@ourco/pkg/-/pkg-1.0.0.tgz",
+		},
+	}
+	for _, c := range cases {
+		if rewritten := r.Rewrite(c.name, c.tarballURL); rewritten != c.expected {
+			t.Errorf("Expected %s for %s, got %s", c.expected, c.tarballURL, rewritten)
+		}
+	}
+}
@@ -0,0 +1,350 @@
+package nodejs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// semver is a parsed semantic version. Build metadata is dropped since it
+// plays no part in ordering.
+type semver struct {
+	major, minor, patch int
+	prerelease          []string
+}
+
+// parseSemver parses a version such as 1.2.3 or v1.2.3-beta.1
+func parseSemver(version string) (semver, error) {
+	var v semver
+	s := strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(version), "="), "v")
+	if i := strings.Index(s, "+"); i >= 0 {
+		s = s[:i]
+	}
+	if i := strings.Index(s, "-"); i >= 0 {
+		v.prerelease = strings.Split(s[i+1:], ".")
+		s = s[:i]
+	}
+
+	parts := strings.Split(s, ".")
+	if len(parts) != 3 {
+		return v, fmt.Errorf("Invalid version: %s", version)
+	}
+	numbers := []*int{&v.major, &v.minor, &v.patch}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return v, fmt.Errorf("Invalid version: %s", version)
+		}
+		*numbers[i] = n
+	}
+	return v, nil
+}
+
+func compareInts(a, b int) int {
+	if a < b {
+		return -1
+	}
+	if a > b {
+		return 1
+	}
+	return 0
+}
+
+// comparePrerelease orders prerelease identifiers, numeric ones
+// numerically and below alphanumeric ones
+func comparePrerelease(a, b []string) int {
+	if len(a) == 0 || len(b) == 0 {
+		// A release ranks above its prereleases
+		return -compareInts(len(a), len(b))
+	}
+	for i := 0; i < len(a) && i < len(b); i++ {
+		an, aErr := strconv.Atoi(a[i])
+		bn, bErr := strconv.Atoi(b[i])
+		switch {
+		case aErr == nil && bErr == nil:
+			if c := compareInts(an, bn); c != 0 {
+				return c
+			}
+		case aErr == nil:
+			return -1
+		case bErr == nil:
+			return 1
+		default:
+			if c := strings.Compare(a[i], b[i]); c != 0 {
+				return c
+			}
+		}
+	}
+	return compareInts(len(a), len(b))
+}
+
+func (v semver) compare(other semver) int {
+	if c := compareInts(v.major, other.major); c != 0 {
+		return c
+	}
+	if c := compareInts(v.minor, other.minor); c != 0 {
+		return c
+	}
+	if c := compareInts(v.patch, other.patch); c != 0 {
+		return c
+	}
+	return comparePrerelease(v.prerelease, other.prerelease)
+}
+
+// semverComparator is one condition of a range, such as >=1.2.3
+type semverComparator struct {
+	op      string
+	version semver
+}
+
+func (c semverComparator) matches(v semver) bool {
+	cmp := v.compare(c.version)
+	switch c.op {
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	}
+	return cmp == 0
+}
+
+// SemverRange is a range of versions in npm's syntax, such as ^1.2.3,
+// ~1.2, 1.x, >=1.0.0 <2.0.0, 1.0.0 - 1.5.0 or ^1.0.0 || ^2.0.0
+type SemverRange struct {
+	// sets are alternatives, each satisfied when all its comparators are
+	sets [][]semverComparator
+}
+
+// partialVersion is a possibly incomplete version from a range, such as
+// 1.2 or 1.x, where parts counts the numbers given
+type partialVersion struct {
+	version semver
+	parts   int
+}
+
+func parsePartialVersion(s string) (partialVersion, error) {
+	var p partialVersion
+	s = strings.TrimPrefix(s, "v")
+	if i := strings.Index(s, "+"); i >= 0 {
+		s = s[:i]
+	}
+	if i := strings.Index(s, "-"); i >= 0 {
+		p.version.prerelease = strings.Split(s[i+1:], ".")
+		s = s[:i]
+	}
+	if s == "" || s == "*" || s == "x" || s == "X" {
+		return p, nil
+	}
+
+	numbers := []*int{&p.version.major, &p.version.minor, &p.version.patch}
+	parts := strings.Split(s, ".")
+	if len(parts) > 3 {
+		return p, fmt.Errorf("Invalid version: %s", s)
+	}
+	for i, part := range parts {
+		if part == "*" || part == "x" || part == "X" {
+			break
+		}
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return p, fmt.Errorf("Invalid version: %s", s)
+		}
+		*numbers[i] = n
+		p.parts++
+	}
+	if p.parts < 3 {
+		p.version.prerelease = nil
+	}
+	return p, nil
+}
+
+// bump returns the lowest version above every version p stands for,
+// as a prerelease so that it excludes that version's prereleases too
+func (p partialVersion) bump(parts int) semver {
+	v := semver{prerelease: []string{"0"}}
+	switch parts {
+	case 1:
+		v.major = p.version.major + 1
+	case 2:
+		v.major, v.minor = p.version.major, p.version.minor+1
+	default:
+		v.major, v.minor, v.patch = p.version.major, p.version.minor, p.version.patch+1
+	}
+	return v
+}
+
+// rangeComparators desugars a single range term, such as ^1.2 or >=1.0.0
+func rangeComparators(term string) ([]semverComparator, error) {
+	op := ""
+	for _, prefix := range []string{">=", "<=", ">", "<", "=", "^", "~>", "~"} {
+		if strings.HasPrefix(term, prefix) {
+			op = prefix
+			break
+		}
+	}
+	p, err := parsePartialVersion(strings.TrimSpace(strings.TrimPrefix(term, op)))
+	if err != nil {
+		return nil, err
+	}
+	lower := p.version
+	anyVersion := []semverComparator{{">=", semver{}}}
+
+	switch op {
+	case "^":
+		if p.parts == 0 {
+			return anyVersion, nil
+		}
+		upperParts := 1
+		if p.version.major == 0 && p.parts >= 2 {
+			upperParts = 2
+			if p.version.minor == 0 && p.parts == 3 {
+				upperParts = 3
+			}
+		}
+		return []semverComparator{{">=", lower}, {"<", p.bump(upperParts)}}, nil
+	case "~", "~>":
+		if p.parts == 0 {
+			return anyVersion, nil
+		}
+		upperParts := 2
+		if p.parts == 1 {
+			upperParts = 1
+		}
+		return []semverComparator{{">=", lower}, {"<", p.bump(upperParts)}}, nil
+	case ">":
+		if p.parts == 0 {
+			return []semverComparator{{"<", semver{prerelease: []string{"0"}}}}, nil
+		}
+		if p.parts < 3 {
+			return []semverComparator{{">=", p.bump(p.parts)}}, nil
+		}
+		return []semverComparator{{">", lower}}, nil
+	case ">=":
+		return []semverComparator{{">=", lower}}, nil
+	case "<":
+		if p.parts > 0 && p.parts < 3 {
+			lower.prerelease = []string{"0"}
+		}
+		return []semverComparator{{"<", lower}}, nil
+	case "<=":
+		if p.parts == 0 {
+			return anyVersion, nil
+		}
+		if p.parts < 3 {
+			return []semverComparator{{"<", p.bump(p.parts)}}, nil
+		}
+		return []semverComparator{{"<=", lower}}, nil
+	}
+
+	// Plain and = versions, possibly with wildcards
+	if p.parts == 0 {
+		return anyVersion, nil
+	}
+	if p.parts < 3 {
+		return []semverComparator{{">=", lower}, {"<", p.bump(p.parts)}}, nil
+	}
+	return []semverComparator{{"=", lower}}, nil
+}
+
+// hyphenComparators desugars a range such as 1.2 - 2.3.4
+func hyphenComparators(from, to string) ([]semverComparator, error) {
+	low, err := parsePartialVersion(from)
+	if err != nil {
+		return nil, err
+	}
+	high, err := parsePartialVersion(to)
+	if err != nil {
+		return nil, err
+	}
+	comparators := []semverComparator{{">=", low.version}}
+	switch {
+	case high.parts == 0:
+	case high.parts < 3:
+		comparators = append(comparators, semverComparator{"<", high.bump(high.parts)})
+	default:
+		comparators = append(comparators, semverComparator{"<=", high.version})
+	}
+	return comparators, nil
+}
+
+// ParseSemverRange parses a range in npm's syntax
+func ParseSemverRange(r string) (SemverRange, error) {
+	var semverRange SemverRange
+	for _, alternative := range strings.Split(r, "||") {
+		fields := strings.Fields(alternative)
+
+		// Operators may be separated from their versions by spaces
+		var terms []string
+		for i := 0; i < len(fields); i++ {
+			if strings.Trim(fields[i], "<>=~^") == "" && i+1 < len(fields) {
+				terms = append(terms, fields[i]+fields[i+1])
+				i++
+				continue
+			}
+			terms = append(terms, fields[i])
+		}
+
+		var set []semverComparator
+		if len(terms) == 3 && terms[1] == "-" {
+			comparators, err := hyphenComparators(terms[0], terms[2])
+			if err != nil {
+				return semverRange, err
+			}
+			set = comparators
+		} else {
+			for _, term := range terms {
+				comparators, err := rangeComparators(term)
+				if err != nil {
+					return semverRange, err
+				}
+				set = append(set, comparators...)
+			}
+		}
+		if len(set) == 0 {
+			set = []semverComparator{{">=", semver{}}}
+		}
+		semverRange.sets = append(semverRange.sets, set)
+	}
+	return semverRange, nil
+}
+
+// satisfiedBy reports whether every comparator in set matches v. As in
+// npm, prereleases only match when a comparator names a prerelease of the
+// same version.
+func satisfiedBy(set []semverComparator, v semver) bool {
+	for _, comparator := range set {
+		if !comparator.matches(v) {
+			return false
+		}
+	}
+	if len(v.prerelease) == 0 {
+		return true
+	}
+	for _, comparator := range set {
+		c := comparator.version
+		if len(c.prerelease) > 0 && !(len(c.prerelease) == 1 && c.prerelease[0] == "0" && comparator.op == "<") &&
+			c.major == v.major && c.minor == v.minor && c.patch == v.patch {
+			return true
+		}
+	}
+	return false
+}
+
+// Contains reports whether version is in the range. Versions which
+// aren't valid semver never are.
+func (r SemverRange) Contains(version string) bool {
+	v, err := parseSemver(version)
+	if err != nil {
+		return false
+	}
+	for _, set := range r.sets {
+		if satisfiedBy(set, v) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,89 @@
+package nodejs
+
+import (
+	"testing"
+)
+
+func TestParseSemver(t *testing.T) {
+	cases := []struct {
+		a, b     string
+		expected int
+	}{
+		{"1.2.3", "1.2.3", 0},
+		{"v1.2.3", "1.2.3+build.5", 0},
+		{"1.2.3", "1.10.0", -1},
+		{"2.0.0", "1.99.99", 1},
+		{"1.0.0-alpha", "1.0.0", -1},
+		{"1.0.0-alpha", "1.0.0-alpha.1", -1},
+		{"1.0.0-alpha.2", "1.0.0-alpha.10", -1},
+		{"1.0.0-1", "1.0.0-alpha", -1},
+		{"1.0.0-beta", "1.0.0-alpha", 1},
+	}
+	for _, c := range cases {
+		a, err := parseSemver(c.a)
+		if err != nil {
+			t.Fatalf("%s: %s", c.a, err)
+		}
+		b, err := parseSemver(c.b)
+		if err != nil {
+			t.Fatalf("%s: %s", c.b, err)
+		}
+		if cmp := a.compare(b); cmp != c.expected {
+			t.Errorf("Expected %s vs %s to compare %d, got %d", c.a, c.b, c.expected, cmp)
+		}
+	}
+
+	for _, invalid := range []string{"", "1.2", "1.2.x", "a.b.c", "file:../shared"} {
+		if _, err := parseSemver(invalid); err == nil {
+			t.Errorf("Expected an error for %q", invalid)
+		}
+	}
+}
+
+func TestSemverRangeContains(t *testing.T) {
+	cases := []struct {
+		r       string
+		in, out []string
+	}{
+		{"^1.2.3", []string{"1.2.3", "1.9.0"}, []string{"1.2.2", "2.0.0", "2.0.0-0"}},
+		{"^0.2.3", []string{"0.2.3", "0.2.9"}, []string{"0.3.0"}},
+		{"^0.0.3", []string{"0.0.3"}, []string{"0.0.4"}},
+		{"^1.2", []string{"1.2.0", "1.9.9"}, []string{"1.1.9", "2.0.0"}},
+		{"~1.2.3", []string{"1.2.3", "1.2.9"}, []string{"1.3.0"}},
+		{"~1", []string{"1.0.0", "1.9.0"}, []string{"2.0.0"}},
+		{"1.x", []string{"1.0.0", "1.5.2"}, []string{"2.0.0", "0.9.0"}},
+		{"1.2.*", []string{"1.2.0", "1.2.7"}, []string{"1.3.0"}},
+		{"*", []string{"0.0.1", "99.0.0"}, []string{"1.0.0-beta"}},
+		{"", []string{"1.0.0"}, nil},
+		{">=1.0.0 <2.0.0", []string{"1.0.0", "1.9.9"}, []string{"0.9.9", "2.0.0"}},
+		{">= 1.0.0 < 2", []string{"1.5.0"}, []string{"2.0.0"}},
+		{"<1.2", []string{"1.1.9"}, []string{"1.2.0", "1.2.0-rc.1"}},
+		{"<=1.2", []string{"1.2.9"}, []string{"1.3.0"}},
+		{">1.2", []string{"1.3.0"}, []string{"1.2.9"}},
+		{"=1.2.3", []string{"1.2.3"}, []string{"1.2.4"}},
+		{"1.0.0 - 1.5", []string{"1.0.0", "1.5.9"}, []string{"1.6.0"}},
+		{"1.0.0 - 1.5.0", []string{"1.5.0"}, []string{"1.5.1"}},
+		{"^1.0.0 || ^3.0.0", []string{"1.1.0", "3.2.0"}, []string{"2.0.0"}},
+		{">=1.0.0-beta.2 <1.0.1", []string{"1.0.0-beta.3", "1.0.0"}, []string{"1.0.0-beta.1", "1.0.1-beta"}},
+	}
+	for _, c := range cases {
+		r, err := ParseSemverRange(c.r)
+		if err != nil {
+			t.Fatalf("%s: %s", c.r, err)
+		}
+		for _, v := range c.in {
+			if !r.Contains(v) {
+				t.Errorf("Expected %q to contain %s", c.r, v)
+			}
+		}
+		for _, v := range c.out {
+			if r.Contains(v) {
+				t.Errorf("Expected %q not to contain %s", c.r, v)
+			}
+		}
+	}
+
+	if _, err := ParseSemverRange("^a.b"); err == nil {
+		t.Error("Expected an error for an invalid range")
+	}
+}
@@ -0,0 +1,57 @@
+package nodejs
+
+import (
+	"archive/tar"
+	"bitbucket.org/bosgood/dep-get/lib/download"
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// packageJSONFileName is the manifest every npm package tarball contains
+const packageJSONFileName = "package.json"
+
+// ValidateTarball reads a whole package tarball, checking that it's
+// gzipped and that it contains a readable package.json. npm strips the
+// top level directory, which is "package" for tarballs from the registry
+// but is named after the repository for tarballs from git hosts.
+func ValidateTarball(r io.Reader) error {
+	br := bufio.NewReader(r)
+	if err := download.CheckMagic(br, "gzip", 0, download.GzipMagic); err != nil {
+		return err
+	}
+
+	gz, err := gzip.NewReader(br)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	foundPackageJSON := false
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("Unreadable tarball: %s", err)
+		}
+
+		parts := strings.Split(strings.TrimPrefix(header.Name, "./"), "/")
+		if len(parts) == 2 && parts[1] == packageJSONFileName {
+			if _, err = ioutil.ReadAll(tr); err != nil {
+				return fmt.Errorf("Unreadable %s: %s", header.Name, err)
+			}
+			foundPackageJSON = true
+		}
+	}
+
+	if !foundPackageJSON {
+		return fmt.Errorf("Tarball has no package/%s", packageJSONFileName)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package nodejs
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"testing"
+)
+
+func makeTarball(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, contents := range files {
+		err := tw.WriteHeader(&tar.Header{
+			Name: name,
+			Mode: 0644,
+			Size: int64(len(contents)),
+		})
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if _, err = tw.Write([]byte(contents)); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	return buf.Bytes()
+}
+
+func TestValidateTarball(t *testing.T) {
+	tarball := makeTarball(t, map[string]string{
+		"package/package.json": `{"name": "ee-first", "version": "1.1.1"}`,
+		"package/index.js":     "module.exports = first",
+	})
+	if err := ValidateTarball(bytes.NewReader(tarball)); err != nil {
+		t.Errorf("err: %s", err)
+	}
+
+	// Tarballs from git hosts are prefixed with the repository name
+	tarball = makeTarball(t, map[string]string{
+		"my-fork-0123456/package.json": `{"name": "my-fork"}`,
+	})
+	if err := ValidateTarball(bytes.NewReader(tarball)); err != nil {
+		t.Errorf("err: %s", err)
+	}
+}
+
+func TestValidateTarballInvalid(t *testing.T) {
+	if err := ValidateTarball(bytes.NewReader([]byte("<html>Not Found</html>"))); err == nil {
+		t.Errorf("Expected an error for an HTML page")
+	}
+
+	tarball := makeTarball(t, map[string]string{
+		"package/index.js": "module.exports = first",
+	})
+	if err := ValidateTarball(bytes.NewReader(tarball)); err == nil {
+		t.Errorf("Expected an error for a tarball without package.json")
+	}
+
+	tarball = makeTarball(t, map[string]string{
+		"package/package.json": `{"name": "ee-first", "version": "1.1.1"}`,
+	})
+	if err := ValidateTarball(bytes.NewReader(tarball[:len(tarball)/2])); err == nil {
+		t.Errorf("Expected an error for a truncated tarball")
+	}
+}
@@ -0,0 +1,290 @@
+package nodejs
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// YarnLockfileName is the name of the yarn dependencies lock file
+const YarnLockfileName = "yarn.lock"
+
+// YarnLockfile represents a yarn classic (v1) yarn.lock file
+type YarnLockfile struct {
+	Entries []*YarnLockEntry
+}
+
+// YarnLockEntry represents one resolved package from a yarn.lock file,
+// along with every specifier (name@range) that resolves to it
+type YarnLockEntry struct {
+	Specifiers           []string
+	Version              string
+	Resolved             string
+	Integrity            string
+	Dependencies         map[string]string
+	OptionalDependencies map[string]string
+}
+
+// Name returns the package name shared by the entry's specifiers
+func (e *YarnLockEntry) Name() string {
+	if len(e.Specifiers) == 0 {
+		return ""
+	}
+	return nameFromSpecifier(e.Specifiers[0])
+}
+
+// localPath returns the path of file: and link: entries, which yarn
+// records without a resolved URL, or of file: tarballs
+func (e *YarnLockEntry) localPath() (string, bool) {
+	if localPath, ok := LocalSpecPath(e.Resolved); ok {
+		return strings.SplitN(localPath, "#", 2)[0], true
+	}
+	if e.Resolved != "" {
+		return "", false
+	}
+	for _, specifier := range e.Specifiers {
+		name := nameFromSpecifier(specifier)
+		if localPath, ok := LocalSpecPath(strings.TrimPrefix(specifier, name+"@")); ok {
+			return localPath, true
+		}
+	}
+	return "", false
+}
+
+// nameFromSpecifier strips the range from a specifier such as
+// @babel/core@^7.0.0, taking care of the scope's leading @
+func nameFromSpecifier(specifier string) string {
+	if len(specifier) == 0 {
+		return ""
+	}
+	i := strings.Index(specifier[1:], "@")
+	if i < 0 {
+		return specifier
+	}
+	return specifier[:i+1]
+}
+
+// splitResolvedHash splits the #sha1 fragment yarn appends to
+// registry tarball URLs from the URL itself. The fragment of a git
+// repository URL is the commit to check out instead, so it's left in
+// place, and http(s) URLs of .git repositories get a git+ prefix so that
+// they're cloned rather than downloaded.
+func splitResolvedHash(resolved string) (string, string) {
+	if _, ok := ParseGitURL(resolved); ok {
+		return resolved, ""
+	}
+	urlObj, err := url.Parse(resolved)
+	if err != nil || (urlObj.Scheme != "http" && urlObj.Scheme != "https") {
+		return resolved, ""
+	}
+	if strings.HasSuffix(urlObj.Path, ".git") {
+		return "git+" + resolved, ""
+	}
+	if !isSHA1Hex(urlObj.Fragment) {
+		return resolved, ""
+	}
+	return resolved[:strings.LastIndex(resolved, "#")], urlObj.Fragment
+}
+
+// isSHA1Hex reports whether s is a hex encoded SHA-1 sum
+func isSHA1Hex(s string) bool {
+	if len(s) != 40 {
+		return false
+	}
+	for _, c := range s {
+		if !strings.ContainsRune("0123456789abcdef", c) {
+			return false
+		}
+	}
+	return true
+}
+
+// readYarnToken reads one possibly quoted string from the start of s,
+// returning it along with the rest of s
+func readYarnToken(s string) (string, string, error) {
+	if strings.HasPrefix(s, `"`) {
+		for i := 1; i < len(s); i++ {
+			switch s[i] {
+			case '\\':
+				i++
+			case '"':
+				token, err := strconv.Unquote(s[:i+1])
+				return token, s[i+1:], err
+			}
+		}
+		return "", "", fmt.Errorf("Unterminated string: %s", s)
+	}
+	i := strings.IndexAny(s, " ,")
+	if i < 0 {
+		return s, "", nil
+	}
+	return s[:i], s[i:], nil
+}
+
+func parseYarnHeader(line string) ([]string, error) {
+	if !strings.HasSuffix(line, ":") {
+		return nil, fmt.Errorf("Expected a package header: %s", line)
+	}
+	rest := strings.TrimSuffix(line, ":")
+
+	var specifiers []string
+	for rest != "" {
+		specifier, tail, err := readYarnToken(rest)
+		if err != nil {
+			return nil, err
+		}
+		if specifier == "" {
+			return nil, fmt.Errorf("Empty specifier in package header: %s", line)
+		}
+		specifiers = append(specifiers, specifier)
+		rest = strings.TrimPrefix(strings.TrimSpace(tail), ",")
+		rest = strings.TrimSpace(rest)
+	}
+	return specifiers, nil
+}
+
+func parseYarnField(line string) (string, string, bool, error) {
+	key, rest, err := readYarnToken(line)
+	if err != nil {
+		return "", "", false, err
+	}
+	if rest == ":" || (rest == "" && strings.HasSuffix(key, ":")) {
+		return strings.TrimSuffix(key, ":"), "", true, nil
+	}
+	value, _, err := readYarnToken(strings.TrimSpace(rest))
+	if err != nil {
+		return "", "", false, err
+	}
+	return key, value, false, nil
+}
+
+// ParseYarnLockfile decodes a yarn classic (v1) yarn.lock file
+func ParseYarnLockfile(contents []byte) (YarnLockfile, error) {
+	var lockfile YarnLockfile
+	var entry *YarnLockEntry
+	var block map[string]string
+
+	scanner := bufio.NewScanner(bytes.NewReader(contents))
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimRight(scanner.Text(), " \r")
+		trimmed := strings.TrimLeft(line, " ")
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		var err error
+		switch indent := len(line) - len(trimmed); indent {
+		case 0:
+			entry = &YarnLockEntry{}
+			block = nil
+			entry.Specifiers, err = parseYarnHeader(trimmed)
+			lockfile.Entries = append(lockfile.Entries, entry)
+		case 2:
+			if entry == nil {
+				err = fmt.Errorf("Field outside of a package: %s", trimmed)
+				break
+			}
+			var key, value string
+			var isBlock bool
+			key, value, isBlock, err = parseYarnField(trimmed)
+			if err != nil {
+				break
+			}
+			block = nil
+			if isBlock {
+				block = make(map[string]string)
+				switch key {
+				case "dependencies":
+					entry.Dependencies = block
+				case "optionalDependencies":
+					entry.OptionalDependencies = block
+				}
+				break
+			}
+			switch key {
+			case "version":
+				entry.Version = value
+			case "resolved":
+				entry.Resolved = value
+			case "integrity":
+				entry.Integrity = value
+			}
+		case 4:
+			if block == nil {
+				err = fmt.Errorf("Nested field outside of a block: %s", trimmed)
+				break
+			}
+			var key, value string
+			key, value, _, err = parseYarnField(trimmed)
+			if err != nil {
+				break
+			}
+			block[key] = value
+		default:
+			err = fmt.Errorf("Unexpected indentation of %d", indent)
+		}
+
+		if err != nil {
+			return lockfile, fmt.Errorf("%s line %d: %s", YarnLockfileName, lineNum, err)
+		}
+	}
+
+	return lockfile, scanner.Err()
+}
+
+// YarnDependencyGraph builds the dependency graph of a yarn.lock file,
+// with nodes ordered and keyed by their specifiers. Since yarn.lock
+// doesn't record the project's own dependencies, the root depends on
+// every package nothing else reaches until LinkProject links it to those
+// of package.json, which tells dev and optional packages apart.
+func YarnDependencyGraph(lockfile YarnLockfile) *DependencyGraph {
+	g := NewDependencyGraph("", "")
+	g.flagsFromEdges = true
+	bySpecifier := make(map[string]*DependencyNode)
+	for _, entry := range lockfile.Entries {
+		packageURL, shasum := splitResolvedHash(entry.Resolved)
+		dep := NodeDependency{
+			Name:       entry.Name(),
+			Version:    entry.Version,
+			PackageURL: packageURL,
+			Integrity:  entry.Integrity,
+			Shasum:     shasum,
+		}
+		if localPath, ok := entry.localPath(); ok {
+			dep.PackageURL = ""
+			dep.Shasum = ""
+			dep.LocalPath = localPath
+		}
+		node := g.Add(dep, strings.Join(entry.Specifiers, ", "))
+		for _, specifier := range entry.Specifiers {
+			bySpecifier[specifier] = node
+		}
+	}
+
+	for i, entry := range lockfile.Entries {
+		deps := entry.Dependencies
+		for _, name := range sortedKeys(deps) {
+			g.Link(g.Nodes[i], bySpecifier[name+"@"+deps[name]])
+		}
+		deps = entry.OptionalDependencies
+		for _, name := range sortedKeys(deps) {
+			g.linkAs(g.Nodes[i], bySpecifier[name+"@"+deps[name]], g.optionalEdges)
+		}
+	}
+	g.resolveProject = func(name, spec string) *DependencyNode {
+		return bySpecifier[name+"@"+spec]
+	}
+	g.finish()
+	return g
+}
+
+// CollectYarnDependencies lists the dependencies of a yarn.lock file
+// ordered by specifiers
+func CollectYarnDependencies(lockfile YarnLockfile) []NodeDependency {
+	return YarnDependencyGraph(lockfile).Dependencies()
+}
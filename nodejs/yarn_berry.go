@@ -0,0 +1,230 @@
+package nodejs
+
+import (
+	"bufio"
+	"bytes"
+	"gopkg.in/yaml.v2"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+)
+
+// yarnBerryMetadataKey marks a yarn.lock file written by yarn 2 or later
+const yarnBerryMetadataKey = "__metadata"
+
+// YarnBerryLockfile represents a yarn berry (v2+) yarn.lock file,
+// which is YAML keyed by comma-separated package descriptors
+type YarnBerryLockfile struct {
+	Metadata YarnBerryMetadata
+	Packages map[string]*YarnBerryPackage
+}
+
+// YarnBerryMetadata represents the __metadata block of a yarn berry lockfile
+type YarnBerryMetadata struct {
+	Version  string `yaml:"version"`
+	CacheKey string `yaml:"cacheKey"`
+}
+
+// YarnBerryPackage represents one resolved package from a yarn berry
+// lockfile. Checksum is the hash of yarn's zip cache archive rather
+// than of the registry tarball.
+type YarnBerryPackage struct {
+	Version          string                             `yaml:"version"`
+	Resolution       string                             `yaml:"resolution"`
+	Checksum         string                             `yaml:"checksum"`
+	Dependencies     map[string]string                  `yaml:"dependencies"`
+	DependenciesMeta map[string]YarnBerryDependencyMeta `yaml:"dependenciesMeta"`
+	LanguageName     string                             `yaml:"languageName"`
+	LinkType         string                             `yaml:"linkType"`
+}
+
+// YarnBerryDependencyMeta represents the settings of one dependency of a
+// yarn berry package, which mark its optional dependencies
+type YarnBerryDependencyMeta struct {
+	Optional bool `yaml:"optional"`
+}
+
+// IsYarnBerryLockfile reports whether a yarn.lock file was written
+// by yarn berry rather than yarn classic
+func IsYarnBerryLockfile(contents []byte) bool {
+	scanner := bufio.NewScanner(bytes.NewReader(contents))
+	for scanner.Scan() {
+		if strings.HasPrefix(scanner.Text(), yarnBerryMetadataKey+":") {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseYarnBerryLockfile decodes a yarn berry (v2+) yarn.lock file
+func ParseYarnBerryLockfile(contents []byte) (YarnBerryLockfile, error) {
+	var lockfile YarnBerryLockfile
+
+	var metadata struct {
+		Metadata YarnBerryMetadata `yaml:"__metadata"`
+	}
+	if err := yaml.Unmarshal(contents, &metadata); err != nil {
+		return lockfile, err
+	}
+	lockfile.Metadata = metadata.Metadata
+
+	if err := yaml.Unmarshal(contents, &lockfile.Packages); err != nil {
+		return lockfile, err
+	}
+	delete(lockfile.Packages, yarnBerryMetadataKey)
+
+	return lockfile, nil
+}
+
+// parseYarnBerryResolution splits a resolution such as @babel/core@npm:7.0.0
+// into the package name, protocol and protocol-specific reference
+func parseYarnBerryResolution(resolution string) (string, string, string) {
+	if resolution == "" {
+		return "", "", ""
+	}
+	i := strings.Index(resolution[1:], "@")
+	if i < 0 {
+		return resolution, "", ""
+	}
+	name := resolution[:i+1]
+	reference := resolution[i+2:]
+
+	j := strings.Index(reference, ":")
+	if j < 0 {
+		return name, "", reference
+	}
+	return name, reference[:j], reference[j+1:]
+}
+
+func yarnBerryPackageURL(protocol, reference string) (string, bool) {
+	switch protocol {
+	case "http", "https", "git", "git+https", "git+ssh", "ssh":
+		// Git references are pinned with #commit=<sha>
+		url := protocol + ":" + strings.Replace(reference, "#commit=", "#", 1)
+		return url, true
+	}
+	// workspace:, patch:, portal:, link: and file: resolutions
+	// can't be downloaded from anywhere
+	return "", false
+}
+
+// yarnBerryLocalPath returns the project relative path of workspace:,
+// portal:, link: and file: resolutions. References other than
+// workspaces are relative to the workspace named by their locator, as
+// in file:../shared::locator=app%40workspace%3Apackages%2Fapp.
+func yarnBerryLocalPath(protocol, reference string) (string, bool) {
+	switch protocol {
+	case "workspace":
+		return reference, true
+	case "portal", "link", "file":
+	default:
+		return "", false
+	}
+
+	localPath := reference
+	var params string
+	if i := strings.Index(localPath, "::"); i >= 0 {
+		localPath, params = localPath[:i], localPath[i+2:]
+	}
+	localPath = strings.SplitN(localPath, "#", 2)[0]
+
+	values, _ := url.ParseQuery(params)
+	if locator := values.Get("locator"); locator != "" {
+		_, locatorProtocol, workspace := parseYarnBerryResolution(locator)
+		if locatorProtocol == "workspace" {
+			localPath = path.Join(workspace, localPath)
+		}
+	}
+	return localPath, true
+}
+
+// yarnBerryDescriptorNode finds the package a dependency block entry
+// resolves to. Ranges without a protocol are npm: ranges.
+func yarnBerryDescriptorNode(byDescriptor map[string]*DependencyNode, name, descriptorRange string) *DependencyNode {
+	if node := byDescriptor[name+"@"+descriptorRange]; node != nil {
+		return node
+	}
+	return byDescriptor[name+"@npm:"+descriptorRange]
+}
+
+// YarnBerryDependencyGraph builds the dependency graph of a yarn berry
+// lockfile, with nodes ordered and keyed by descriptor. The root
+// workspace is the graph's root, and registry tarball URLs are
+// reconstructed from npm: resolutions. The lockfile merges the root's
+// dev dependencies into its dependencies, so dev and optional packages
+// are only told apart once LinkProject has read package.json.
+func YarnBerryDependencyGraph(lockfile YarnBerryLockfile) *DependencyGraph {
+	var descriptors []string
+	for key := range lockfile.Packages {
+		descriptors = append(descriptors, key)
+	}
+	sort.Strings(descriptors)
+
+	g := NewDependencyGraph("", "")
+	g.flagsFromEdges = true
+	byDescriptor := make(map[string]*DependencyNode)
+	var linked []string
+	for _, key := range descriptors {
+		pkg := lockfile.Packages[key]
+		name, protocol, reference := parseYarnBerryResolution(pkg.Resolution)
+
+		var packageURL, localPath string
+		if protocol == "npm" {
+			packageURL = RegistryTarballURL(DefaultRegistryURL, name, reference)
+		} else {
+			var ok bool
+			packageURL, ok = yarnBerryPackageURL(protocol, reference)
+			if !ok {
+				localPath, ok = yarnBerryLocalPath(protocol, reference)
+			}
+			// patch: resolutions can't be fetched from anywhere
+			if !ok {
+				continue
+			}
+		}
+
+		var node *DependencyNode
+		if localPath == "." {
+			// The root workspace is the project itself
+			node = g.Root
+			node.Name, node.Version = name, pkg.Version
+		} else {
+			node = g.Add(NodeDependency{
+				Name:       name,
+				Version:    pkg.Version,
+				PackageURL: packageURL,
+				LocalPath:  localPath,
+			}, key)
+		}
+		for _, descriptor := range strings.Split(key, ", ") {
+			byDescriptor[descriptor] = node
+		}
+		linked = append(linked, key)
+	}
+
+	for _, key := range linked {
+		node := byDescriptor[strings.Split(key, ", ")[0]]
+		pkg := lockfile.Packages[key]
+		for _, name := range sortedKeys(pkg.Dependencies) {
+			child := yarnBerryDescriptorNode(byDescriptor, name, pkg.Dependencies[name])
+			if pkg.DependenciesMeta[name].Optional {
+				g.linkAs(node, child, g.optionalEdges)
+			} else {
+				g.Link(node, child)
+			}
+		}
+	}
+	g.resolveProject = func(name, spec string) *DependencyNode {
+		return yarnBerryDescriptorNode(byDescriptor, name, spec)
+	}
+	g.finish()
+	return g
+}
+
+// CollectYarnBerryDependencies lists the dependencies of a yarn berry
+// lockfile ordered by descriptor, reconstructing registry tarball URLs
+// from npm: resolutions
+func CollectYarnBerryDependencies(lockfile YarnBerryLockfile) []NodeDependency {
+	return YarnBerryDependencyGraph(lockfile).Dependencies()
+}
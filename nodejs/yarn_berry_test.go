@@ -0,0 +1,167 @@
+package nodejs
+
+import (
+	"testing"
+)
+
+var yarnBerryLock = []byte(`# This file is generated by running "yarn install" inside your project.
+# Manual changes might be lost - proceed with caution!
+
+__metadata:
+  version: 6
+  cacheKey: 8
+
+"@babel/code-frame@npm:^7.0.0, @babel/code-frame@npm:^7.10.4":
+  version: 7.10.4
+  resolution: "@babel/code-frame@npm:7.10.4"
+  dependencies:
+    "@babel/highlight": ^7.10.4
+  checksum: feb4543c8a509fe30f0f6e8d7aa84f82b41148b963b826cd330e34986f649a85cb63b2f13dd4effdf434ac555d16f14940b8ea5f4433297c2f5ff85486ded019
+  languageName: node
+  linkType: hard
+
+"ee-first@npm:1.1.1":
+  version: 1.1.1
+  resolution: "ee-first@npm:1.1.1"
+  checksum: 1b4cac778d64ce3b582a7e26b218afe07e207a0f9bfe13cc7395a6d307849cfe361e65033c3251e00c27dd060cab43014c2d6b2647676135e18b77d2d05b3f4f
+  languageName: node
+  linkType: hard
+
+"ee-first-alias@npm:ee-first@1.1.1":
+  version: 1.1.1
+  resolution: "ee-first@npm:1.1.1"
+  languageName: node
+  linkType: hard
+
+"my-app@workspace:.":
+  version: 0.0.0-use.local
+  resolution: "my-app@workspace:."
+  languageName: unknown
+  linkType: soft
+
+"my-fork@https://github.com/bosgood/my-fork.git#v1.0.0":
+  version: 1.0.0
+  resolution: "my-fork@https://github.com/bosgood/my-fork.git#commit=0123456789abcdef0123456789abcdef01234567"
+  languageName: node
+  linkType: hard
+
+"resolve@patch:resolve@^1.20.0#~builtin<compat/resolve>":
+  version: 1.22.1
+  resolution: "resolve@patch:resolve@npm%3A1.22.1#~builtin<compat/resolve>::version=1.22.1&hash=07638b"
+  languageName: node
+  linkType: hard
+`)
+
+func TestIsYarnBerryLockfile(t *testing.T) {
+	if !IsYarnBerryLockfile(yarnBerryLock) {
+		t.Errorf("Expected yarn berry lockfile to be detected")
+	}
+	if IsYarnBerryLockfile(yarnLockV1) {
+		t.Errorf("Expected yarn classic lockfile not to be detected")
+	}
+}
+
+func TestParseYarnBerryLockfile(t *testing.T) {
+	lockfile, err := ParseYarnBerryLockfile(yarnBerryLock)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if lockfile.Metadata.Version != "6" {
+		t.Errorf("Unexpected metadata version: %s", lockfile.Metadata.Version)
+	}
+	if len(lockfile.Packages) != 6 {
+		t.Fatalf("Expected six packages, found %d", len(lockfile.Packages))
+	}
+
+	pkg := lockfile.Packages["@babel/code-frame@npm:^7.0.0, @babel/code-frame@npm:^7.10.4"]
+	if pkg == nil {
+		t.Fatalf("Expected @babel/code-frame package")
+	}
+	if pkg.Version != "7.10.4" || pkg.Resolution != "@babel/code-frame@npm:7.10.4" {
+		t.Errorf("Unexpected package: %+v", pkg)
+	}
+	if pkg.Dependencies["@babel/highlight"] != "^7.10.4" {
+		t.Errorf("Expected @babel/highlight dependency")
+	}
+}
+
+func TestCollectYarnBerryDependencies(t *testing.T) {
+	deps, err := ParseDependencies(YarnLockfileName, yarnBerryLock)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := []NodeDependency{
+		{
+			Name:       "@babel/code-frame",
+			Version:    "7.10.4",
+			PackageURL: "https://registry.npmjs.org/@babel/code-frame/-/code-frame-7.10.4.tgz",
+		},
+		{
+			Name:       "ee-first",
+			Version:    "1.1.1",
+			PackageURL: "https://registry.npmjs.org/ee-first/-/ee-first-1.1.1.tgz",
+		},
+		{
+			Name:       "my-fork",
+			Version:    "1.0.0",
+			PackageURL: "https://github.com/bosgood/my-fork.git#0123456789abcdef0123456789abcdef01234567",
+		},
+	}
+	if len(deps) != len(expected) {
+		t.Fatalf("Expected %d dependencies, found %d: %+v", len(expected), len(deps), deps)
+	}
+	for i, dep := range expected {
+		if deps[i] != dep {
+			t.Errorf("Expected %+v at %d, got %+v", dep, i, deps[i])
+		}
+	}
+}
+
+func TestCollectYarnBerryDependenciesLocal(t *testing.T) {
+	lockfile, err := ParseYarnBerryLockfile([]byte(`__metadata:
+  version: 6
+
+"app@workspace:packages/app":
+  version: 1.0.0
+  resolution: "app@workspace:packages/app"
+  languageName: unknown
+  linkType: soft
+
+"monorepo@workspace:.":
+  version: 0.0.0-use.local
+  resolution: "monorepo@workspace:."
+  languageName: unknown
+  linkType: soft
+
+"shared@file:../shared::locator=app%40workspace%3Apackages%2Fapp":
+  version: 2.0.0
+  resolution: "shared@file:../shared#../shared::hash=0a1b2c&locator=app%40workspace%3Apackages%2Fapp"
+  languageName: node
+  linkType: hard
+
+"tools@link:../tools::locator=monorepo%40workspace%3A.":
+  version: 0.0.0-use.local
+  resolution: "tools@link:../tools::locator=monorepo%40workspace%3A."
+  languageName: node
+  linkType: soft
+`))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	deps := CollectYarnBerryDependencies(lockfile)
+	expected := []NodeDependency{
+		{Name: "app", Version: "1.0.0", LocalPath: "packages/app"},
+		{Name: "shared", Version: "2.0.0", LocalPath: "packages/shared"},
+		{Name: "tools", Version: "0.0.0-use.local", LocalPath: "../tools"},
+	}
+	if len(deps) != len(expected) {
+		t.Fatalf("Expected %d dependencies, found %d: %+v", len(expected), len(deps), deps)
+	}
+	for i, dep := range expected {
+		if deps[i] != dep {
+			t.Errorf("Expected %+v at %d, got %+v", dep, i, deps[i])
+		}
+	}
+}
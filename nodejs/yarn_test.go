@@ -0,0 +1,205 @@
+package nodejs
+
+import (
+	"strings"
+	"testing"
+)
+
+var yarnLockV1 = []byte(`# THIS IS AN AUTOGENERATED FILE. DO NOT EDIT THIS FILE DIRECTLY.
+# yarn lockfile v1
+
+
+"@babel/code-frame@^7.0.0", "@babel/code-frame@^7.10.4":
+  version "7.10.4"
+  resolved "https://registry.yarnpkg.com/@babel/code-frame/-/code-frame-7.10.4.tgz#168da1a36e90da68ae8d49c0f1b48c7c6249213a"
+  integrity sha512-vG6SvB6oYEhvgisZNFRmRCUkLz11c7rp+tbNTynGqc6mS1d5ATd/sGyV6W0KZZnXRKMTzZDRgQT3Ou9jhpAfUg==
+  dependencies:
+    "@babel/highlight" "^7.10.4"
+
+ee-first@1.1.1:
+  version "1.1.1"
+  resolved "https://registry.yarnpkg.com/ee-first/-/ee-first-1.1.1.tgz#590c61156b0ae2f4f0255732a158b266bc56b21d"
+
+on-finished@^2.3.0, on-finished@~2.3.0:
+  version "2.3.0"
+  resolved "https://registry.yarnpkg.com/on-finished/-/on-finished-2.3.0.tgz#20f1336481b083cd75337992a16971aa2d906947"
+  dependencies:
+    ee-first "1.1.1"
+  optionalDependencies:
+    fsevents "^1.2.7"
+
+"my-fork@git+https://github.com/bosgood/my-fork.git#v1.0.0":
+  version "1.0.0"
+  resolved "git+https://github.com/bosgood/my-fork.git#0123456789abcdef0123456789abcdef01234567"
+`)
+
+func TestParseYarnLockfile(t *testing.T) {
+	lockfile, err := ParseYarnLockfile(yarnLockV1)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(lockfile.Entries) != 4 {
+		t.Fatalf("Expected four entries, found %d", len(lockfile.Entries))
+	}
+
+	entry := lockfile.Entries[0]
+	if len(entry.Specifiers) != 2 ||
+		entry.Specifiers[0] != "@babel/code-frame@^7.0.0" ||
+		entry.Specifiers[1] != "@babel/code-frame@^7.10.4" {
+		t.Errorf("Unexpected specifiers: %v", entry.Specifiers)
+	}
+	if entry.Name() != "@babel/code-frame" {
+		t.Errorf("Unexpected name: %s", entry.Name())
+	}
+	if entry.Integrity != "sha512-vG6SvB6oYEhvgisZNFRmRCUkLz11c7rp+tbNTynGqc6mS1d5ATd/sGyV6W0KZZnXRKMTzZDRgQT3Ou9jhpAfUg==" {
+		t.Errorf("Unexpected integrity: %s", entry.Integrity)
+	}
+	if entry.Dependencies["@babel/highlight"] != "^7.10.4" {
+		t.Errorf("Expected @babel/highlight dependency")
+	}
+
+	entry = lockfile.Entries[2]
+	if len(entry.Specifiers) != 2 || entry.Name() != "on-finished" {
+		t.Errorf("Unexpected specifiers: %v", entry.Specifiers)
+	}
+	if entry.Dependencies["ee-first"] != "1.1.1" {
+		t.Errorf("Expected ee-first dependency")
+	}
+	if entry.OptionalDependencies["fsevents"] != "^1.2.7" {
+		t.Errorf("Expected fsevents optional dependency")
+	}
+}
+
+func TestParseYarnLockfileMalformed(t *testing.T) {
+	_, err := ParseYarnLockfile([]byte("  version \"1.0.0\"\n"))
+	if err == nil {
+		t.Errorf("Expected an error for a field outside of a package")
+	}
+
+	_, err = ParseYarnLockfile([]byte("\"ee-first@1.1.1:\n"))
+	if err == nil {
+		t.Errorf("Expected an error for an unterminated string")
+	}
+
+	for _, contents := range []string{
+		"\"\":\n  version \"1.0.0\"\n",
+		"ee-first@1.1.1, , on-finished@~2.3.0:\n  version \"1.1.1\"\n",
+	} {
+		_, err = ParseYarnLockfile([]byte(contents))
+		if err == nil || !strings.Contains(err.Error(), "Empty specifier") {
+			t.Errorf("Expected an error for an empty specifier, got %v", err)
+		}
+	}
+
+	entry := &YarnLockEntry{Specifiers: []string{""}}
+	if entry.Name() != "" {
+		t.Errorf("Expected no name for an empty specifier")
+	}
+}
+
+func TestSplitResolvedHash(t *testing.T) {
+	cases := []struct {
+		resolved   string
+		packageURL string
+		shasum     string
+	}{
+		{
+			"https://registry.yarnpkg.com/ee-first/-/ee-first-1.1.1.tgz#590c61156b0ae2f4f0255732a158b266bc56b21d",
+			"https://registry.yarnpkg.com/ee-first/-/ee-first-1.1.1.tgz",
+			"590c61156b0ae2f4f0255732a158b266bc56b21d",
+		},
+		{
+			"https://github.com/bosgood/my-fork.git#0123456789abcdef0123456789abcdef01234567",
+			"git+https://github.com/bosgood/my-fork.git#0123456789abcdef0123456789abcdef01234567",
+			"",
+		},
+		{
+			"git+https://github.com/bosgood/my-fork.git#0123456789abcdef0123456789abcdef01234567",
+			"git+https://github.com/bosgood/my-fork.git#0123456789abcdef0123456789abcdef01234567",
+			"",
+		},
+		{
+			"https://npm.ourco.com/pkg/-/pkg-1.0.0.tgz#v1.0.0",
+			"https://npm.ourco.com/pkg/-/pkg-1.0.0.tgz#v1.0.0",
+			"",
+		},
+	}
+	for _, c := range cases {
+		packageURL, shasum := splitResolvedHash(c.resolved)
+		if packageURL != c.packageURL || shasum != c.shasum {
+			t.Errorf("Expected %s and %q for %s, got %s and %q", c.packageURL, c.shasum, c.resolved, packageURL, shasum)
+		}
+	}
+}
+
+func TestCollectYarnDependencies(t *testing.T) {
+	deps, err := ParseDependencies(YarnLockfileName, yarnLockV1)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := []NodeDependency{
+		{
+			Name:       "@babel/code-frame",
+			Version:    "7.10.4",
+			PackageURL: "https://registry.yarnpkg.com/@babel/code-frame/-/code-frame-7.10.4.tgz",
+			Integrity:  "sha512-vG6SvB6oYEhvgisZNFRmRCUkLz11c7rp+tbNTynGqc6mS1d5ATd/sGyV6W0KZZnXRKMTzZDRgQT3Ou9jhpAfUg==",
+			Shasum:     "168da1a36e90da68ae8d49c0f1b48c7c6249213a",
+		},
+		{
+			Name:       "ee-first",
+			Version:    "1.1.1",
+			PackageURL: "https://registry.yarnpkg.com/ee-first/-/ee-first-1.1.1.tgz",
+			Shasum:     "590c61156b0ae2f4f0255732a158b266bc56b21d",
+		},
+		{
+			Name:       "my-fork",
+			Version:    "1.0.0",
+			PackageURL: "git+https://github.com/bosgood/my-fork.git#0123456789abcdef0123456789abcdef01234567",
+		},
+		{
+			Name:       "on-finished",
+			Version:    "2.3.0",
+			PackageURL: "https://registry.yarnpkg.com/on-finished/-/on-finished-2.3.0.tgz",
+			Shasum:     "20f1336481b083cd75337992a16971aa2d906947",
+		},
+	}
+	if len(deps) != len(expected) {
+		t.Fatalf("Expected %d dependencies, found %d", len(expected), len(deps))
+	}
+	for i, dep := range expected {
+		if deps[i] != dep {
+			t.Errorf("Expected %+v at %d, got %+v", dep, i, deps[i])
+		}
+	}
+}
+
+func TestCollectYarnDependenciesLocal(t *testing.T) {
+	lockfile, err := ParseYarnLockfile([]byte(`# yarn lockfile v1
+
+
+"shared@file:../shared":
+  version "2.0.0"
+
+"vendored@file:vendor/vendored-0.1.0.tgz":
+  version "0.1.0"
+  resolved "file:vendor/vendored-0.1.0.tgz#0123456789abcdef0123456789abcdef01234567"
+`))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	deps := CollectYarnDependencies(lockfile)
+	expected := []NodeDependency{
+		{Name: "shared", Version: "2.0.0", LocalPath: "../shared"},
+		{Name: "vendored", Version: "0.1.0", LocalPath: "vendor/vendored-0.1.0.tgz"},
+	}
+	if len(deps) != len(expected) {
+		t.Fatalf("Expected %d dependencies, found %d: %+v", len(expected), len(deps), deps)
+	}
+	for i, dep := range expected {
+		if deps[i] != dep {
+			t.Errorf("Expected %+v at %d, got %+v", dep, i, deps[i])
+		}
+	}
+}
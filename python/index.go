@@ -0,0 +1,347 @@
+package python
+
+import (
+	"bitbucket.org/bosgood/dep-get/lib/download"
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// DefaultIndexURL is PyPI's PEP 503 simple repository
+const DefaultIndexURL = "https://pypi.org/simple/"
+
+// sdistExtensions are the archive extensions of source distributions,
+// in the order they're preferred
+var sdistExtensions = []string{".tar.gz", ".zip", ".tar.bz2", ".tar.xz", ".tgz"}
+
+var (
+	anchorPattern    = regexp.MustCompile(`(?is)<a\s([^>]*)>(.*?)</a>`)
+	attributePattern = regexp.MustCompile(`([A-Za-z_:][-A-Za-z0-9_:.]*)(?:\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+)))?`)
+	basePattern      = regexp.MustCompile(`(?is)<base\s([^>]*)>`)
+)
+
+// DistributionFile is one file of a project listed by a simple index
+type DistributionFile struct {
+	Filename string
+	// URL is where to download the file, without the fragment holding
+	// its hash
+	URL string
+	// Hashes are the digests the index gives for the file, written
+	// ALGORITHM:HEX
+	Hashes         []string
+	RequiresPython string
+	Yanked         bool
+}
+
+// ProjectURL returns the URL of a project's page in a simple index
+func ProjectURL(indexURL, name string) string {
+	return strings.TrimSuffix(indexURL, "/") + "/" + NormalizeName(name) + "/"
+}
+
+// parseAttributes parses the attributes of an HTML tag
+func parseAttributes(tag string) map[string]string {
+	attributes := make(map[string]string)
+	for _, m := range attributePattern.FindAllStringSubmatch(tag, -1) {
+		attributes[strings.ToLower(m[1])] = html.UnescapeString(m[2] + m[3] + m[4])
+	}
+	return attributes
+}
+
+// ParseProjectPage lists the files linked from a project's page in a
+// PEP 503 simple index, resolving their URLs against the page's
+func ParseProjectPage(contents []byte, pageURL string) ([]DistributionFile, error) {
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return nil, err
+	}
+	if m := basePattern.FindSubmatch(contents); m != nil {
+		if href, ok := parseAttributes(string(m[1]))["href"]; ok {
+			if baseURL, err := base.Parse(href); err == nil {
+				base = baseURL
+			}
+		}
+	}
+
+	var files []DistributionFile
+	for _, m := range anchorPattern.FindAllSubmatch(contents, -1) {
+		attributes := parseAttributes(string(m[1]))
+		href, ok := attributes["href"]
+		if !ok {
+			continue
+		}
+		fileURL, err := base.Parse(href)
+		if err != nil {
+			return nil, fmt.Errorf("Malformed link %s in %s", href, pageURL)
+		}
+
+		file := DistributionFile{
+			Filename:       strings.TrimSpace(html.UnescapeString(string(m[2]))),
+			RequiresPython: attributes["data-requires-python"],
+		}
+		_, file.Yanked = attributes["data-yanked"]
+		if fileURL.Fragment != "" {
+			if i := strings.Index(fileURL.Fragment, "="); i > 0 {
+				file.Hashes = append(file.Hashes, fileURL.Fragment[:i]+":"+fileURL.Fragment[i+1:])
+			}
+			fileURL.Fragment = ""
+		}
+		file.URL = fileURL.String()
+		if file.Filename == "" {
+			file.Filename = FileNameFromURL(file.URL)
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// ParseDistributionFileName parses the name of a wheel or source archive
+// such as requests-2.31.0-py3-none-any.whl or requests-2.31.0.tar.gz into
+// the project's name and version. Since project names in source archives
+// may hold dashes, the name expected, when known, picks which dash ends it.
+func ParseDistributionFileName(fileName, name string) (string, string, bool) {
+	if strings.HasSuffix(fileName, ".whl") {
+		parts := strings.Split(strings.TrimSuffix(fileName, ".whl"), "-")
+		if len(parts) != 5 && len(parts) != 6 {
+			return "", "", false
+		}
+		if name != "" && NormalizeName(parts[0]) != NormalizeName(name) {
+			return "", "", false
+		}
+		return parts[0], parts[1], true
+	}
+
+	stem := ""
+	for _, ext := range sdistExtensions {
+		if strings.HasSuffix(fileName, ext) {
+			stem = strings.TrimSuffix(fileName, ext)
+			break
+		}
+	}
+	if stem == "" {
+		return "", "", false
+	}
+	if name != "" {
+		for i := len(stem) - 1; i > 0; i-- {
+			if stem[i] == '-' && NormalizeName(stem[:i]) == NormalizeName(name) {
+				return stem[:i], stem[i+1:], true
+			}
+		}
+		return "", "", false
+	}
+	// Versions start with a number, which project names rarely do after
+	// a dash
+	for i := len(stem) - 1; i > 0; i-- {
+		if stem[i] == '-' && i+1 < len(stem) && stem[i+1] >= '0' && stem[i+1] <= '9' {
+			return stem[:i], stem[i+1:], true
+		}
+	}
+	return "", "", false
+}
+
+// archiveMagic gives the bytes archives of each distribution extension
+// start with
+var archiveMagic = []struct {
+	extension string
+	format    string
+	magic     string
+}{
+	{".whl", "zip", "PK\x03\x04"},
+	{".zip", "zip", "PK\x03\x04"},
+	{".tar.gz", "gzip", download.GzipMagic},
+	{".tgz", "gzip", download.GzipMagic},
+	{".tar.bz2", "bzip2", "BZh"},
+	{".tar.xz", "xz", "\xfd7zXZ\x00"},
+}
+
+// ValidateDistribution checks that r is in the archive format the
+// extension of the distribution file fileName names: wheels are zip
+// files, and sdists are tarballs compressed in one of several ways
+func ValidateDistribution(fileName string, r io.Reader) error {
+	for _, archive := range archiveMagic {
+		if strings.HasSuffix(fileName, archive.extension) {
+			return download.CheckMagic(bufio.NewReader(r), archive.format, 0, archive.magic)
+		}
+	}
+	return nil
+}
+
+// IsPureWheel reports whether a wheel installs on any platform and
+// interpreter, as ones tagged none-any do
+func IsPureWheel(fileName string) bool {
+	return strings.HasSuffix(fileName, "-none-any.whl")
+}
+
+// fileRank orders the files of a release by preference: pure wheels, then
+// source archives by extension. Platform wheels can't be chosen without
+// knowing the target platform.
+func fileRank(fileName string) int {
+	if IsPureWheel(fileName) {
+		return 0
+	}
+	for i, ext := range sdistExtensions {
+		if strings.HasSuffix(fileName, ext) {
+			return i + 1
+		}
+	}
+	return -1
+}
+
+// sharesHash reports whether any of the hashes matches one of pinned
+func sharesHash(hashes, pinned []string) bool {
+	for _, hash := range hashes {
+		for _, pin := range pinned {
+			if strings.EqualFold(hash, pin) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// releaseFiles returns the files of dep's pinned version which, when dep
+// has hashes and the index gives them, match one
+func releaseFiles(files []DistributionFile, dep PythonDependency) []DistributionFile {
+	var release []DistributionFile
+	for _, file := range files {
+		_, version, ok := ParseDistributionFileName(file.Filename, dep.Name)
+		if !ok || !VersionsEqual(version, dep.Version) {
+			continue
+		}
+		if len(dep.Hashes) > 0 && len(file.Hashes) > 0 && !sharesHash(file.Hashes, dep.Hashes) {
+			continue
+		}
+		release = append(release, file)
+	}
+	return release
+}
+
+// bestFile returns the file rank orders first, preferring files which
+// weren't yanked, and leaving out those it ranks below zero
+func bestFile(files []DistributionFile, rank func(string) int) (DistributionFile, bool) {
+	var candidates []DistributionFile
+	for _, file := range files {
+		if rank(file.Filename) >= 0 {
+			candidates = append(candidates, file)
+		}
+	}
+	if len(candidates) == 0 {
+		return DistributionFile{}, false
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.Yanked != b.Yanked {
+			return !a.Yanked
+		}
+		if rank(a.Filename) != rank(b.Filename) {
+			return rank(a.Filename) < rank(b.Filename)
+		}
+		return a.Filename < b.Filename
+	})
+	return candidates[0], true
+}
+
+// SelectFile picks the file to download for dep from the files an index
+// lists for its project. Files must be of the pinned version and, when
+// dep has hashes and the index gives them, match one. Of those, files
+// which weren't yanked are preferred, then pure wheels, then sdists.
+func SelectFile(files []DistributionFile, dep PythonDependency) (DistributionFile, error) {
+	file, ok := bestFile(releaseFiles(files, dep), fileRank)
+	if !ok {
+		return DistributionFile{}, fmt.Errorf(
+			"No sdist or pure python wheel of %s matching its hashes in the index",
+			dep.GetCanonicalName(),
+		)
+	}
+	return file, nil
+}
+
+// targetRank orders the files of a release for the environment target
+// names: compatible wheels in the order of CompatibleTags, then sdists by
+// extension. Wheels for other environments rank below zero.
+func targetRank(target Tag) func(string) int {
+	priorities := make(map[Tag]int)
+	compatible := CompatibleTags(target)
+	for i, tag := range compatible {
+		if _, ok := priorities[tag]; !ok {
+			priorities[tag] = i
+		}
+	}
+	return func(fileName string) int {
+		if strings.HasSuffix(fileName, ".whl") {
+			rank := -1
+			for _, tag := range WheelTags(fileName) {
+				if priority, ok := priorities[tag]; ok && (rank < 0 || priority < rank) {
+					rank = priority
+				}
+			}
+			return rank
+		}
+		for i, ext := range sdistExtensions {
+			if strings.HasSuffix(fileName, ext) {
+				return len(compatible) + i
+			}
+		}
+		return -1
+	}
+}
+
+// forPython leaves out the files whose Requires-Python excludes the
+// python version of target, keeping every file when its interpreter tag
+// doesn't say which minor version it is. Malformed Requires-Python
+// values are ignored, as pip ignores them.
+func forPython(files []DistributionFile, target Tag) []DistributionFile {
+	version, ok := target.PythonVersion()
+	if !ok {
+		return files
+	}
+	var kept []DistributionFile
+	for _, file := range files {
+		if matches, err := MatchesSpecifiers(version, file.RequiresPython); err == nil && !matches {
+			continue
+		}
+		kept = append(kept, file)
+	}
+	return kept
+}
+
+// SelectFiles picks the files to download for dep so that each of the
+// targets can install it: the best wheel for each target, or its sdist
+// when no wheel is compatible, among the files whose Requires-Python
+// the target's interpreter satisfies. Files are picked among those
+// SelectFile would consider, and are listed once even when several
+// targets install the same one. Without targets, it picks the one file
+// SelectFile does.
+func SelectFiles(files []DistributionFile, dep PythonDependency, targets []Tag) ([]DistributionFile, error) {
+	if len(targets) == 0 {
+		file, err := SelectFile(files, dep)
+		if err != nil {
+			return nil, err
+		}
+		return []DistributionFile{file}, nil
+	}
+
+	release := releaseFiles(files, dep)
+	var selected []DistributionFile
+	seen := make(map[string]bool)
+	for _, target := range targets {
+		file, ok := bestFile(forPython(release, target), targetRank(target))
+		if !ok {
+			return nil, fmt.Errorf(
+				"No sdist or wheel for %s of %s matching its hashes and Requires-Python in the index",
+				target,
+				dep.GetCanonicalName(),
+			)
+		}
+		if !seen[file.Filename] {
+			seen[file.Filename] = true
+			selected = append(selected, file)
+		}
+	}
+	return selected, nil
+}
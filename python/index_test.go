@@ -0,0 +1,198 @@
+package python
+
+import (
+	"reflect"
+	"testing"
+)
+
+var sixProjectPage = []byte(`<!DOCTYPE html>
+<html>
+  <head><title>Links for six</title></head>
+  <body>
+    <h1>Links for six</h1>
+    <a href="../../packages/six-1.15.0-py2.py3-none-any.whl#sha256=8b74bedcbbbaca38ff6d7491d76f2b06b3592611af620f8426e82dddb04a5ced" data-requires-python="&gt;=2.7, !=3.0.*">six-1.15.0-py2.py3-none-any.whl</a><br/>
+    <a href="../../packages/six-1.16.0.tar.gz#sha256=1e61c37477a1626458e36f7b1d82aa5c9b094fa4802892072e49de9c60c4c926">six-1.16.0.tar.gz</a><br/>
+    <a href='https://files.example.com/six-1.16.0-py2.py3-none-any.whl#sha256=8abb2f1d86890a2dfb989f9a77cfcfd3e47c2a354b01111771326f8aa26e0254' data-yanked="">six-1.16.0-py2.py3-none-any.whl</a><br/>
+    <a href="../../packages/six-1.16.0-cp311-cp311-manylinux_2_28_x86_64.whl">six-1.16.0-cp311-cp311-manylinux_2_28_x86_64.whl</a><br/>
+  </body>
+</html>
+`)
+
+func TestParseProjectPage(t *testing.T) {
+	files, err := ParseProjectPage(sixProjectPage, "https://pypi.example.com/simple/six/")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(files) != 4 {
+		t.Fatalf("Expected 4 files, got %+v", files)
+	}
+	expected := DistributionFile{
+		Filename:       "six-1.15.0-py2.py3-none-any.whl",
+		URL:            "https://pypi.example.com/packages/six-1.15.0-py2.py3-none-any.whl",
+		Hashes:         []string{"sha256:8b74bedcbbbaca38ff6d7491d76f2b06b3592611af620f8426e82dddb04a5ced"},
+		RequiresPython: ">=2.7, !=3.0.*",
+	}
+	if !reflect.DeepEqual(files[0], expected) {
+		t.Errorf("Expected %+v, got %+v", expected, files[0])
+	}
+	if !files[2].Yanked || files[1].Yanked {
+		t.Errorf("Expected only the 1.16.0 wheel to be yanked")
+	}
+	if files[3].Hashes != nil {
+		t.Errorf("Expected no hashes without a fragment, got %q", files[3].Hashes)
+	}
+}
+
+func TestParseProjectPageBase(t *testing.T) {
+	page := []byte(`<html><head><base href="https://mirror.example.com/files/"></head>
+<body><a href="six-1.16.0.tar.gz">six-1.16.0.tar.gz</a></body></html>`)
+	files, err := ParseProjectPage(page, "https://pypi.example.com/simple/six/")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(files) != 1 || files[0].URL != "https://mirror.example.com/files/six-1.16.0.tar.gz" {
+		t.Errorf("Expected the link to resolve against the base, got %+v", files)
+	}
+}
+
+func TestParseDistributionFileName(t *testing.T) {
+	cases := []struct {
+		fileName, name  string
+		expectedName    string
+		expectedVersion string
+	}{
+		{"requests-2.31.0-py3-none-any.whl", "", "requests", "2.31.0"},
+		{"zope.interface-6.0-1-cp311-cp311-manylinux_2_28_x86_64.whl", "zope-interface", "zope.interface", "6.0"},
+		{"python-dateutil-2.8.2.tar.gz", "python_dateutil", "python-dateutil", "2.8.2"},
+		{"python-dateutil-2.8.2.tar.gz", "", "python-dateutil", "2.8.2"},
+		{"pyyaml-6.0.1.zip", "PyYAML", "pyyaml", "6.0.1"},
+	}
+	for _, c := range cases {
+		name, version, ok := ParseDistributionFileName(c.fileName, c.name)
+		if !ok || name != c.expectedName || version != c.expectedVersion {
+			t.Errorf("Expected %s to be %s %s, got %s %s", c.fileName, c.expectedName, c.expectedVersion, name, version)
+		}
+	}
+
+	for _, invalid := range []string{"requests-2.31.0.exe", "requests.whl", "download"} {
+		if _, _, ok := ParseDistributionFileName(invalid, ""); ok {
+			t.Errorf("Expected %s not to parse", invalid)
+		}
+	}
+	if _, _, ok := ParseDistributionFileName("requests-2.31.0.tar.gz", "six"); ok {
+		t.Errorf("Expected another project's file not to parse")
+	}
+}
+
+func TestSelectFile(t *testing.T) {
+	files, err := ParseProjectPage(sixProjectPage, "https://pypi.example.com/simple/six/")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// The yanked wheel loses out to the sdist
+	file, err := SelectFile(files, PythonDependency{Name: "six", Version: "1.16"})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if file.Filename != "six-1.16.0.tar.gz" {
+		t.Errorf("Expected the sdist, got %s", file.Filename)
+	}
+
+	file, err = SelectFile(files, PythonDependency{Name: "six", Version: "1.15.0"})
+	if err != nil || file.Filename != "six-1.15.0-py2.py3-none-any.whl" {
+		t.Errorf("Expected the pure wheel, got %s (%v)", file.Filename, err)
+	}
+
+	// Pinned hashes rule out files the index gives other hashes for
+	file, err = SelectFile(files, PythonDependency{
+		Name:    "six",
+		Version: "1.16.0",
+		Hashes:  []string{"sha256:8abb2f1d86890a2dfb989f9a77cfcfd3e47c2a354b01111771326f8aa26e0254"},
+	})
+	if err != nil || file.Filename != "six-1.16.0-py2.py3-none-any.whl" {
+		t.Errorf("Expected the wheel matching the hash, got %s (%v)", file.Filename, err)
+	}
+
+	if _, err = SelectFile(files, PythonDependency{Name: "six", Version: "1.17.0"}); err == nil {
+		t.Errorf("Expected an error for a version the index doesn't have")
+	}
+}
+
+func TestSelectFiles(t *testing.T) {
+	names := []string{
+		"numpy-1.26.0.tar.gz",
+		"numpy-1.26.0-cp311-cp311-manylinux_2_17_x86_64.manylinux2014_x86_64.whl",
+		"numpy-1.26.0-cp311-cp311-musllinux_1_1_x86_64.whl",
+		"numpy-1.26.0-cp311-cp311-win_amd64.whl",
+		"numpy-1.26.0-cp310-cp310-manylinux_2_17_x86_64.manylinux2014_x86_64.whl",
+		"numpy-1.26.0-cp39-abi3-manylinux_2_28_x86_64.whl",
+	}
+	var files []DistributionFile
+	for _, name := range names {
+		files = append(files, DistributionFile{Filename: name})
+	}
+	dep := PythonDependency{Name: "numpy", Version: "1.26.0"}
+
+	tests := []struct {
+		targets  []string
+		expected []string
+	}{
+		{
+			[]string{"cp311-cp311-manylinux_2_28_x86_64"},
+			[]string{"numpy-1.26.0-cp311-cp311-manylinux_2_17_x86_64.manylinux2014_x86_64.whl"},
+		},
+		// The stable ABI wheel is the only one built for cp312
+		{
+			[]string{"cp312-cp312-manylinux_2_28_x86_64", "cp311-cp311-win_amd64"},
+			[]string{"numpy-1.26.0-cp39-abi3-manylinux_2_28_x86_64.whl", "numpy-1.26.0-cp311-cp311-win_amd64.whl"},
+		},
+		// Too old a glibc for every wheel, or another architecture, falls
+		// back to the sdist
+		{
+			[]string{"cp311-cp311-manylinux_2_12_x86_64", "cp311-cp311-manylinux_2_28_aarch64"},
+			[]string{"numpy-1.26.0.tar.gz"},
+		},
+		{nil, []string{"numpy-1.26.0.tar.gz"}},
+	}
+	for _, test := range tests {
+		var targets []Tag
+		for _, target := range test.targets {
+			tag, err := ParseTag(target)
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+			targets = append(targets, tag)
+		}
+		selected, err := SelectFiles(files, dep, targets)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		var selectedNames []string
+		for _, file := range selected {
+			selectedNames = append(selectedNames, file.Filename)
+		}
+		if !reflect.DeepEqual(selectedNames, test.expected) {
+			t.Errorf("Expected %q for %q, got %q", test.expected, test.targets, selectedNames)
+		}
+	}
+
+	// numpy 1.26 needs python 3.9, so the target older than that gets no
+	// file at all
+	for i := range files {
+		files[i].RequiresPython = ">=3.9,<3.13"
+	}
+	oldPython := []Tag{{"cp38", "cp38", "manylinux_2_28_x86_64"}}
+	if _, err := SelectFiles(files, dep, oldPython); err == nil {
+		t.Errorf("Expected an error with no file for python 3.8")
+	}
+	selected, err := SelectFiles(files, dep, []Tag{{"py3", "none", "any"}})
+	if err != nil || len(selected) != 1 {
+		t.Errorf("Expected the sdist for a target without a minor version, got %v, %v", selected, err)
+	}
+
+	wheelsOnly := files[1:]
+	if _, err := SelectFiles(wheelsOnly, dep, []Tag{{"cp311", "cp311", "macosx_11_0_arm64"}}); err == nil {
+		t.Errorf("Expected an error with no file for the target")
+	}
+}
@@ -0,0 +1,332 @@
+package python
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Environment holds the values of the PEP 508 marker variables, such as
+// python_version and sys_platform, for the environment dependencies are
+// fetched for. Markers comparing a variable missing from it hold, so an
+// empty Environment keeps every dependency.
+type Environment map[string]string
+
+// legacyMarkerNames maps the dotted variable names of PEP 345 to their
+// PEP 508 names
+var legacyMarkerNames = map[string]string{
+	"os.name":                        "os_name",
+	"sys.platform":                   "sys_platform",
+	"platform.version":               "platform_version",
+	"platform.machine":               "platform_machine",
+	"platform.python_implementation": "platform_python_implementation",
+	"python_implementation":          "platform_python_implementation",
+}
+
+// ParseEnvironment parses KEY=VALUE marker variable settings
+func ParseEnvironment(values []string) (Environment, error) {
+	env := make(Environment)
+	for _, value := range values {
+		i := strings.Index(value, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("Expected KEY=VALUE for the marker environment: %s", value)
+		}
+		env[strings.TrimSpace(value[:i])] = strings.TrimSpace(value[i+1:])
+	}
+	return env, nil
+}
+
+// markerToken is a token of a marker: a quoted string, an operator, a
+// parenthesis or a word such as a variable name, and, or, in and not
+type markerToken struct {
+	text   string
+	quoted bool
+}
+
+func tokenizeMarker(marker string) ([]markerToken, error) {
+	var tokens []markerToken
+	for i := 0; i < len(marker); {
+		ch := marker[i]
+		switch {
+		case ch == ' ' || ch == '\t':
+			i++
+		case ch == '(' || ch == ')':
+			tokens = append(tokens, markerToken{text: string(ch)})
+			i++
+		case ch == '"' || ch == '\'':
+			end := strings.IndexByte(marker[i+1:], ch)
+			if end < 0 {
+				return nil, fmt.Errorf("Unterminated string in marker: %s", marker)
+			}
+			tokens = append(tokens, markerToken{text: marker[i+1 : i+1+end], quoted: true})
+			i += end + 2
+		case strings.IndexByte("<>=!~", ch) >= 0:
+			j := i
+			for j < len(marker) && strings.IndexByte("<>=!~", marker[j]) >= 0 {
+				j++
+			}
+			tokens = append(tokens, markerToken{text: marker[i:j]})
+			i = j
+		default:
+			j := i
+			for j < len(marker) && strings.IndexByte(" \t()'\"<>=!~", marker[j]) < 0 {
+				j++
+			}
+			tokens = append(tokens, markerToken{text: marker[i:j]})
+			i = j
+		}
+	}
+	return tokens, nil
+}
+
+// markerParser evaluates a marker as it parses it, by recursive descent
+type markerParser struct {
+	marker string
+	tokens []markerToken
+	pos    int
+	env    Environment
+}
+
+func (p *markerParser) peek() (markerToken, bool) {
+	if p.pos >= len(p.tokens) {
+		return markerToken{}, false
+	}
+	return p.tokens[p.pos], true
+}
+
+func (p *markerParser) next() (markerToken, error) {
+	token, ok := p.peek()
+	if !ok {
+		return token, fmt.Errorf("Unexpected end of marker: %s", p.marker)
+	}
+	p.pos++
+	return token, nil
+}
+
+// peekWord reports whether the next token is the unquoted word
+func (p *markerParser) peekWord(word string) bool {
+	token, ok := p.peek()
+	return ok && !token.quoted && token.text == word
+}
+
+func (p *markerParser) parseOr() (bool, error) {
+	result, err := p.parseAnd()
+	if err != nil {
+		return false, err
+	}
+	for p.peekWord("or") {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return false, err
+		}
+		result = result || right
+	}
+	return result, nil
+}
+
+func (p *markerParser) parseAnd() (bool, error) {
+	result, err := p.parseExpr()
+	if err != nil {
+		return false, err
+	}
+	for p.peekWord("and") {
+		p.pos++
+		right, err := p.parseExpr()
+		if err != nil {
+			return false, err
+		}
+		result = result && right
+	}
+	return result, nil
+}
+
+func (p *markerParser) parseExpr() (bool, error) {
+	if p.peekWord("(") {
+		p.pos++
+		result, err := p.parseOr()
+		if err != nil {
+			return false, err
+		}
+		if !p.peekWord(")") {
+			return false, fmt.Errorf("Expected ) in marker: %s", p.marker)
+		}
+		p.pos++
+		return result, nil
+	}
+
+	left, err := p.next()
+	if err != nil {
+		return false, err
+	}
+	op, err := p.next()
+	if err != nil {
+		return false, err
+	}
+	if !op.quoted && op.text == "not" {
+		if !p.peekWord("in") {
+			return false, fmt.Errorf("Expected not in in marker: %s", p.marker)
+		}
+		p.pos++
+		op.text = "not in"
+	}
+	right, err := p.next()
+	if err != nil {
+		return false, err
+	}
+
+	leftValue, leftKnown, err := p.value(left)
+	if err != nil {
+		return false, err
+	}
+	rightValue, rightKnown, err := p.value(right)
+	if err != nil {
+		return false, err
+	}
+	variable := left.text
+	if left.quoted {
+		variable = right.text
+	}
+	if !leftKnown || !rightKnown {
+		return true, validMarkerOp(op.text, p.marker)
+	}
+	return compareMarkerValues(variable, leftValue, op.text, rightValue, p.marker)
+}
+
+// value returns a quoted string, or the value of a variable and whether
+// the environment sets it
+func (p *markerParser) value(token markerToken) (string, bool, error) {
+	if token.quoted {
+		return token.text, true, nil
+	}
+	name := token.text
+	if legacy, ok := legacyMarkerNames[name]; ok {
+		name = legacy
+	}
+	if !isMarkerVariable(name) {
+		return "", false, fmt.Errorf("Unknown marker variable %s: %s", token.text, p.marker)
+	}
+	value, ok := p.env[name]
+	return value, ok, nil
+}
+
+func isMarkerVariable(name string) bool {
+	switch name {
+	case "os_name", "sys_platform", "platform_release", "platform_system",
+		"platform_version", "platform_machine", "platform_python_implementation",
+		"python_version", "python_full_version", "implementation_name",
+		"implementation_version", "extra":
+		return true
+	}
+	return false
+}
+
+func validMarkerOp(op, marker string) error {
+	switch op {
+	case "<", "<=", "==", "!=", ">=", ">", "~=", "===", "in", "not in":
+		return nil
+	}
+	return fmt.Errorf("Unknown marker operator %s: %s", op, marker)
+}
+
+// compareMarkerValues compares two values as versions, when they both are
+// and the variable is a version, and otherwise as strings
+func compareMarkerValues(variable, left, op, right, marker string) (bool, error) {
+	if err := validMarkerOp(op, marker); err != nil {
+		return false, err
+	}
+	switch op {
+	case "in":
+		return strings.Contains(right, left), nil
+	case "not in":
+		return !strings.Contains(right, left), nil
+	case "===":
+		return left == right, nil
+	}
+
+	_, leftOK := parseVersion(left)
+	_, rightOK := parseVersion(right)
+	if strings.HasSuffix(variable, "_version") && leftOK && rightOK {
+		if op == "~=" {
+			return compatibleRelease(left, right), nil
+		}
+		return compareResult(compareVersions(left, right), op), nil
+	}
+	if op == "~=" {
+		return false, fmt.Errorf("Can't compare %s and %s with ~=: %s", left, right, marker)
+	}
+	return compareResult(strings.Compare(left, right), op), nil
+}
+
+func compareResult(c int, op string) bool {
+	switch op {
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case "==":
+		return c == 0
+	case "!=":
+		return c != 0
+	case ">=":
+		return c >= 0
+	}
+	return c > 0
+}
+
+// compatibleRelease reports whether version matches ~=spec, being at
+// least spec and sharing all but its last release number, so 3.8.2
+// matches ~=3.8.0 and 3.9 matches ~=3.8
+func compatibleRelease(version, spec string) bool {
+	if compareVersions(version, spec) < 0 {
+		return false
+	}
+	m := versionRegexp.FindStringSubmatch(strings.TrimPrefix(strings.ToLower(strings.TrimSpace(spec)), "v"))
+	if m == nil {
+		return false
+	}
+	// The trailing zeros parseVersion drops count here, as ~=3.8.0 only
+	// allows 3.8.x
+	specParts := strings.Split(m[2], ".")
+	if len(specParts) < 2 {
+		return false
+	}
+	v, _ := parseVersion(version)
+	for i, part := range specParts[:len(specParts)-1] {
+		n, _ := strconv.Atoi(part)
+		var x int
+		if i < len(v.release) {
+			x = v.release[i]
+		}
+		if x != n {
+			return false
+		}
+	}
+	return true
+}
+
+// EvaluateMarker reports whether a PEP 508 environment marker such as
+// python_version < "3.8" and sys_platform == "win32" holds in env
+func EvaluateMarker(marker string, env Environment) (bool, error) {
+	tokens, err := tokenizeMarker(marker)
+	if err != nil {
+		return false, err
+	}
+	p := &markerParser{marker: marker, tokens: tokens, env: env}
+	result, err := p.parseOr()
+	if err != nil {
+		return false, err
+	}
+	if p.pos != len(p.tokens) {
+		return false, fmt.Errorf("Unexpected %s in marker: %s", p.tokens[p.pos].text, marker)
+	}
+	return result, nil
+}
+
+// AppliesTo reports whether the distribution is needed in env
+func (d *PythonDependency) AppliesTo(env Environment) (bool, error) {
+	if d.Marker == "" {
+		return true, nil
+	}
+	return EvaluateMarker(d.Marker, env)
+}
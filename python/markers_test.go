@@ -0,0 +1,68 @@
+package python
+
+import (
+	"testing"
+)
+
+func TestEvaluateMarker(t *testing.T) {
+	env := Environment{
+		"python_version": "3.11",
+		"sys_platform":   "linux",
+		"os_name":        "posix",
+	}
+	cases := []struct {
+		marker   string
+		expected bool
+	}{
+		{`python_version < "3.8"`, false},
+		{`python_version >= "3.8"`, true},
+		{`python_version >= '3.10'`, true},
+		{`"3.8" < python_version`, true},
+		{`python_version ~= "3.9"`, true},
+		{`python_version ~= "3.9.0"`, false},
+		{`sys_platform == "win32" or os_name == "posix"`, true},
+		{`sys_platform == "linux" and python_version < "3.8"`, false},
+		{`(sys_platform == "win32" or sys_platform == "linux") and os_name == "posix"`, true},
+		{`"linux" in sys_platform`, true},
+		{`sys_platform not in "win32 cygwin"`, true},
+		{`sys.platform == "linux"`, true},
+		// Variables the environment doesn't set can't rule anything out
+		{`platform_machine == "arm64"`, true},
+		{`extra == "socks"`, true},
+	}
+	for _, c := range cases {
+		result, err := EvaluateMarker(c.marker, env)
+		if err != nil {
+			t.Errorf("%s: %s", c.marker, err)
+			continue
+		}
+		if result != c.expected {
+			t.Errorf("Expected %s to be %t", c.marker, c.expected)
+		}
+	}
+
+	for _, invalid := range []string{
+		`python_version <`,
+		`python_version < "3.8`,
+		`python_versions < "3.8"`,
+		`(python_version < "3.8"`,
+		`python_version < "3.8" sys_platform`,
+	} {
+		if _, err := EvaluateMarker(invalid, env); err == nil {
+			t.Errorf("Expected an error for %s", invalid)
+		}
+	}
+}
+
+func TestParseEnvironment(t *testing.T) {
+	env, err := ParseEnvironment([]string{"python_version=3.11", "sys_platform = linux"})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if env["python_version"] != "3.11" || env["sys_platform"] != "linux" {
+		t.Errorf("Unexpected environment %v", env)
+	}
+	if _, err = ParseEnvironment([]string{"python_version"}); err == nil {
+		t.Errorf("Expected an error without a value")
+	}
+}
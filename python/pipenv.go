@@ -0,0 +1,139 @@
+package python
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// PipfileLockFileName is the name of Pipenv's lock file
+const PipfileLockFileName = "Pipfile.lock"
+
+// PipfileLock represents a Pipfile.lock file. Default holds the
+// project's dependencies and Develop its dev dependencies, both keyed by
+// project name.
+type PipfileLock struct {
+	Meta    PipfileLockMeta                `json:"_meta"`
+	Default map[string]*PipfileLockPackage `json:"default"`
+	Develop map[string]*PipfileLockPackage `json:"develop"`
+}
+
+// PipfileLockMeta represents the _meta block of a Pipfile.lock file
+type PipfileLockMeta struct {
+	Sources []PipfileSource `json:"sources"`
+}
+
+// PipfileSource is a package index named in a Pipfile
+type PipfileSource struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// PipfileLockPackage represents a locked package. Packages from an index
+// pin a Version such as ==2.31.0 and name the Index, while others give
+// the Path, File or Git repository they come from.
+type PipfileLockPackage struct {
+	Version  string   `json:"version"`
+	Hashes   []string `json:"hashes"`
+	Markers  string   `json:"markers"`
+	Extras   []string `json:"extras"`
+	Index    string   `json:"index"`
+	Editable bool     `json:"editable"`
+	Path     string   `json:"path"`
+	File     string   `json:"file"`
+	Git      string   `json:"git"`
+	Ref      string   `json:"ref"`
+}
+
+// pipfileLockDependency converts a locked package, finding the URL of the
+// index it names among sources
+func pipfileLockDependency(
+	name string,
+	pkg *PipfileLockPackage,
+	sources map[string]string,
+) (PythonDependency, error) {
+	dep := PythonDependency{
+		Name:     name,
+		Version:  strings.TrimLeft(pkg.Version, "="),
+		Extras:   pkg.Extras,
+		Marker:   pkg.Markers,
+		Editable: pkg.Editable,
+		Hashes:   pkg.Hashes,
+	}
+	switch {
+	case pkg.Git != "":
+		dep.URL = "git+" + pkg.Git
+		if pkg.Ref != "" {
+			dep.URL += "@" + pkg.Ref
+		}
+	case pkg.Path != "":
+		dep.LocalPath = pkg.Path
+	case pkg.File != "":
+		if err := dep.setURL(pkg.File); err != nil {
+			return dep, fmt.Errorf("%s: %s", name, err)
+		}
+	case dep.Version == "":
+		return dep, fmt.Errorf("%s isn't pinned to a version", name)
+	}
+
+	if pkg.Index != "" {
+		indexURL, ok := sources[pkg.Index]
+		if !ok {
+			return dep, fmt.Errorf("%s comes from an unknown index: %s", name, pkg.Index)
+		}
+		dep.IndexURL = indexURL
+	}
+	if err := dep.checkMarker(); err != nil {
+		return dep, fmt.Errorf("%s: %s", name, err)
+	}
+	return dep, nil
+}
+
+// ParsePipfileLock decodes a Pipfile.lock file. Packages locked at the
+// same version in both the default and develop sections are listed once,
+// as dependencies of the project itself.
+func ParsePipfileLock(contents []byte) (*DependenciesFile, error) {
+	var lockfile PipfileLock
+	if err := json.Unmarshal(contents, &lockfile); err != nil {
+		return nil, err
+	}
+
+	file := &DependenciesFile{Path: PipfileLockFileName}
+	sources := make(map[string]string)
+	for i, source := range lockfile.Meta.Sources {
+		sources[source.Name] = source.URL
+		if i == 0 {
+			file.IndexURL = source.URL
+		} else {
+			file.ExtraIndexURLs = append(file.ExtraIndexURLs, source.URL)
+		}
+	}
+
+	inDefault := make(map[string]bool)
+	for i, section := range []map[string]*PipfileLockPackage{lockfile.Default, lockfile.Develop} {
+		dev := i == 1
+		var names []string
+		for name := range section {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			dep, err := pipfileLockDependency(name, section[name], sources)
+			if err != nil {
+				return nil, err
+			}
+			key := NormalizeName(name) + "==" + dep.Version
+			if dev && inDefault[key] {
+				continue
+			}
+			if !dev {
+				inDefault[key] = true
+			}
+			dep.Dev = dev
+			file.Dependencies = append(file.Dependencies, dep)
+		}
+	}
+	return file, nil
+}
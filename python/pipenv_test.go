@@ -0,0 +1,117 @@
+package python
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var pipfileLock = []byte(`{
+    "_meta": {
+        "hash": {"sha256": "0f3c"},
+        "pipfile-spec": 6,
+        "requires": {"python_version": "3.11"},
+        "sources": [
+            {"name": "pypi", "url": "https://pypi.org/simple", "verify_ssl": true},
+            {"name": "internal", "url": "https://pypi.internal/simple", "verify_ssl": true}
+        ]
+    },
+    "default": {
+        "requests": {
+            "hashes": [
+                "sha256:58cd2187c01e70e6e26505bca751777aa9f2ee0b7f4300988b709f44e013003f",
+                "sha256:942c5a758f98d790eaed1a29cb6eefc7ffb0d1cf7af05c3d2791656dbd6ad1e1"
+            ],
+            "index": "pypi",
+            "markers": "python_version >= '3.7'",
+            "version": "==2.31.0"
+        },
+        "widget": {
+            "git": "https://github.com/bosgood/widget.git",
+            "ref": "9d2c1e4"
+        },
+        "shared": {
+            "editable": true,
+            "path": "./libs/shared"
+        },
+        "six": {
+            "hashes": [],
+            "index": "internal",
+            "version": "==1.16.0"
+        }
+    },
+    "develop": {
+        "pytest": {
+            "hashes": ["sha256:1d881c6124e08ff0a1bb75ba3ec0bfd8b5354a01c194ddd5a0a870a48d99b002"],
+            "index": "pypi",
+            "version": "==7.4.2"
+        },
+        "six": {
+            "hashes": [],
+            "index": "internal",
+            "version": "==1.16.0"
+        }
+    }
+}`)
+
+func TestParsePipfileLock(t *testing.T) {
+	file, err := ParsePipfileLock(pipfileLock)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if file.IndexURL != "https://pypi.org/simple" {
+		t.Errorf("Unexpected index URL %s", file.IndexURL)
+	}
+	if !reflect.DeepEqual(file.ExtraIndexURLs, []string{"https://pypi.internal/simple"}) {
+		t.Errorf("Unexpected extra index URLs %q", file.ExtraIndexURLs)
+	}
+
+	expected := []PythonDependency{
+		{
+			Name:    "requests",
+			Version: "2.31.0",
+			Marker:  "python_version >= '3.7'",
+			Hashes: []string{
+				"sha256:58cd2187c01e70e6e26505bca751777aa9f2ee0b7f4300988b709f44e013003f",
+				"sha256:942c5a758f98d790eaed1a29cb6eefc7ffb0d1cf7af05c3d2791656dbd6ad1e1",
+			},
+			IndexURL: "https://pypi.org/simple",
+		},
+		{Name: "shared", LocalPath: "./libs/shared", Editable: true},
+		{
+			Name:     "six",
+			Version:  "1.16.0",
+			Hashes:   []string{},
+			IndexURL: "https://pypi.internal/simple",
+		},
+		{Name: "widget", URL: "git+https://github.com/bosgood/widget.git@9d2c1e4"},
+		{
+			Name:     "pytest",
+			Version:  "7.4.2",
+			Hashes:   []string{"sha256:1d881c6124e08ff0a1bb75ba3ec0bfd8b5354a01c194ddd5a0a870a48d99b002"},
+			IndexURL: "https://pypi.org/simple",
+			Dev:      true,
+		},
+	}
+	if !reflect.DeepEqual(file.Dependencies, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, file.Dependencies)
+	}
+}
+
+func TestParsePipfileLockErrors(t *testing.T) {
+	tests := []struct {
+		contents string
+		expected string
+	}{
+		{`{"default": {"six": {"index": "pypi"}}}`, "isn't pinned"},
+		{`{"default": {"six": {"index": "other", "version": "==1.16.0"}}}`, "unknown index: other"},
+		{`{"default": {"six": {"version": "==1.16.0", "markers": "python_version >"}}}`, "six"},
+		{`{"default": []}`, "cannot unmarshal"},
+	}
+	for _, test := range tests {
+		_, err := ParsePipfileLock([]byte(test.contents))
+		if err == nil || !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("Expected an error containing %q parsing %s, got %v", test.expected, test.contents, err)
+		}
+	}
+}
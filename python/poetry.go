@@ -0,0 +1,132 @@
+package python
+
+import (
+	"fmt"
+	"github.com/BurntSushi/toml"
+)
+
+// PoetryLockFileName is the name of Poetry's lock file
+const PoetryLockFileName = "poetry.lock"
+
+// PoetryLock represents a poetry.lock file
+type PoetryLock struct {
+	Packages []PoetryPackage `toml:"package"`
+	Metadata PoetryMetadata  `toml:"metadata"`
+}
+
+// PoetryMetadata represents the metadata table of a poetry.lock file.
+// Lock files before version 2.0 list the files of each package here
+// rather than with the package.
+type PoetryMetadata struct {
+	LockVersion string                  `toml:"lock-version"`
+	Files       map[string][]PoetryFile `toml:"files"`
+}
+
+// PoetryPackage represents a [[package]] entry of a poetry.lock file.
+// Category is dev for dev dependencies before lock version 2.0, which
+// records the dependency Groups needing the package instead, if anything.
+// Markers is either one marker or a table of them by group.
+type PoetryPackage struct {
+	Name     string        `toml:"name"`
+	Version  string        `toml:"version"`
+	Category string        `toml:"category"`
+	Optional bool          `toml:"optional"`
+	Groups   []string      `toml:"groups"`
+	Markers  interface{}   `toml:"markers"`
+	Files    []PoetryFile  `toml:"files"`
+	Source   *PoetrySource `toml:"source"`
+}
+
+// PoetryFile is one file of a package with its hash, such as
+// sha256:<hex>
+type PoetryFile struct {
+	File string `toml:"file"`
+	Hash string `toml:"hash"`
+}
+
+// PoetrySource represents where a package comes from when it isn't PyPI:
+// a legacy (PEP 503) index, a git repository, a directory or file on
+// disk or the URL of an archive
+type PoetrySource struct {
+	Type              string `toml:"type"`
+	URL               string `toml:"url"`
+	Reference         string `toml:"reference"`
+	ResolvedReference string `toml:"resolved_reference"`
+}
+
+// isDev reports whether only dev dependencies need the package
+func (p *PoetryPackage) isDev() bool {
+	if p.Category != "" {
+		return p.Category == "dev"
+	}
+	if len(p.Groups) == 0 {
+		return false
+	}
+	for _, group := range p.Groups {
+		if group == "main" {
+			return false
+		}
+	}
+	return true
+}
+
+// poetryDependency converts a [[package]] entry, whose files are listed
+// in files when the lock file is from before version 2.0
+func poetryDependency(pkg PoetryPackage, files []PoetryFile) (PythonDependency, error) {
+	dep := PythonDependency{
+		Name:     pkg.Name,
+		Version:  pkg.Version,
+		Dev:      pkg.isDev(),
+		Optional: pkg.Optional,
+	}
+	if marker, ok := pkg.Markers.(string); ok {
+		dep.Marker = marker
+	}
+	if len(pkg.Files) > 0 {
+		files = pkg.Files
+	}
+	for _, file := range files {
+		dep.Hashes = append(dep.Hashes, file.Hash)
+	}
+
+	if pkg.Source != nil {
+		switch pkg.Source.Type {
+		case "legacy":
+			dep.IndexURL = pkg.Source.URL
+		case "git":
+			ref := pkg.Source.ResolvedReference
+			if ref == "" {
+				ref = pkg.Source.Reference
+			}
+			dep.URL = "git+" + pkg.Source.URL + "@" + ref
+		case "directory", "file":
+			dep.LocalPath = pkg.Source.URL
+		case "url":
+			dep.URL = pkg.Source.URL
+		default:
+			return dep, fmt.Errorf("%s comes from an unknown source type: %s", pkg.Name, pkg.Source.Type)
+		}
+	}
+	if err := dep.checkMarker(); err != nil {
+		return dep, fmt.Errorf("%s: %s", pkg.Name, err)
+	}
+	return dep, nil
+}
+
+// ParsePoetryLock decodes a poetry.lock file
+func ParsePoetryLock(contents []byte) (*DependenciesFile, error) {
+	var lockfile PoetryLock
+	if _, err := toml.Decode(string(contents), &lockfile); err != nil {
+		return nil, err
+	}
+
+	file := &DependenciesFile{Path: PoetryLockFileName}
+	for _, pkg := range lockfile.Packages {
+		dep, err := poetryDependency(pkg, lockfile.Metadata.Files[pkg.Name])
+		if err != nil {
+			return nil, err
+		}
+		file.Dependencies = append(file.Dependencies, dep)
+	}
+	return file, nil
+}
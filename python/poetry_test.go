@@ -0,0 +1,219 @@
+package python
+
+import (
+	"bitbucket.org/bosgood/dep-get/lib/fs"
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var poetryLock = []byte(`# This file is automatically @generated by Poetry 1.6.1 and should not be changed by hand.
+
+[[package]]
+name = "colorama"
+version = "0.4.6"
+description = "Cross-platform colored terminal text."
+optional = false
+python-versions = "!=3.0.*,!=3.1.*,!=3.2.*,!=3.3.*,!=3.4.*,!=3.5.*,!=3.6.*,>=2.7"
+files = [
+    {file = "colorama-0.4.6-py2.py3-none-any.whl", hash = "sha256:4f1d9991f5acc0ca119f9d443620b77f9d6b33703e51011c16baf57afb285fc6"},
+    {file = "colorama-0.4.6.tar.gz", hash = "sha256:08695f5cb7ed6e0531a20572697297273c47b8cae5a63ffc6d6ed5c201be6e44"},
+]
+
+[package.extras]
+
+[[package]]
+name = "pytest"
+version = "7.4.2"
+description = "pytest: simple powerful testing with Python"
+optional = false
+python-versions = ">=3.7"
+groups = ["dev"]
+markers = "python_version >= \"3.7\""
+files = [
+    {file = "pytest-7.4.2-py3-none-any.whl", hash = "sha256:1d881c6124e08ff0a1bb75ba3ec0bfd8b5354a01c194ddd5a0a870a48d99b002"},
+]
+
+[[package]]
+name = "pysocks"
+version = "1.7.1"
+description = "A Python SOCKS client module."
+optional = true
+python-versions = ">=2.7"
+groups = ["main"]
+files = []
+
+[package.source]
+type = "legacy"
+url = "https://pypi.internal/simple"
+reference = "internal"
+
+[[package]]
+name = "widget"
+version = "1.0.0"
+description = ""
+optional = false
+python-versions = "*"
+files = []
+
+[package.source]
+type = "git"
+url = "https://github.com/bosgood/widget.git"
+reference = "main"
+resolved_reference = "9d2c1e4"
+
+[[package]]
+name = "shared"
+version = "0.1.0"
+description = ""
+optional = false
+python-versions = "^3.11"
+files = []
+develop = true
+
+[package.source]
+type = "directory"
+url = "libs/shared"
+
+[metadata]
+lock-version = "2.0"
+python-versions = "^3.11"
+content-hash = "5e1b"
+`)
+
+// poetryLockV1 lists its files in the metadata table, as poetry.lock
+// files before version 2.0 do
+var poetryLockV1 = []byte(`[[package]]
+name = "six"
+version = "1.16.0"
+description = "Python 2 and 3 compatibility utilities"
+category = "main"
+optional = false
+python-versions = ">=2.7, !=3.0.*, !=3.1.*, !=3.2.*"
+
+[[package]]
+name = "mock"
+version = "5.1.0"
+description = "Rolling backport of unittest.mock for all Pythons"
+category = "dev"
+optional = false
+python-versions = ">=3.6"
+
+[metadata]
+lock-version = "1.1"
+python-versions = "^3.8"
+content-hash = "a6f2"
+
+[metadata.files]
+six = [
+    {file = "six-1.16.0-py2.py3-none-any.whl", hash = "sha256:8abb2f1d86890a2dfb989f9a77cfcfd3e47c2a354b01111771326f8aa26e0254"},
+]
+mock = []
+`)
+
+func TestParsePoetryLock(t *testing.T) {
+	tests := []struct {
+		contents []byte
+		expected []PythonDependency
+	}{
+		{
+			poetryLock,
+			[]PythonDependency{
+				{
+					Name:    "colorama",
+					Version: "0.4.6",
+					Hashes: []string{
+						"sha256:4f1d9991f5acc0ca119f9d443620b77f9d6b33703e51011c16baf57afb285fc6",
+						"sha256:08695f5cb7ed6e0531a20572697297273c47b8cae5a63ffc6d6ed5c201be6e44",
+					},
+				},
+				{
+					Name:    "pytest",
+					Version: "7.4.2",
+					Marker:  `python_version >= "3.7"`,
+					Hashes:  []string{"sha256:1d881c6124e08ff0a1bb75ba3ec0bfd8b5354a01c194ddd5a0a870a48d99b002"},
+					Dev:     true,
+				},
+				{
+					Name:     "pysocks",
+					Version:  "1.7.1",
+					IndexURL: "https://pypi.internal/simple",
+					Optional: true,
+				},
+				{Name: "widget", Version: "1.0.0", URL: "git+https://github.com/bosgood/widget.git@9d2c1e4"},
+				{Name: "shared", Version: "0.1.0", LocalPath: "libs/shared"},
+			},
+		},
+		{
+			poetryLockV1,
+			[]PythonDependency{
+				{
+					Name:    "six",
+					Version: "1.16.0",
+					Hashes:  []string{"sha256:8abb2f1d86890a2dfb989f9a77cfcfd3e47c2a354b01111771326f8aa26e0254"},
+				},
+				{Name: "mock", Version: "5.1.0", Dev: true},
+			},
+		},
+	}
+	for _, test := range tests {
+		file, err := ParsePoetryLock(test.contents)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if !reflect.DeepEqual(file.Dependencies, test.expected) {
+			t.Errorf("Expected %+v, got %+v", test.expected, file.Dependencies)
+		}
+	}
+}
+
+func TestParsePoetryLockUnknownSource(t *testing.T) {
+	contents := []byte(`[[package]]
+name = "six"
+version = "1.16.0"
+
+[package.source]
+type = "svn"
+url = "svn://example.com/six"
+`)
+	_, err := ParsePoetryLock(contents)
+	if err == nil || !strings.Contains(err.Error(), "unknown source type: svn") {
+		t.Errorf("Expected an unknown source error, got %v", err)
+	}
+}
+
+func TestReadDependenciesDetectsLockfile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dep-get-python")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	if _, err = ReadDependencies(&fs.OSFS{}, dir); err == nil || !strings.Contains(err.Error(), "No dependencies file") {
+		t.Errorf("Expected no dependencies file to be found, got %v", err)
+	}
+
+	files := []struct {
+		name     string
+		contents []byte
+	}{
+		{RequirementsFileName, []byte("six==1.16.0\n")},
+		{PoetryLockFileName, poetryLockV1},
+		{PipfileLockFileName, pipfileLock},
+	}
+	for _, f := range files {
+		if err = ioutil.WriteFile(path.Join(dir, f.name), f.contents, 0644); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		file, err := ReadDependencies(&fs.OSFS{}, dir)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if file.Path != path.Join(dir, f.name) {
+			t.Errorf("Expected to read %s, read %s", f.name, file.Path)
+		}
+	}
+}
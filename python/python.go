@@ -0,0 +1,168 @@
+package python
+
+import (
+	"bitbucket.org/bosgood/dep-get/lib/fs"
+	"bitbucket.org/bosgood/dep-get/lib/integrity"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// DependenciesFileNames lists the python lock files in the order they
+// are looked for
+var DependenciesFileNames = []string{
+	PipfileLockFileName,
+	PoetryLockFileName,
+	RequirementsFileName,
+}
+
+// PythonDependency declares a pinned python distribution and a way to
+// download it. Distributions from a package index only have a Name and
+// Version, while direct URL requirements also have the URL to download.
+// Requirements on disk, such as editable installs of the project's own
+// packages, have a LocalPath relative to the project directory instead.
+//
+// Hashes are the digests pip checks the downloaded file against, written
+// ALGORITHM:HEX as in --hash options, any of which the file may match.
+// Marker is the environment marker limiting the environments needing
+// the distribution, such as python_version < "3.8". IndexURL is the
+// package index the lockfile pins the distribution to, if any.
+//
+// Dev marks distributions only dev dependencies need and Optional those
+// only needed for extras, when the lockfile records them.
+type PythonDependency struct {
+	Name      string
+	Version   string
+	Extras    []string
+	Marker    string
+	URL       string
+	LocalPath string
+	Editable  bool
+	Hashes    []string
+	IndexURL  string
+	Dev       bool
+	Optional  bool
+}
+
+// GetCanonicalName returns a unique name for the distribution at this
+// version, as it would be pinned in a requirements file
+func (d *PythonDependency) GetCanonicalName() string {
+	if d.Version == "" && d.URL != "" {
+		return fmt.Sprintf("%s @ %s", d.Name, d.URL)
+	}
+	return fmt.Sprintf("%s==%s", d.Name, d.Version)
+}
+
+// IsLocal reports whether the distribution is on disk rather than
+// downloaded
+func (d *PythonDependency) IsLocal() bool {
+	return d.LocalPath != ""
+}
+
+// IsVCS reports whether the distribution is checked out of a version
+// control system, as git+https://... requirements are, rather than
+// downloaded as a file
+func (d *PythonDependency) IsVCS() bool {
+	urlObj, err := url.Parse(d.URL)
+	if err != nil {
+		return false
+	}
+	return strings.Contains(urlObj.Scheme, "+")
+}
+
+// Integrity returns the subresource integrity string matching the
+// distribution's hashes
+func (d *PythonDependency) Integrity() (string, error) {
+	var digests []string
+	for _, hash := range d.Hashes {
+		i := strings.Index(hash, ":")
+		if i < 0 {
+			return "", fmt.Errorf("Malformed hash for %s: %s", d.Name, hash)
+		}
+		digest, err := integrity.ParseHexDigest(hash[:i], hash[i+1:])
+		if err != nil {
+			return "", fmt.Errorf("%s: %s", d.Name, err)
+		}
+		digests = append(digests, digest.String())
+	}
+	return strings.Join(digests, " "), nil
+}
+
+var nameSeparators = regexp.MustCompile(`[-_.]+`)
+
+// NormalizeName normalizes a project name as PEP 503 does, so that
+// Foo.Bar, foo_bar and foo-bar are all foo-bar
+func NormalizeName(name string) string {
+	return strings.ToLower(nameSeparators.ReplaceAllString(name, "-"))
+}
+
+// FileNameFromURL returns the file name at the end of a download URL,
+// without its query or fragment
+func FileNameFromURL(fileURL string) string {
+	urlObj, err := url.Parse(fileURL)
+	if err != nil {
+		return path.Base(fileURL)
+	}
+	return path.Base(urlObj.Path)
+}
+
+// DependenciesFile holds the distributions a python lockfile pins and
+// the package indexes it names, if any
+type DependenciesFile struct {
+	Path         string
+	Dependencies []PythonDependency
+	// IndexURL replaces the default package index and ExtraIndexURLs are
+	// searched after it
+	IndexURL       string
+	ExtraIndexURLs []string
+}
+
+// ParseDependencies collects the dependencies from the contents of the
+// lockfile with the given file name, which for requirements files must
+// include no others
+func ParseDependencies(fileName string, contents []byte) (*DependenciesFile, error) {
+	switch fileName {
+	case PipfileLockFileName:
+		return ParsePipfileLock(contents)
+	case PoetryLockFileName:
+		return ParsePoetryLock(contents)
+	case RequirementsFileName:
+		return ParseRequirements(contents)
+	}
+	return nil, fmt.Errorf("Unknown dependencies file: %s", fileName)
+}
+
+// ReadDependencies reads the first python lock file found in dirPath
+func ReadDependencies(fileSystem fs.FileSystem, dirPath string) (*DependenciesFile, error) {
+	for _, fileName := range DependenciesFileNames {
+		filePath := path.Join(dirPath, fileName)
+		if fileName == RequirementsFileName {
+			if _, err := fileSystem.Stat(filePath); os.IsNotExist(err) {
+				continue
+			}
+			return ReadRequirements(fileSystem, filePath)
+		}
+
+		contents, err := fileSystem.ReadFile(filePath)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		file, err := ParseDependencies(fileName, contents)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", filePath, err)
+		}
+		file.Path = filePath
+		return file, nil
+	}
+	return nil, fmt.Errorf(
+		"No dependencies file found in %s (looked for %s)",
+		dirPath,
+		strings.Join(DependenciesFileNames, ", "),
+	)
+}
@@ -0,0 +1,48 @@
+package python
+
+import (
+	"testing"
+)
+
+func TestNormalizeName(t *testing.T) {
+	for _, name := range []string{"Foo.Bar", "foo_bar", "foo--bar", "FOO-bar"} {
+		if normalized := NormalizeName(name); normalized != "foo-bar" {
+			t.Errorf("Expected %s to normalize to foo-bar, got %s", name, normalized)
+		}
+	}
+}
+
+func TestPythonDependencyIntegrity(t *testing.T) {
+	dep := PythonDependency{
+		Name:    "six",
+		Version: "1.16.0",
+		Hashes: []string{
+			"sha256:1e61c37477a1626458e36f7b1d82aa5c9b094fa4802892072e49de9c60c4c926",
+			"sha256:8abb2f1d86890a2dfb989f9a77cfcfd3e47c2a354b01111771326f8aa26e0254",
+		},
+	}
+	integrity, err := dep.Integrity()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	expected := "sha256-HmHDdHehYmRY4297HYKqXJsJT6SAKJIHLknenGDEySY= sha256-irsvHYaJCi37mJ+ad8/P0+R8KjVLAREXcTJviqJuAlQ="
+	if integrity != expected {
+		t.Errorf("Expected %s, got %s", expected, integrity)
+	}
+
+	dep.Hashes = []string{"md5:abc"}
+	if _, err = dep.Integrity(); err == nil {
+		t.Errorf("Expected an error for an unsupported hash")
+	}
+}
+
+func TestPythonDependencyCanonicalName(t *testing.T) {
+	dep := PythonDependency{Name: "six", Version: "1.16.0"}
+	if name := dep.GetCanonicalName(); name != "six==1.16.0" {
+		t.Errorf("Expected six==1.16.0, got %s", name)
+	}
+	dep = PythonDependency{Name: "widget", URL: "git+https://github.com/bosgood/widget.git@v1.0"}
+	if name := dep.GetCanonicalName(); name != "widget @ git+https://github.com/bosgood/widget.git@v1.0" {
+		t.Errorf("Unexpected name %s", name)
+	}
+}
@@ -0,0 +1,344 @@
+package python
+
+import (
+	"bitbucket.org/bosgood/dep-get/lib/fs"
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// RequirementsFileName is the name of pip's requirements file
+const RequirementsFileName = "requirements.txt"
+
+// requirementPattern matches the name, extras and remainder of a PEP 508
+// requirement such as requests[socks]==2.31.0 ; python_version >= "3.7"
+var requirementPattern = regexp.MustCompile(`^([A-Za-z0-9][A-Za-z0-9._-]*)\s*(?:\[([^\]]*)\])?\s*(.*)$`)
+
+// commentPattern matches comments, which start a line or follow whitespace
+var commentPattern = regexp.MustCompile(`(^|\s)#.*$`)
+
+// urlMarkerPattern splits a direct URL from its marker, which PEP 508
+// requires whitespace before so URLs may contain semicolons
+var urlMarkerPattern = regexp.MustCompile(`\s;`)
+
+// requirementsParser reads a requirements file and the files it includes
+type requirementsParser struct {
+	fs      fs.FileSystem
+	file    *DependenciesFile
+	reading map[string]bool
+}
+
+// ReadRequirements reads the pinned requirements in the requirements file
+// at filePath, following -r includes. Each requirement must pin one
+// version with == or ===, or be a direct URL or path.
+func ReadRequirements(fileSystem fs.FileSystem, filePath string) (*DependenciesFile, error) {
+	p := &requirementsParser{
+		fs:      fileSystem,
+		file:    &DependenciesFile{Path: filePath},
+		reading: make(map[string]bool),
+	}
+	if err := p.read(filePath); err != nil {
+		return nil, err
+	}
+	return p.file, nil
+}
+
+// ParseRequirements parses the contents of a requirements file which
+// includes no others
+func ParseRequirements(contents []byte) (*DependenciesFile, error) {
+	p := &requirementsParser{
+		file:    &DependenciesFile{Path: RequirementsFileName},
+		reading: make(map[string]bool),
+	}
+	if err := p.parse(contents, RequirementsFileName); err != nil {
+		return nil, err
+	}
+	return p.file, nil
+}
+
+func (p *requirementsParser) read(filePath string) error {
+	if p.reading[filePath] {
+		return fmt.Errorf("%s includes itself", filePath)
+	}
+	p.reading[filePath] = true
+	defer delete(p.reading, filePath)
+
+	contents, err := p.fs.ReadFile(filePath)
+	if err != nil {
+		return err
+	}
+	return p.parse(contents, filePath)
+}
+
+// logicalLines joins lines continued with a backslash and strips comments
+// and blank lines, returning each line with the number it starts on
+func logicalLines(contents []byte) ([]string, []int) {
+	var lines []string
+	var numbers []int
+	var current strings.Builder
+	start := 0
+	for i, line := range strings.Split(string(contents), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if current.Len() == 0 {
+			start = i + 1
+		}
+		line = commentPattern.ReplaceAllString(line, "")
+		if strings.HasSuffix(line, "\\") {
+			current.WriteString(strings.TrimSuffix(line, "\\"))
+			continue
+		}
+		current.WriteString(line)
+		if text := strings.TrimSpace(current.String()); text != "" {
+			lines = append(lines, text)
+			numbers = append(numbers, start)
+		}
+		current.Reset()
+	}
+	if text := strings.TrimSpace(current.String()); text != "" {
+		lines = append(lines, text)
+		numbers = append(numbers, start)
+	}
+	return lines, numbers
+}
+
+// optionValue returns the value of an option given as --name=value,
+// --name value, -n value or -nvalue, and the number of fields it used
+func optionValue(fields []string) (string, int) {
+	option := fields[0]
+	if strings.HasPrefix(option, "--") {
+		if i := strings.Index(option, "="); i >= 0 {
+			return option[i+1:], 1
+		}
+	} else if len(option) > 2 {
+		return option[2:], 1
+	}
+	if len(fields) < 2 {
+		return "", 1
+	}
+	return fields[1], 2
+}
+
+// optionName returns the name of an option field such as --hash=sha256:x
+func optionName(field string) string {
+	if strings.HasPrefix(field, "--") {
+		return strings.SplitN(field, "=", 2)[0]
+	}
+	if len(field) > 2 {
+		return field[:2]
+	}
+	return field
+}
+
+func (p *requirementsParser) parse(contents []byte, filePath string) error {
+	lines, numbers := logicalLines(contents)
+	for i, line := range lines {
+		if err := p.parseLine(line, filePath); err != nil {
+			return fmt.Errorf("%s:%d: %s", filePath, numbers[i], err)
+		}
+	}
+	return nil
+}
+
+func (p *requirementsParser) parseLine(line, filePath string) error {
+	fields := strings.Fields(line)
+	if !strings.HasPrefix(fields[0], "-") {
+		return p.parseRequirementLine(fields)
+	}
+
+	value, _ := optionValue(fields)
+	switch optionName(fields[0]) {
+	case "-r", "--requirement":
+		if value == "" {
+			return fmt.Errorf("Missing file to include: %s", line)
+		}
+		if p.fs == nil {
+			return fmt.Errorf("Can't include %s", value)
+		}
+		if !path.IsAbs(value) {
+			value = path.Join(path.Dir(filePath), value)
+		}
+		return p.read(value)
+	case "-i", "--index-url":
+		p.file.IndexURL = value
+	case "--extra-index-url":
+		p.file.ExtraIndexURLs = append(p.file.ExtraIndexURLs, value)
+	case "-e", "--editable":
+		dep, err := parseDirectRequirement(value)
+		if err != nil {
+			return err
+		}
+		dep.Editable = true
+		p.file.Dependencies = append(p.file.Dependencies, dep)
+	}
+	// Every other option, such as constraint files, --find-links and
+	// --require-hashes, changes nothing about what's downloaded
+	return nil
+}
+
+// parseRequirementLine parses a requirement followed by its options,
+// such as --hash=sha256:...
+func (p *requirementsParser) parseRequirementLine(fields []string) error {
+	var requirement []string
+	var hashes []string
+	for i := 0; i < len(fields); {
+		if !strings.HasPrefix(fields[i], "--") {
+			requirement = append(requirement, fields[i])
+			i++
+			continue
+		}
+		value, n := optionValue(fields[i:])
+		if optionName(fields[i]) == "--hash" {
+			hashes = append(hashes, value)
+		}
+		i += n
+	}
+
+	dep, err := ParseRequirement(strings.Join(requirement, " "))
+	if err != nil {
+		return err
+	}
+	for _, hash := range hashes {
+		if !strings.Contains(hash, ":") {
+			return fmt.Errorf("Malformed hash, expected ALGORITHM:HEX: %s", hash)
+		}
+		dep.Hashes = append(dep.Hashes, hash)
+	}
+	p.file.Dependencies = append(p.file.Dependencies, dep)
+	return nil
+}
+
+// isDirectReference reports whether a requirement is a bare URL or path
+// rather than starting with a project name
+func isDirectReference(requirement string) bool {
+	return strings.Contains(strings.Fields(requirement)[0], "://") ||
+		strings.HasPrefix(requirement, ".") ||
+		strings.HasPrefix(requirement, "/") ||
+		strings.HasPrefix(requirement, "file:")
+}
+
+// ParseRequirement parses a single PEP 508 requirement, which must either
+// pin a version, as in requests[socks]==2.31.0, or name a URL, as in
+// pip @ https://example.com/pip-23.0-py3-none-any.whl
+func ParseRequirement(requirement string) (PythonDependency, error) {
+	if isDirectReference(requirement) {
+		return parseDirectRequirement(requirement)
+	}
+
+	m := requirementPattern.FindStringSubmatch(requirement)
+	if m == nil {
+		return PythonDependency{}, fmt.Errorf("Malformed requirement: %s", requirement)
+	}
+	dep := PythonDependency{Name: m[1]}
+	for _, extra := range strings.Split(m[2], ",") {
+		if extra = strings.TrimSpace(extra); extra != "" {
+			dep.Extras = append(dep.Extras, extra)
+		}
+	}
+
+	rest := strings.TrimSpace(m[3])
+	if strings.HasPrefix(rest, "@") {
+		rest = strings.TrimSpace(strings.TrimPrefix(rest, "@"))
+		parts := urlMarkerPattern.Split(rest, 2)
+		if err := dep.setURL(strings.TrimSpace(parts[0])); err != nil {
+			return dep, err
+		}
+		if len(parts) == 2 {
+			dep.Marker = strings.TrimSpace(parts[1])
+		}
+		return dep, dep.checkMarker()
+	}
+
+	specifiers := rest
+	if i := strings.Index(rest, ";"); i >= 0 {
+		specifiers = rest[:i]
+		dep.Marker = strings.TrimSpace(rest[i+1:])
+	}
+	specifiers = strings.TrimSpace(specifiers)
+	specifiers = strings.TrimSuffix(strings.TrimPrefix(specifiers, "("), ")")
+	for _, specifier := range strings.Split(specifiers, ",") {
+		specifier = strings.TrimSpace(specifier)
+		switch {
+		case strings.HasPrefix(specifier, "==="):
+			dep.Version = strings.TrimSpace(strings.TrimPrefix(specifier, "==="))
+		case strings.HasPrefix(specifier, "=="):
+			version := strings.TrimSpace(strings.TrimPrefix(specifier, "=="))
+			if !strings.HasSuffix(version, "*") {
+				dep.Version = version
+			}
+		}
+	}
+	if dep.Version == "" {
+		return dep, fmt.Errorf("%s isn't pinned to a version with ==", requirement)
+	}
+	return dep, dep.checkMarker()
+}
+
+// checkMarker makes sure the dependency's marker parses
+func (d *PythonDependency) checkMarker() error {
+	_, err := d.AppliesTo(Environment{})
+	return err
+}
+
+// parseDirectRequirement parses a bare URL or path, optionally naming its
+// project with an #egg= fragment, as -e options and old requirements
+// files give them
+func parseDirectRequirement(requirement string) (PythonDependency, error) {
+	dep := PythonDependency{}
+	parts := urlMarkerPattern.Split(strings.TrimSpace(requirement), 2)
+	if len(parts) == 2 {
+		dep.Marker = strings.TrimSpace(parts[1])
+	}
+	if err := dep.setURL(strings.TrimSpace(parts[0])); err != nil {
+		return dep, err
+	}
+
+	if dep.Name == "" {
+		return dep, fmt.Errorf("Can't tell the project name of %s, add #egg=NAME", requirement)
+	}
+	return dep, dep.checkMarker()
+}
+
+// setURL records where a direct URL requirement comes from: a local path,
+// a version control URL or an archive to download, whose name tells the
+// version. Hashes and project names in the URL's fragment are taken too.
+func (d *PythonDependency) setURL(reference string) error {
+	base, fragment := reference, ""
+	if i := strings.Index(reference, "#"); i >= 0 {
+		base, fragment = reference[:i], reference[i+1:]
+	}
+	values, err := url.ParseQuery(fragment)
+	if err != nil {
+		return fmt.Errorf("Malformed URL fragment: %s", reference)
+	}
+	if egg := values.Get("egg"); egg != "" && d.Name == "" {
+		d.Name = strings.SplitN(egg, "[", 2)[0]
+	}
+	for _, algorithm := range []string{"sha256", "sha384", "sha512"} {
+		if sum := values.Get(algorithm); sum != "" {
+			d.Hashes = append(d.Hashes, algorithm+":"+sum)
+		}
+	}
+
+	switch {
+	case strings.HasPrefix(base, "file:"):
+		d.LocalPath = strings.TrimPrefix(strings.TrimPrefix(base, "file:"), "//")
+	case !strings.Contains(base, "://"):
+		d.LocalPath = base
+	default:
+		d.URL = base
+	}
+	if d.LocalPath != "" || d.IsVCS() {
+		return nil
+	}
+
+	name, version, ok := ParseDistributionFileName(FileNameFromURL(d.URL), d.Name)
+	if ok {
+		if d.Name == "" {
+			d.Name = name
+		}
+		d.Version = version
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package python
+
+import (
+	"bitbucket.org/bosgood/dep-get/lib/fs"
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var requirementsTxt = []byte(`# Pinned with pip-compile
+--index-url https://pypi.internal/simple/
+--extra-index-url=https://pypi.org/simple/
+
+certifi==2023.7.22 \
+    --hash=sha256:539cc1d13202e33ca466e88b2807e29f4c13049d6d87031a3c110744495cb082 \
+    --hash=sha256:92d6037539857d8206b8f6ae472e8b77db8058fec5937a1ef3f54304089edbb9
+    # via requests
+requests[socks, security]==2.31.0  # the one we came for
+importlib-metadata==6.8.0 ; python_version < "3.8"
+pip @ https://files.example.com/pip-23.2.1-py3-none-any.whl#sha256=7ccf472345f20d35bdc9d1841ff5f313260c2c33fe417f48c30ac46cccabf5be
+https://files.example.com/six-1.16.0.tar.gz#egg=six
+-e ./libs/shared#egg=shared
+-e git+https://github.com/bosgood/widget.git@v1.0#egg=widget
+-c constraints.txt
+--require-hashes
+`)
+
+func TestParseRequirements(t *testing.T) {
+	file, err := ParseRequirements(requirementsTxt)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if file.IndexURL != "https://pypi.internal/simple/" {
+		t.Errorf("Unexpected index URL %s", file.IndexURL)
+	}
+	if !reflect.DeepEqual(file.ExtraIndexURLs, []string{"https://pypi.org/simple/"}) {
+		t.Errorf("Unexpected extra index URLs %q", file.ExtraIndexURLs)
+	}
+
+	expected := []PythonDependency{
+		{
+			Name:    "certifi",
+			Version: "2023.7.22",
+			Hashes: []string{
+				"sha256:539cc1d13202e33ca466e88b2807e29f4c13049d6d87031a3c110744495cb082",
+				"sha256:92d6037539857d8206b8f6ae472e8b77db8058fec5937a1ef3f54304089edbb9",
+			},
+		},
+		{Name: "requests", Version: "2.31.0", Extras: []string{"socks", "security"}},
+		{Name: "importlib-metadata", Version: "6.8.0", Marker: `python_version < "3.8"`},
+		{
+			Name:    "pip",
+			Version: "23.2.1",
+			URL:     "https://files.example.com/pip-23.2.1-py3-none-any.whl",
+			Hashes:  []string{"sha256:7ccf472345f20d35bdc9d1841ff5f313260c2c33fe417f48c30ac46cccabf5be"},
+		},
+		{Name: "six", Version: "1.16.0", URL: "https://files.example.com/six-1.16.0.tar.gz"},
+		{Name: "shared", LocalPath: "./libs/shared", Editable: true},
+		{Name: "widget", URL: "git+https://github.com/bosgood/widget.git@v1.0", Editable: true},
+	}
+	if len(file.Dependencies) != len(expected) {
+		t.Fatalf("Expected %d dependencies, got %+v", len(expected), file.Dependencies)
+	}
+	for i, dep := range file.Dependencies {
+		if !reflect.DeepEqual(dep, expected[i]) {
+			t.Errorf("Expected %+v, got %+v", expected[i], dep)
+		}
+	}
+	if !file.Dependencies[6].IsVCS() || file.Dependencies[3].IsVCS() {
+		t.Errorf("Expected only widget to come from version control")
+	}
+}
+
+func TestParseRequirementsErrors(t *testing.T) {
+	cases := []string{
+		"requests>=2.0\n",
+		"requests==2.*\n",
+		"requests\n",
+		"requests==2.31.0 ; python_version <\n",
+		"https://files.example.com/download?id=1\n",
+		"requests==2.31.0 --hash=abc\n",
+	}
+	for _, contents := range cases {
+		_, err := ParseRequirements([]byte(contents))
+		if err == nil {
+			t.Errorf("Expected an error for %q", contents)
+			continue
+		}
+		if !strings.HasPrefix(err.Error(), RequirementsFileName+":1: ") {
+			t.Errorf("Expected the error to give the line: %s", err)
+		}
+	}
+}
+
+func TestReadRequirementsIncludes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dep-get-python")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	os.MkdirAll(path.Join(dir, "requirements"), 0755)
+	ioutil.WriteFile(path.Join(dir, "requirements.txt"), []byte("-r requirements/base.txt\npytest==7.4.0\n"), 0644)
+	ioutil.WriteFile(path.Join(dir, "requirements/base.txt"), []byte("--requirement=common.txt\nflask==2.3.3\n"), 0644)
+	ioutil.WriteFile(path.Join(dir, "requirements/common.txt"), []byte("click==8.1.7\n"), 0644)
+
+	file, err := ReadDependencies(&fs.OSFS{}, dir)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	var names []string
+	for _, dep := range file.Dependencies {
+		names = append(names, dep.GetCanonicalName())
+	}
+	expected := []string{"click==8.1.7", "flask==2.3.3", "pytest==7.4.0"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected %q, got %q", expected, names)
+	}
+
+	ioutil.WriteFile(path.Join(dir, "requirements/common.txt"), []byte("-r base.txt\n"), 0644)
+	if _, err = ReadDependencies(&fs.OSFS{}, dir); err == nil || !strings.Contains(err.Error(), "includes itself") {
+		t.Errorf("Expected an include cycle error, got %v", err)
+	}
+}
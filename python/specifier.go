@@ -0,0 +1,86 @@
+package python
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// specifierPattern splits a clause of a PEP 440 version specifier set,
+// such as >=3.8 or !=3.0.*, into its operator and version
+var specifierPattern = regexp.MustCompile(`^(~=|===|==|!=|<=|>=|<|>)\s*(\S+)$`)
+
+// releaseNumbers returns the release numbers of version as written,
+// keeping the trailing zeros parseVersion drops
+func releaseNumbers(version string) ([]int, bool) {
+	m := versionRegexp.FindStringSubmatch(strings.TrimPrefix(strings.ToLower(strings.TrimSpace(version)), "v"))
+	if m == nil {
+		return nil, false
+	}
+	var numbers []int
+	for _, part := range strings.Split(m[2], ".") {
+		n, _ := strconv.Atoi(part)
+		numbers = append(numbers, n)
+	}
+	return numbers, true
+}
+
+// matchesPrefix reports whether version's release starts with prefix, so
+// that 3.0.1 matches ==3.0.* and 3.10 doesn't. Missing numbers of version
+// are zeros.
+func matchesPrefix(version, prefix string) bool {
+	v, ok := releaseNumbers(version)
+	p, pok := releaseNumbers(prefix)
+	if !ok || !pok {
+		return false
+	}
+	for i, n := range p {
+		var x int
+		if i < len(v) {
+			x = v[i]
+		}
+		if x != n {
+			return false
+		}
+	}
+	return true
+}
+
+// MatchesSpecifiers reports whether version satisfies every clause of a
+// PEP 440 specifier set such as >=2.7, !=3.0.*, as Requires-Python is
+// written. An empty set matches every version.
+func MatchesSpecifiers(version, specifiers string) (bool, error) {
+	for _, clause := range strings.Split(specifiers, ",") {
+		clause = strings.TrimSpace(clause)
+		if clause == "" {
+			continue
+		}
+		m := specifierPattern.FindStringSubmatch(clause)
+		if m == nil {
+			return false, fmt.Errorf("Malformed version specifier %s", clause)
+		}
+		op, spec := m[1], m[2]
+
+		var matches bool
+		switch {
+		case op == "===":
+			matches = version == spec
+		case strings.HasSuffix(spec, ".*") && (op == "==" || op == "!="):
+			matches = matchesPrefix(version, strings.TrimSuffix(spec, ".*")) == (op == "==")
+		default:
+			if _, ok := parseVersion(spec); !ok {
+				return false, fmt.Errorf("Malformed version specifier %s", clause)
+			}
+			if op == "~=" {
+				matches = compatibleRelease(version, spec)
+			} else {
+				matches = compareResult(compareVersions(version, spec), op)
+			}
+		}
+		if !matches {
+			return false, nil
+		}
+	}
+	return true, nil
+}
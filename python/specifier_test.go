@@ -0,0 +1,38 @@
+package python
+
+import (
+	"testing"
+)
+
+func TestMatchesSpecifiers(t *testing.T) {
+	tests := []struct {
+		version    string
+		specifiers string
+		expected   bool
+	}{
+		{"3.11", "", true},
+		{"3.11", ">=3.8", true},
+		{"3.7", ">=3.8", false},
+		{"2.7", ">=2.7, !=3.0.*, !=3.1.*", true},
+		{"3.0", ">=2.7, !=3.0.*, !=3.1.*", false},
+		{"3.10", ">=2.7, !=3.0.*, !=3.1.*", true},
+		{"3.12", ">=3.8,<3.12", false},
+		{"3.9", "~=3.8", true},
+		{"4.0", "~=3.8", false},
+		{"3.11", "==3.*", true},
+		{"3.11", "===3.11", true},
+	}
+	for _, test := range tests {
+		matches, err := MatchesSpecifiers(test.version, test.specifiers)
+		if err != nil {
+			t.Fatalf("%s: %s", test.specifiers, err)
+		}
+		if matches != test.expected {
+			t.Errorf("Expected %s in %q to be %t", test.version, test.specifiers, test.expected)
+		}
+	}
+
+	if _, err := MatchesSpecifiers("3.11", ">=three"); err == nil {
+		t.Errorf("Expected an error for a malformed specifier")
+	}
+}
@@ -0,0 +1,243 @@
+package python
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Tag is a PEP 425 compatibility tag, such as cp311-cp311-manylinux_2_28_x86_64,
+// naming the interpreter, ABI and platform a wheel is built for
+type Tag struct {
+	Interpreter string
+	ABI         string
+	Platform    string
+}
+
+func (t Tag) String() string {
+	return t.Interpreter + "-" + t.ABI + "-" + t.Platform
+}
+
+// ParseTag parses an interpreter-abi-platform tag naming the environment
+// distributions are installed in
+func ParseTag(tag string) (Tag, error) {
+	parts := strings.Split(strings.ToLower(tag), "-")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return Tag{}, fmt.Errorf("Malformed tag %s (expected INTERPRETER-ABI-PLATFORM, such as cp311-cp311-manylinux_2_28_x86_64)", tag)
+	}
+	for _, part := range parts {
+		if strings.Contains(part, ".") {
+			return Tag{}, fmt.Errorf("Malformed tag %s (name one interpreter, ABI and platform)", tag)
+		}
+	}
+	return Tag{Interpreter: parts[0], ABI: parts[1], Platform: parts[2]}, nil
+}
+
+// WheelTags returns the tags of a wheel from its file name, expanding
+// compressed tag sets such as py2.py3-none-any
+func WheelTags(fileName string) []Tag {
+	parts := strings.Split(strings.TrimSuffix(fileName, ".whl"), "-")
+	if !strings.HasSuffix(fileName, ".whl") || (len(parts) != 5 && len(parts) != 6) {
+		return nil
+	}
+	n := len(parts)
+	var tags []Tag
+	for _, interpreter := range strings.Split(strings.ToLower(parts[n-3]), ".") {
+		for _, abi := range strings.Split(strings.ToLower(parts[n-2]), ".") {
+			for _, platform := range strings.Split(strings.ToLower(parts[n-1]), ".") {
+				tags = append(tags, Tag{interpreter, abi, platform})
+			}
+		}
+	}
+	return tags
+}
+
+var interpreterPattern = regexp.MustCompile(`^([a-z]+)(\d)(\d*)$`)
+
+// interpreterVersion returns the python version of an interpreter tag
+// such as cp311, whose minor version is -1 when it only names the major
+// one, as py3 does
+func interpreterVersion(interpreter string) (string, int, int, bool) {
+	m := interpreterPattern.FindStringSubmatch(interpreter)
+	if m == nil {
+		return "", 0, 0, false
+	}
+	major, _ := strconv.Atoi(m[2])
+	minor := -1
+	if m[3] != "" {
+		minor, _ = strconv.Atoi(m[3])
+	}
+	return m[1], major, minor, true
+}
+
+// PythonVersion returns the python version, such as 3.11, the target's
+// interpreter tag names, unless it only names the major version
+func (t Tag) PythonVersion() (string, bool) {
+	_, major, minor, ok := interpreterVersion(t.Interpreter)
+	if !ok || minor < 0 {
+		return "", false
+	}
+	return fmt.Sprintf("%d.%d", major, minor), true
+}
+
+// pythonVersionTags lists the generic interpreter tags of a python
+// version from the most specific, as py311, py3, py310 down to py30
+func pythonVersionTags(major, minor int) []string {
+	if minor < 0 {
+		return []string{fmt.Sprintf("py%d", major)}
+	}
+	versions := []string{fmt.Sprintf("py%d%d", major, minor), fmt.Sprintf("py%d", major)}
+	for m := minor - 1; m >= 0; m-- {
+		versions = append(versions, fmt.Sprintf("py%d%d", major, m))
+	}
+	return versions
+}
+
+var (
+	manylinuxPattern = regexp.MustCompile(`^manylinux_(\d+)_(\d+)_(.+)$`)
+	musllinuxPattern = regexp.MustCompile(`^musllinux_(\d+)_(\d+)_(.+)$`)
+	macosxPattern    = regexp.MustCompile(`^macosx_(\d+)_(\d+)_(.+)$`)
+)
+
+// legacyManylinux gives the glibc versions of the manylinux tags from
+// before PEP 600
+var legacyManylinux = []struct {
+	name  string
+	minor int
+}{
+	{"manylinux2014", 17},
+	{"manylinux2010", 12},
+	{"manylinux1", 5},
+}
+
+// macosxFormats gives the binary formats able to run on a mac
+// architecture, in the order they're preferred
+func macosxFormats(arch string) []string {
+	switch arch {
+	case "x86_64":
+		return []string{"x86_64", "intel", "fat64", "fat32", "universal2", "universal"}
+	case "arm64":
+		return []string{"arm64", "universal2"}
+	}
+	return []string{arch, "universal"}
+}
+
+// compatiblePlatforms lists the platform tags of wheels able to run on
+// platform, from the most specific: manylinux wheels for older glibc
+// versions, followed by their legacy names and linux_ARCH, musllinux
+// wheels for older musl versions, and macosx wheels for older releases
+func compatiblePlatforms(platform string) []string {
+	for _, legacy := range legacyManylinux {
+		if strings.HasPrefix(platform, legacy.name+"_") {
+			platform = fmt.Sprintf("manylinux_2_%d_%s", legacy.minor, strings.TrimPrefix(platform, legacy.name+"_"))
+		}
+	}
+
+	if m := manylinuxPattern.FindStringSubmatch(platform); m != nil && m[1] == "2" {
+		minor, _ := strconv.Atoi(m[2])
+		arch := m[3]
+		oldest := 17
+		if arch == "x86_64" || arch == "i686" {
+			oldest = 5
+		}
+		var platforms []string
+		for v := minor; v >= oldest; v-- {
+			platforms = append(platforms, fmt.Sprintf("manylinux_2_%d_%s", v, arch))
+			for _, legacy := range legacyManylinux {
+				if legacy.minor == v {
+					platforms = append(platforms, legacy.name+"_"+arch)
+				}
+			}
+		}
+		return append(platforms, "linux_"+arch)
+	}
+
+	if m := musllinuxPattern.FindStringSubmatch(platform); m != nil {
+		minor, _ := strconv.Atoi(m[2])
+		var platforms []string
+		for v := minor; v >= 0; v-- {
+			platforms = append(platforms, fmt.Sprintf("musllinux_%s_%d_%s", m[1], v, m[3]))
+		}
+		return append(platforms, "linux_"+m[3])
+	}
+
+	if m := macosxPattern.FindStringSubmatch(platform); m != nil {
+		major, _ := strconv.Atoi(m[1])
+		minor, _ := strconv.Atoi(m[2])
+		formats := macosxFormats(m[3])
+		var platforms []string
+		// Since macOS 11 only major versions count
+		for v := major; v >= 11; v-- {
+			for _, format := range formats {
+				platforms = append(platforms, fmt.Sprintf("macosx_%d_0_%s", v, format))
+			}
+		}
+		if major >= 11 {
+			minor = 16
+			if m[3] == "arm64" {
+				// arm64 macs only run universal2 builds for older releases
+				formats = []string{"universal2"}
+			}
+		}
+		if major >= 10 {
+			for v := minor; v >= 0; v-- {
+				for _, format := range formats {
+					platforms = append(platforms, fmt.Sprintf("macosx_10_%d_%s", v, format))
+				}
+			}
+		}
+		return platforms
+	}
+
+	return []string{platform}
+}
+
+// CompatibleTags lists the tags of wheels installable in the environment
+// target names, from the most preferred as pip orders them: wheels for
+// the interpreter and its ABI, then the stable abi3 and ABI-less ones,
+// abi3 wheels for older CPython releases, generic pyXY wheels for the
+// platform and finally pure python wheels. Within each group, wheels for
+// the most specific platform come first.
+func CompatibleTags(target Tag) []Tag {
+	platforms := compatiblePlatforms(target.Platform)
+	var tags []Tag
+	addAll := func(interpreter, abi string) {
+		for _, platform := range platforms {
+			tags = append(tags, Tag{interpreter, abi, platform})
+		}
+	}
+
+	implementation, major, minor, ok := interpreterVersion(target.Interpreter)
+	if target.ABI != "abi3" && target.ABI != "none" {
+		addAll(target.Interpreter, target.ABI)
+	}
+	// The stable ABI dates from CPython 3.2
+	abi3 := ok && implementation == "cp" && major == 3 && minor >= 2
+	if abi3 {
+		addAll(target.Interpreter, "abi3")
+	}
+	addAll(target.Interpreter, "none")
+	if abi3 {
+		for m := minor - 1; m >= 2; m-- {
+			addAll(fmt.Sprintf("cp3%d", m), "abi3")
+		}
+	}
+	if !ok {
+		return append(tags, Tag{target.Interpreter, "none", "any"})
+	}
+
+	versions := pythonVersionTags(major, minor)
+	for _, version := range versions {
+		if version != target.Interpreter {
+			addAll(version, "none")
+		}
+	}
+	if implementation != "py" {
+		tags = append(tags, Tag{target.Interpreter, "none", "any"})
+	}
+	for _, version := range versions {
+		tags = append(tags, Tag{version, "none", "any"})
+	}
+	return tags
+}
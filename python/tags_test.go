@@ -0,0 +1,139 @@
+package python
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTag(t *testing.T) {
+	tag, err := ParseTag("CP311-cp311-manylinux_2_28_x86_64")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	expected := Tag{"cp311", "cp311", "manylinux_2_28_x86_64"}
+	if tag != expected {
+		t.Errorf("Expected %+v, got %+v", expected, tag)
+	}
+	if tag.String() != "cp311-cp311-manylinux_2_28_x86_64" {
+		t.Errorf("Unexpected string %s", tag)
+	}
+
+	for _, malformed := range []string{"cp311", "cp311-cp311", "cp311--any", "py2.py3-none-any"} {
+		if _, err := ParseTag(malformed); err == nil {
+			t.Errorf("Expected an error parsing %s", malformed)
+		}
+	}
+}
+
+func TestWheelTags(t *testing.T) {
+	tests := []struct {
+		fileName string
+		expected []Tag
+	}{
+		{
+			"six-1.16.0-py2.py3-none-any.whl",
+			[]Tag{{"py2", "none", "any"}, {"py3", "none", "any"}},
+		},
+		{
+			"numpy-1.26.0-1-cp311-cp311-manylinux_2_17_x86_64.manylinux2014_x86_64.whl",
+			[]Tag{
+				{"cp311", "cp311", "manylinux_2_17_x86_64"},
+				{"cp311", "cp311", "manylinux2014_x86_64"},
+			},
+		},
+		{"six-1.16.0.tar.gz", nil},
+	}
+	for _, test := range tests {
+		tags := WheelTags(test.fileName)
+		if !reflect.DeepEqual(tags, test.expected) {
+			t.Errorf("Expected %+v for %s, got %+v", test.expected, test.fileName, tags)
+		}
+	}
+}
+
+func TestCompatiblePlatforms(t *testing.T) {
+	tests := []struct {
+		platform string
+		expected []string
+	}{
+		{
+			"manylinux_2_18_aarch64",
+			[]string{"manylinux_2_18_aarch64", "manylinux_2_17_aarch64", "manylinux2014_aarch64", "linux_aarch64"},
+		},
+		{
+			"manylinux2010_x86_64",
+			[]string{
+				"manylinux_2_12_x86_64", "manylinux2010_x86_64",
+				"manylinux_2_11_x86_64", "manylinux_2_10_x86_64", "manylinux_2_9_x86_64",
+				"manylinux_2_8_x86_64", "manylinux_2_7_x86_64", "manylinux_2_6_x86_64",
+				"manylinux_2_5_x86_64", "manylinux1_x86_64",
+				"linux_x86_64",
+			},
+		},
+		{
+			"musllinux_1_1_x86_64",
+			[]string{"musllinux_1_1_x86_64", "musllinux_1_0_x86_64", "linux_x86_64"},
+		},
+		{
+			"macosx_10_1_x86_64",
+			[]string{
+				"macosx_10_1_x86_64", "macosx_10_1_intel", "macosx_10_1_fat64",
+				"macosx_10_1_fat32", "macosx_10_1_universal2", "macosx_10_1_universal",
+				"macosx_10_0_x86_64", "macosx_10_0_intel", "macosx_10_0_fat64",
+				"macosx_10_0_fat32", "macosx_10_0_universal2", "macosx_10_0_universal",
+			},
+		},
+		{"win_amd64", []string{"win_amd64"}},
+	}
+	for _, test := range tests {
+		platforms := compatiblePlatforms(test.platform)
+		if !reflect.DeepEqual(platforms, test.expected) {
+			t.Errorf("Expected %q for %s, got %q", test.expected, test.platform, platforms)
+		}
+	}
+
+	platforms := compatiblePlatforms("macosx_12_0_arm64")
+	expected := []string{
+		"macosx_12_0_arm64", "macosx_12_0_universal2",
+		"macosx_11_0_arm64", "macosx_11_0_universal2",
+		"macosx_10_16_universal2",
+	}
+	if !reflect.DeepEqual(platforms[:5], expected) {
+		t.Errorf("Expected %q first, got %q", expected, platforms[:5])
+	}
+}
+
+func TestCompatibleTags(t *testing.T) {
+	tags := CompatibleTags(Tag{"cp33", "cp33m", "win_amd64"})
+	expected := []Tag{
+		{"cp33", "cp33m", "win_amd64"},
+		{"cp33", "abi3", "win_amd64"},
+		{"cp33", "none", "win_amd64"},
+		{"cp32", "abi3", "win_amd64"},
+		{"py33", "none", "win_amd64"},
+		{"py3", "none", "win_amd64"},
+		{"py32", "none", "win_amd64"},
+		{"py31", "none", "win_amd64"},
+		{"py30", "none", "win_amd64"},
+		{"cp33", "none", "any"},
+		{"py33", "none", "any"},
+		{"py3", "none", "any"},
+		{"py32", "none", "any"},
+		{"py31", "none", "any"},
+		{"py30", "none", "any"},
+	}
+	if !reflect.DeepEqual(tags, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, tags)
+	}
+
+	tags = CompatibleTags(Tag{"pp39", "pypy39_pp73", "linux_x86_64"})
+	expected = []Tag{
+		{"pp39", "pypy39_pp73", "linux_x86_64"},
+		{"pp39", "none", "linux_x86_64"},
+		{"py39", "none", "linux_x86_64"},
+		{"py3", "none", "linux_x86_64"},
+	}
+	if !reflect.DeepEqual(tags[:4], expected) {
+		t.Errorf("Expected %+v first, got %+v", expected, tags[:4])
+	}
+}
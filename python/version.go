@@ -0,0 +1,127 @@
+package python
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// pep440Version is a version split into its release numbers and the rest,
+// such as 1.2.0rc1 into [1 2 0] and rc1
+type pep440Version struct {
+	epoch   int
+	release []int
+	suffix  string
+}
+
+var versionRegexp = regexp.MustCompile(`^(?:(\d+)!)?(\d+(?:\.\d+)*)(.*)$`)
+
+// suffixSpellings maps the alternative spellings PEP 440 allows for
+// prerelease and postrelease markers to their normal forms
+var suffixSpellings = strings.NewReplacer(
+	"rc", "rc",
+	"alpha", "a",
+	"beta", "b",
+	"preview", "rc",
+	"pre", "rc",
+	"c", "rc",
+	"rev", "post",
+	"r", "post",
+	"-", "",
+	"_", "",
+	".", "",
+)
+
+func parseVersion(version string) (pep440Version, bool) {
+	v := pep440Version{}
+	s := strings.TrimPrefix(strings.ToLower(strings.TrimSpace(version)), "v")
+	m := versionRegexp.FindStringSubmatch(s)
+	if m == nil {
+		return v, false
+	}
+	if m[1] != "" {
+		v.epoch, _ = strconv.Atoi(m[1])
+	}
+	for _, part := range strings.Split(m[2], ".") {
+		n, _ := strconv.Atoi(part)
+		v.release = append(v.release, n)
+	}
+	// Trailing zeros don't change a version, so 1.0 equals 1.0.0
+	for len(v.release) > 1 && v.release[len(v.release)-1] == 0 {
+		v.release = v.release[:len(v.release)-1]
+	}
+
+	suffix, local := m[3], ""
+	if i := strings.Index(suffix, "+"); i >= 0 {
+		suffix, local = suffix[:i], suffix[i:]
+	}
+	suffix = suffixSpellings.Replace(suffix)
+	v.suffix = suffix + strings.Replace(local, "_", ".", -1)
+	return v, true
+}
+
+// compareVersions orders two versions by epoch and release numbers and
+// then, roughly, by what follows: dev releases and prereleases come
+// before the release, and postreleases after it. Versions which can't
+// be parsed are compared as strings.
+func compareVersions(a, b string) int {
+	va, okA := parseVersion(a)
+	vb, okB := parseVersion(b)
+	if !okA || !okB {
+		return strings.Compare(a, b)
+	}
+	if va.epoch != vb.epoch {
+		return compareInts(va.epoch, vb.epoch)
+	}
+	for i := 0; i < len(va.release) || i < len(vb.release); i++ {
+		var x, y int
+		if i < len(va.release) {
+			x = va.release[i]
+		}
+		if i < len(vb.release) {
+			y = vb.release[i]
+		}
+		if x != y {
+			return compareInts(x, y)
+		}
+	}
+	if c := compareInts(suffixRank(va.suffix), suffixRank(vb.suffix)); c != 0 {
+		return c
+	}
+	return strings.Compare(va.suffix, vb.suffix)
+}
+
+// suffixRank places dev releases and prereleases below a release and
+// postreleases above it
+func suffixRank(suffix string) int {
+	switch {
+	case suffix == "" || strings.HasPrefix(suffix, "+"):
+		return 2
+	case strings.HasPrefix(suffix, "dev"):
+		return 0
+	case strings.HasPrefix(suffix, "post"):
+		return 3
+	}
+	return 1
+}
+
+func compareInts(a, b int) int {
+	if a < b {
+		return -1
+	}
+	if a > b {
+		return 1
+	}
+	return 0
+}
+
+// VersionsEqual reports whether two versions are the same release under
+// PEP 440's normalization, as 1.0 and 1.0.0 or 1.0c1 and 1.0rc1 are
+func VersionsEqual(a, b string) bool {
+	_, okA := parseVersion(a)
+	_, okB := parseVersion(b)
+	if !okA || !okB {
+		return a == b
+	}
+	return compareVersions(a, b) == 0
+}
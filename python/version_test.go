@@ -0,0 +1,41 @@
+package python
+
+import (
+	"testing"
+)
+
+func TestCompareVersions(t *testing.T) {
+	cases := []struct {
+		a, b     string
+		expected int
+	}{
+		{"1.0", "1.0.0", 0},
+		{"v1.2", "1.2", 0},
+		{"1.2", "1.10", -1},
+		{"1!0.1", "2.0", 1},
+		{"1.0rc1", "1.0c1", 0},
+		{"1.0-alpha.1", "1.0a1", 0},
+		{"1.0.dev1", "1.0a1", -1},
+		{"1.0a1", "1.0", -1},
+		{"1.0", "1.0.post1", -1},
+		{"1.0-r1", "1.0post1", 0},
+		{"1.0", "1.0+local.1", -1},
+	}
+	for _, c := range cases {
+		if cmp := compareVersions(c.a, c.b); cmp != c.expected {
+			t.Errorf("Expected %s vs %s to compare %d, got %d", c.a, c.b, c.expected, cmp)
+		}
+	}
+}
+
+func TestVersionsEqual(t *testing.T) {
+	if !VersionsEqual("2.31", "2.31.0") {
+		t.Errorf("Expected 2.31 to equal 2.31.0")
+	}
+	if VersionsEqual("2.31.0", "2.31.1") {
+		t.Errorf("Expected 2.31.0 not to equal 2.31.1")
+	}
+	if VersionsEqual("1.0", "1.0+local") {
+		t.Errorf("Expected a local version not to equal its release")
+	}
+}
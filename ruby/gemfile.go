@@ -0,0 +1,120 @@
+package ruby
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// GemfileLockFileName is the name of Bundler's lock file
+const GemfileLockFileName = "Gemfile.lock"
+
+var (
+	// specPattern matches the gems under specs:, such as
+	// nokogiri (1.15.0-x86_64-linux)
+	specPattern = regexp.MustCompile(`^    ([^ ]+) \(([^)]+)\)$`)
+	// checksumPattern matches the gems under CHECKSUMS, along with their
+	// checksums, which gems from git or a path don't have
+	checksumPattern = regexp.MustCompile(`^  ([^ ]+) \(([^)]+)\)(?: (.+))?$`)
+)
+
+// splitVersion splits a locked version such as 1.15.0-x86_64-linux into
+// the gem's version and platform. Gem versions never hold a dash.
+func splitVersion(version string) (string, string) {
+	if i := strings.Index(version, "-"); i >= 0 {
+		return version[:i], version[i+1:]
+	}
+	return version, ""
+}
+
+// ParseGemfileLock parses a Gemfile.lock file into the gems of its GEM,
+// GIT and PATH sections, the platforms it was resolved for and, when it
+// has a CHECKSUMS section, the checksums of each gem
+func ParseGemfileLock(contents []byte) (*DependenciesFile, error) {
+	file := &DependenciesFile{Path: GemfileLockFileName}
+	checksums := make(map[string][]string)
+
+	section := ""
+	var source GemDependency
+	inSpecs := false
+	for i, line := range strings.Split(strings.Replace(string(contents), "\r\n", "\n", -1), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if !strings.HasPrefix(line, " ") {
+			section = strings.TrimSpace(line)
+			source = GemDependency{}
+			inSpecs = false
+			continue
+		}
+
+		switch section {
+		case "GEM", "GIT", "PATH":
+			trimmed := strings.TrimSpace(line)
+			if !inSpecs {
+				if trimmed == "specs:" {
+					inSpecs = true
+					continue
+				}
+				j := strings.Index(trimmed, ": ")
+				if j < 0 {
+					continue
+				}
+				value := trimmed[j+2:]
+				switch trimmed[:j] {
+				case "remote":
+					// Old lockfiles may list several remotes, tried in order
+					if section == "GEM" && source.Remote != "" {
+						continue
+					}
+					switch section {
+					case "GEM":
+						source.Remote = value
+					case "GIT":
+						source.Git = value
+					case "PATH":
+						source.Path = value
+					}
+				case "revision":
+					source.Revision = value
+				}
+				continue
+			}
+
+			// Dependencies of each spec are indented further, and only
+			// matter to Bundler's resolution
+			m := specPattern.FindStringSubmatch(line)
+			if m == nil {
+				if strings.HasPrefix(line, "      ") {
+					continue
+				}
+				return nil, fmt.Errorf("%s:%d: Malformed spec %q", GemfileLockFileName, i+1, trimmed)
+			}
+			dep := source
+			dep.Name = m[1]
+			dep.Version, dep.Platform = splitVersion(m[2])
+			file.Dependencies = append(file.Dependencies, dep)
+
+		case "PLATFORMS":
+			file.Platforms = append(file.Platforms, strings.TrimSpace(line))
+
+		case "CHECKSUMS":
+			m := checksumPattern.FindStringSubmatch(line)
+			if m == nil {
+				return nil, fmt.Errorf("%s:%d: Malformed checksum %q", GemfileLockFileName, i+1, strings.TrimSpace(line))
+			}
+			if m[3] == "" {
+				continue
+			}
+			for _, checksum := range strings.Split(m[3], ",") {
+				checksums[m[1]+"-"+m[2]] = append(checksums[m[1]+"-"+m[2]], strings.TrimSpace(checksum))
+			}
+		}
+	}
+
+	for i := range file.Dependencies {
+		dep := &file.Dependencies[i]
+		dep.Checksums = checksums[dep.FullName()]
+	}
+	return file, nil
+}
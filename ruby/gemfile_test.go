@@ -0,0 +1,119 @@
+package ruby
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var gemfileLock = []byte(`GIT
+  remote: https://github.com/bosgood/widget.git
+  revision: 9d2c1e4f0a7b3c5d6e8f9a0b1c2d3e4f5a6b7c8d
+  branch: main
+  specs:
+    widget (1.0.0)
+      rack (>= 2.0)
+
+PATH
+  remote: engines/billing
+  specs:
+    billing (0.1.0)
+
+GEM
+  remote: https://rubygems.org/
+  specs:
+    mini_portile2 (2.8.4)
+    nokogiri (1.15.0)
+      mini_portile2 (~> 2.8.2)
+      racc (~> 1.4)
+    nokogiri (1.15.0-arm64-darwin)
+      racc (~> 1.4)
+    nokogiri (1.15.0-x86_64-linux)
+      racc (~> 1.4)
+    racc (1.7.1)
+    rack (3.0.8)
+
+GEM
+  remote: https://gems.internal/
+  specs:
+    ourco-auth (2.3.0)
+
+PLATFORMS
+  arm64-darwin-22
+  ruby
+  x86_64-linux
+
+DEPENDENCIES
+  billing!
+  nokogiri
+  ourco-auth!
+  widget!
+
+CHECKSUMS
+  billing (0.1.0)
+  nokogiri (1.15.0-x86_64-linux) sha256=8a4f2b1e5c6d7f8091a2b3c4d5e6f708192a3b4c5d6e7f8091a2b3c4d5e6f708
+  racc (1.7.1) sha256=af64124836fdd3c00e830703d7f873ea5deabde923f37006a39f5a5e0da16387
+
+RUBY VERSION
+   ruby 3.2.2p53
+
+BUNDLED WITH
+   2.5.0
+`)
+
+func TestParseGemfileLock(t *testing.T) {
+	file, err := ParseGemfileLock(gemfileLock)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	rubygems := "https://rubygems.org/"
+	expected := []GemDependency{
+		{
+			Name:     "widget",
+			Version:  "1.0.0",
+			Git:      "https://github.com/bosgood/widget.git",
+			Revision: "9d2c1e4f0a7b3c5d6e8f9a0b1c2d3e4f5a6b7c8d",
+		},
+		{Name: "billing", Version: "0.1.0", Path: "engines/billing"},
+		{Name: "mini_portile2", Version: "2.8.4", Remote: rubygems},
+		{Name: "nokogiri", Version: "1.15.0", Remote: rubygems},
+		{Name: "nokogiri", Version: "1.15.0", Platform: "arm64-darwin", Remote: rubygems},
+		{
+			Name:      "nokogiri",
+			Version:   "1.15.0",
+			Platform:  "x86_64-linux",
+			Remote:    rubygems,
+			Checksums: []string{"sha256=8a4f2b1e5c6d7f8091a2b3c4d5e6f708192a3b4c5d6e7f8091a2b3c4d5e6f708"},
+		},
+		{
+			Name:      "racc",
+			Version:   "1.7.1",
+			Remote:    rubygems,
+			Checksums: []string{"sha256=af64124836fdd3c00e830703d7f873ea5deabde923f37006a39f5a5e0da16387"},
+		},
+		{Name: "rack", Version: "3.0.8", Remote: rubygems},
+		{Name: "ourco-auth", Version: "2.3.0", Remote: "https://gems.internal/"},
+	}
+	if !reflect.DeepEqual(file.Dependencies, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, file.Dependencies)
+	}
+	if !reflect.DeepEqual(file.Platforms, []string{"arm64-darwin-22", "ruby", "x86_64-linux"}) {
+		t.Errorf("Unexpected platforms %q", file.Platforms)
+	}
+}
+
+func TestParseGemfileLockErrors(t *testing.T) {
+	tests := []struct {
+		contents string
+		expected string
+	}{
+		{"GEM\n  remote: https://rubygems.org/\n  specs:\n    rack 3.0.8\n", "Gemfile.lock:4: Malformed spec"},
+		{"CHECKSUMS\n  rack\n", "Gemfile.lock:2: Malformed checksum"},
+	}
+	for _, test := range tests {
+		_, err := ParseGemfileLock([]byte(test.contents))
+		if err == nil || !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("Expected an error containing %q, got %v", test.expected, err)
+		}
+	}
+}
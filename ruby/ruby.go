@@ -0,0 +1,217 @@
+package ruby
+
+import (
+	"bitbucket.org/bosgood/dep-get/lib/download"
+	"bitbucket.org/bosgood/dep-get/lib/fs"
+	"bitbucket.org/bosgood/dep-get/lib/integrity"
+	"bufio"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// DefaultRemote is the rubygems.org source
+const DefaultRemote = "https://rubygems.org/"
+
+// GemDependency is a gem locked in a Gemfile.lock. Gems from a GEM
+// section are downloaded from their Remote, while those from GIT and
+// PATH sections are checked out of the Git repository at Revision or
+// read from the Path on disk.
+//
+// Platform is empty for pure ruby gems, and otherwise names the platform
+// a precompiled gem is built for, such as x86_64-linux. Checksums are
+// those the lockfile's CHECKSUMS section gives, written ALGORITHM=HEX.
+type GemDependency struct {
+	Name      string
+	Version   string
+	Platform  string
+	Remote    string
+	Git       string
+	Revision  string
+	Path      string
+	Checksums []string
+}
+
+// FullName returns the gem's name, version and platform as rubygems
+// names its .gem file, such as nokogiri-1.15.0-x86_64-linux
+func (d *GemDependency) FullName() string {
+	if d.Platform == "" || d.Platform == "ruby" {
+		return d.Name + "-" + d.Version
+	}
+	return d.Name + "-" + d.Version + "-" + d.Platform
+}
+
+// GetCanonicalName returns a unique name for the gem at this version and
+// platform
+func (d *GemDependency) GetCanonicalName() string {
+	return d.FullName()
+}
+
+// IsLocal reports whether the gem is read from a directory on disk
+func (d *GemDependency) IsLocal() bool {
+	return d.Path != ""
+}
+
+// IsGit reports whether the gem is checked out of a Git repository
+func (d *GemDependency) IsGit() bool {
+	return d.Git != ""
+}
+
+// GemURL returns where the gem's remote, or remote when it's given
+// instead, serves its .gem file
+func (d *GemDependency) GemURL(remote string) string {
+	if remote == "" {
+		remote = d.Remote
+	}
+	if remote == "" {
+		remote = DefaultRemote
+	}
+	return strings.TrimSuffix(remote, "/") + "/gems/" + d.FullName() + ".gem"
+}
+
+// Integrity returns the subresource integrity string matching the gem's
+// checksums
+func (d *GemDependency) Integrity() (string, error) {
+	var digests []string
+	for _, checksum := range d.Checksums {
+		i := strings.Index(checksum, "=")
+		if i < 0 {
+			return "", fmt.Errorf("Malformed checksum for %s: %s", d.FullName(), checksum)
+		}
+		digest, err := integrity.ParseHexDigest(checksum[:i], checksum[i+1:])
+		if err != nil {
+			return "", fmt.Errorf("%s: %s", d.FullName(), err)
+		}
+		digests = append(digests, digest.String())
+	}
+	return strings.Join(digests, " "), nil
+}
+
+// rubyPlatform is a platform such as x86_64-linux or arm64-darwin-22,
+// split into its CPU, OS and OS version
+type rubyPlatform struct {
+	cpu     string
+	os      string
+	version string
+}
+
+func parsePlatform(platform string) rubyPlatform {
+	parts := strings.SplitN(platform, "-", 3)
+	switch len(parts) {
+	case 1:
+		// Platforms such as java name only the OS
+		return rubyPlatform{os: parts[0]}
+	case 2:
+		return rubyPlatform{cpu: parts[0], os: parts[1]}
+	}
+	return rubyPlatform{cpu: parts[0], os: parts[1], version: parts[2]}
+}
+
+// matches reports whether gems built for p run on other, as rubygems
+// decides it: universal builds run on any CPU and builds naming no OS
+// version on every version
+func (p rubyPlatform) matches(other rubyPlatform) bool {
+	cpuMatches := p.cpu == other.cpu || p.cpu == "" || other.cpu == "" ||
+		p.cpu == "universal" || other.cpu == "universal"
+	versionMatches := p.version == other.version || p.version == "" || other.version == ""
+	return cpuMatches && p.os == other.os && versionMatches
+}
+
+// ForPlatforms reports whether the gem is needed on any of platforms:
+// pure ruby gems always are, and precompiled ones where they run
+func (d *GemDependency) ForPlatforms(platforms []string) bool {
+	if d.Platform == "" || d.Platform == "ruby" {
+		return true
+	}
+	gemPlatform := parsePlatform(d.Platform)
+	for _, platform := range platforms {
+		if gemPlatform.matches(parsePlatform(platform)) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseGemFileName splits a .gem file name such as
+// nokogiri-1.15.0-x86_64-linux.gem into the gem it holds. Gem names may
+// hold dashes, but none of their parts start with a digit the way
+// versions do, so the version is the first part which does and any parts
+// after it name the platform.
+func ParseGemFileName(fileName string) (GemDependency, bool) {
+	if !strings.HasSuffix(fileName, ".gem") {
+		return GemDependency{}, false
+	}
+	parts := strings.Split(strings.TrimSuffix(fileName, ".gem"), "-")
+	for i := 1; i < len(parts); i++ {
+		if parts[i] == "" || parts[i][0] < '0' || parts[i][0] > '9' {
+			continue
+		}
+		return GemDependency{
+			Name:     strings.Join(parts[:i], "-"),
+			Version:  parts[i],
+			Platform: strings.Join(parts[i+1:], "-"),
+		}, true
+	}
+	return GemDependency{}, false
+}
+
+// SelectGemFiles picks the .gem file to install of each gem version
+// among fileNames on a machine running gems built for platforms: a
+// precompiled gem which runs there or, failing that, the pure ruby gem.
+// Files built for other platforms and those which aren't .gem files are
+// left out.
+func SelectGemFiles(fileNames []string, platforms []string) []string {
+	selected := make(map[string]int)
+	var files []string
+	precompiled := make(map[string]bool)
+	for _, fileName := range fileNames {
+		dep, ok := ParseGemFileName(fileName)
+		if !ok || !dep.ForPlatforms(platforms) {
+			continue
+		}
+		key := dep.Name + "-" + dep.Version
+		i, seen := selected[key]
+		if !seen {
+			selected[key] = len(files)
+			files = append(files, fileName)
+		} else if dep.Platform != "" && !precompiled[key] {
+			// Precompiled gems win over the pure ruby gem, which has
+			// to build its extensions
+			files[i] = fileName
+		}
+		precompiled[key] = precompiled[key] || dep.Platform != ""
+	}
+	return files
+}
+
+// ValidateGem checks that r opens with a POSIX tar header, whose magic
+// sits 257 bytes in. A .gem file is a plain tar archive of the gem's
+// metadata.gz and data.tar.gz, so this is all that can be checked without
+// unpacking it.
+func ValidateGem(r io.Reader) error {
+	return download.CheckMagic(bufio.NewReader(r), "tar", 257, "ustar")
+}
+
+// DependenciesFile holds the gems a Gemfile.lock locks and the platforms
+// it was resolved for
+type DependenciesFile struct {
+	Path         string
+	Dependencies []GemDependency
+	Platforms    []string
+}
+
+// ReadDependencies reads the Gemfile.lock in dirPath
+func ReadDependencies(fileSystem fs.FileSystem, dirPath string) (*DependenciesFile, error) {
+	filePath := path.Join(dirPath, GemfileLockFileName)
+	contents, err := fileSystem.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	file, err := ParseGemfileLock(contents)
+	if err != nil {
+		return nil, err
+	}
+	file.Path = filePath
+	return file, nil
+}
@@ -0,0 +1,141 @@
+package ruby
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGemURL(t *testing.T) {
+	tests := []struct {
+		dep      GemDependency
+		remote   string
+		expected string
+	}{
+		{
+			GemDependency{Name: "nokogiri", Version: "1.15.0", Platform: "x86_64-linux", Remote: "https://rubygems.org/"},
+			"",
+			"https://rubygems.org/gems/nokogiri-1.15.0-x86_64-linux.gem",
+		},
+		{
+			GemDependency{Name: "rack", Version: "3.0.8", Platform: "ruby"},
+			"",
+			"https://rubygems.org/gems/rack-3.0.8.gem",
+		},
+		{
+			GemDependency{Name: "rack", Version: "3.0.8", Remote: "https://rubygems.org/"},
+			"https://gems.internal",
+			"https://gems.internal/gems/rack-3.0.8.gem",
+		},
+	}
+	for _, test := range tests {
+		if gemURL := test.dep.GemURL(test.remote); gemURL != test.expected {
+			t.Errorf("Expected %s, got %s", test.expected, gemURL)
+		}
+	}
+}
+
+func TestForPlatforms(t *testing.T) {
+	tests := []struct {
+		platform  string
+		platforms []string
+		expected  bool
+	}{
+		{"", []string{"x86_64-linux"}, true},
+		{"x86_64-linux", []string{"x86_64-linux"}, true},
+		{"arm64-darwin", []string{"arm64-darwin-22"}, true},
+		{"universal-darwin", []string{"arm64-darwin-22"}, true},
+		{"arm64-darwin", []string{"x86_64-linux", "ruby"}, false},
+		{"x86_64-darwin-21", []string{"x86_64-darwin-22"}, false},
+		{"java", []string{"java"}, true},
+	}
+	for _, test := range tests {
+		dep := GemDependency{Name: "nokogiri", Version: "1.15.0", Platform: test.platform}
+		if dep.ForPlatforms(test.platforms) != test.expected {
+			t.Errorf("Expected %s on %q to be %t", test.platform, test.platforms, test.expected)
+		}
+	}
+}
+
+func TestParseGemFileName(t *testing.T) {
+	tests := []struct {
+		fileName string
+		expected GemDependency
+		ok       bool
+	}{
+		{"rack-3.0.8.gem", GemDependency{Name: "rack", Version: "3.0.8"}, true},
+		{"net-http-0.4.1.gem", GemDependency{Name: "net-http", Version: "0.4.1"}, true},
+		{"nokogiri-1.15.0-x86_64-linux.gem", GemDependency{Name: "nokogiri", Version: "1.15.0", Platform: "x86_64-linux"}, true},
+		{"nokogiri-1.15.0-arm64-darwin-22.gem", GemDependency{Name: "nokogiri", Version: "1.15.0", Platform: "arm64-darwin-22"}, true},
+		{"rack.gem", GemDependency{}, false},
+		{"rack-3.0.8.tgz", GemDependency{}, false},
+	}
+	for _, test := range tests {
+		dep, ok := ParseGemFileName(test.fileName)
+		if ok != test.ok || !reflect.DeepEqual(dep, test.expected) {
+			t.Errorf("Expected %+v (%t) for %s, got %+v (%t)", test.expected, test.ok, test.fileName, dep, ok)
+		}
+	}
+}
+
+func TestSelectGemFiles(t *testing.T) {
+	fileNames := []string{
+		"nokogiri-1.15.0-arm64-darwin.gem",
+		"nokogiri-1.15.0-x86_64-linux.gem",
+		"nokogiri-1.15.0.gem",
+		"rack-3.0.8.gem",
+		"sqlite3-1.6.3-arm64-darwin.gem",
+		"sqlite3-1.6.3.gem",
+		"manifest.json",
+	}
+	tests := []struct {
+		platforms []string
+		expected  []string
+	}{
+		{
+			[]string{"x86_64-linux"},
+			[]string{"nokogiri-1.15.0-x86_64-linux.gem", "rack-3.0.8.gem", "sqlite3-1.6.3.gem"},
+		},
+		{
+			[]string{"arm64-darwin-22"},
+			[]string{"nokogiri-1.15.0-arm64-darwin.gem", "rack-3.0.8.gem", "sqlite3-1.6.3-arm64-darwin.gem"},
+		},
+	}
+	for _, test := range tests {
+		if files := SelectGemFiles(fileNames, test.platforms); !reflect.DeepEqual(files, test.expected) {
+			t.Errorf("Expected %v on %v, got %v", test.expected, test.platforms, files)
+		}
+	}
+}
+
+func TestGemIntegrity(t *testing.T) {
+	dep := GemDependency{
+		Name:      "racc",
+		Version:   "1.7.1",
+		Checksums: []string{"sha256=af64124836fdd3c00e830703d7f873ea5deabde923f37006a39f5a5e0da16387"},
+	}
+	sri, err := dep.Integrity()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if sri != "sha256-r2QSSDb908AOgwcD1/hz6l3qvekj83AGo59aXg2hY4c=" {
+		t.Errorf("Unexpected integrity %s", sri)
+	}
+
+	dep.Checksums = []string{"sha256:af64"}
+	if _, err = dep.Integrity(); err == nil {
+		t.Errorf("Expected an error for a malformed checksum")
+	}
+}
+
+func TestValidateGem(t *testing.T) {
+	header := make([]byte, 512)
+	copy(header, "metadata.gz")
+	copy(header[257:], "ustar\x0000")
+	if err := ValidateGem(strings.NewReader(string(header))); err != nil {
+		t.Errorf("err: %s", err)
+	}
+	if err := ValidateGem(strings.NewReader("<html>Not found</html>")); err == nil {
+		t.Errorf("Expected an error for an error page")
+	}
+}
@@ -0,0 +1,123 @@
+package rust
+
+import (
+	"bitbucket.org/bosgood/dep-get/lib/fs"
+	"bitbucket.org/bosgood/dep-get/lib/integrity"
+	"fmt"
+	"github.com/BurntSushi/toml"
+	"path"
+	"strings"
+)
+
+// CargoLockFileName is the name of Cargo's lock file
+const CargoLockFileName = "Cargo.lock"
+
+// CargoLock represents a Cargo.lock file. Version is 3 or 4 for the
+// lockfiles fetch reads, which give each package's checksum inline.
+type CargoLock struct {
+	Version  int            `toml:"version"`
+	Packages []CargoPackage `toml:"package"`
+}
+
+// CargoPackage represents a [[package]] entry of a Cargo.lock file.
+// Source is empty for the workspace's own packages and path dependencies,
+// registry+URL or sparse+URL for crates from a registry and git+URL for
+// those checked out of a repository.
+type CargoPackage struct {
+	Name     string `toml:"name"`
+	Version  string `toml:"version"`
+	Source   string `toml:"source"`
+	Checksum string `toml:"checksum"`
+}
+
+// CrateDependency is a crate locked in a Cargo.lock, with the SHA-256
+// Checksum of its .crate file when it comes from a registry
+type CrateDependency struct {
+	Name     string
+	Version  string
+	Source   string
+	Checksum string
+}
+
+// GetCanonicalName returns a unique name for the crate at this version
+func (d *CrateDependency) GetCanonicalName() string {
+	return d.Name + "@" + d.Version
+}
+
+// FileName returns the name of the crate's .crate file
+func (d *CrateDependency) FileName() string {
+	return d.Name + "-" + d.Version + ".crate"
+}
+
+// IsRegistry reports whether the crate is downloaded from a registry
+func (d *CrateDependency) IsRegistry() bool {
+	return strings.HasPrefix(d.Source, "registry+") || strings.HasPrefix(d.Source, "sparse+")
+}
+
+// IsGit reports whether the crate is checked out of a git repository
+func (d *CrateDependency) IsGit() bool {
+	return strings.HasPrefix(d.Source, "git+")
+}
+
+// Integrity returns the subresource integrity string matching the
+// crate's checksum
+func (d *CrateDependency) Integrity() (string, error) {
+	if d.Checksum == "" {
+		return "", nil
+	}
+	digest, err := integrity.ParseHexDigest("sha256", d.Checksum)
+	if err != nil {
+		return "", fmt.Errorf("%s: %s", d.GetCanonicalName(), err)
+	}
+	return digest.String(), nil
+}
+
+// ParseCargoLock decodes a Cargo.lock file
+func ParseCargoLock(contents []byte) ([]CrateDependency, error) {
+	var lockfile CargoLock
+	if _, err := toml.Decode(string(contents), &lockfile); err != nil {
+		return nil, err
+	}
+	if lockfile.Version != 3 && lockfile.Version != 4 {
+		return nil, fmt.Errorf(
+			"Unsupported %s version %d (expected 3 or 4, which cargo generate-lockfile writes)",
+			CargoLockFileName,
+			lockfile.Version,
+		)
+	}
+
+	var deps []CrateDependency
+	for _, pkg := range lockfile.Packages {
+		dep := CrateDependency{
+			Name:     pkg.Name,
+			Version:  pkg.Version,
+			Source:   pkg.Source,
+			Checksum: pkg.Checksum,
+		}
+		if dep.IsRegistry() && dep.Checksum == "" {
+			return nil, fmt.Errorf("%s has no checksum", dep.GetCanonicalName())
+		}
+		deps = append(deps, dep)
+	}
+	return deps, nil
+}
+
+// DependenciesFile holds the crates a Cargo.lock locks
+type DependenciesFile struct {
+	Path         string
+	Dependencies []CrateDependency
+}
+
+// ReadDependencies reads the Cargo.lock in dirPath
+func ReadDependencies(fileSystem fs.FileSystem, dirPath string) (*DependenciesFile, error) {
+	filePath := path.Join(dirPath, CargoLockFileName)
+	contents, err := fileSystem.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	deps, err := ParseCargoLock(contents)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", filePath, err)
+	}
+	return &DependenciesFile{Path: filePath, Dependencies: deps}, nil
+}
@@ -0,0 +1,107 @@
+package rust
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testCargoLock = `# This file is automatically @generated by Cargo.
+# It is not intended for manual editing.
+version = 4
+
+[[package]]
+name = "app"
+version = "0.1.0"
+dependencies = [
+ "serde",
+ "sha-1",
+]
+
+[[package]]
+name = "serde"
+version = "1.0.188"
+source = "registry+https://github.com/rust-lang/crates.io-index"
+checksum = "cf9e0fcba69a370eed61bcf2b728575f726b50b55cba78064753d708ddc7549e"
+
+[[package]]
+name = "sha-1"
+version = "0.10.1"
+source = "sparse+https://index.crates.io/"
+checksum = "f5058ada175748e33390e40e872bd0fe59a19f265d0158daa551c5a88a76009c"
+
+[[package]]
+name = "tokio"
+version = "1.32.0"
+source = "git+https://github.com/tokio-rs/tokio?branch=master#1e2dd5d0"
+`
+
+func TestParseCargoLock(t *testing.T) {
+	deps, err := ParseCargoLock([]byte(testCargoLock))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	expected := []CrateDependency{
+		{Name: "app", Version: "0.1.0"},
+		{
+			Name:     "serde",
+			Version:  "1.0.188",
+			Source:   CratesIOSource,
+			Checksum: "cf9e0fcba69a370eed61bcf2b728575f726b50b55cba78064753d708ddc7549e",
+		},
+		{
+			Name:     "sha-1",
+			Version:  "0.10.1",
+			Source:   CratesIOSparseSource,
+			Checksum: "f5058ada175748e33390e40e872bd0fe59a19f265d0158daa551c5a88a76009c",
+		},
+		{
+			Name:    "tokio",
+			Version: "1.32.0",
+			Source:  "git+https://github.com/tokio-rs/tokio?branch=master#1e2dd5d0",
+		},
+	}
+	if !reflect.DeepEqual(deps, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, deps)
+	}
+	if deps[0].IsRegistry() || !deps[1].IsRegistry() || !deps[2].IsRegistry() || deps[3].IsRegistry() || !deps[3].IsGit() {
+		t.Errorf("Expected only serde and sha-1 to come from a registry")
+	}
+}
+
+func TestParseCargoLockErrors(t *testing.T) {
+	tests := []struct {
+		contents string
+		expected string
+	}{
+		{
+			"[[package]]\nname = \"serde\"\nversion = \"1.0.188\"\n",
+			"Unsupported Cargo.lock version 0",
+		},
+		{
+			"version = 4\n\n[[package]]\nname = \"serde\"\nversion = \"1.0.188\"\nsource = \"registry+https://github.com/rust-lang/crates.io-index\"\n",
+			"serde@1.0.188 has no checksum",
+		},
+	}
+	for _, test := range tests {
+		_, err := ParseCargoLock([]byte(test.contents))
+		if err == nil || !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("Expected %q, got %v", test.expected, err)
+		}
+	}
+}
+
+func TestCrateIntegrity(t *testing.T) {
+	dep := CrateDependency{
+		Name:     "serde",
+		Version:  "1.0.188",
+		Checksum: "cf9e0fcba69a370eed61bcf2b728575f726b50b55cba78064753d708ddc7549e",
+	}
+	sri, err := dep.Integrity()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if sri != "sha256-z54Py6aaNw7tYbzytyhXX3JrULVcungGR1PXCN3HVJ4=" {
+		t.Errorf("Unexpected integrity %s", sri)
+	}
+}
@@ -0,0 +1,96 @@
+package rust
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const (
+	// CratesIOSource is the source Cargo.lock gives crates from crates.io,
+	// through its git index
+	CratesIOSource = "registry+https://github.com/rust-lang/crates.io-index"
+	// CratesIOSparseSource is the source Cargo.lock gives crates from
+	// crates.io through its sparse index
+	CratesIOSparseSource = "sparse+https://index.crates.io/"
+	// CratesIODownloadURL is where crates.io serves .crate files, as the
+	// dl key of its index's config.json gives it
+	CratesIODownloadURL = "https://static.crates.io/crates"
+)
+
+// IsCratesIO reports whether source is crates.io's, through either index
+func IsCratesIO(source string) bool {
+	return source == CratesIOSource || source == CratesIOSparseSource
+}
+
+// SparseIndexURL returns the URL of a sparse registry index from the
+// source Cargo.lock gives, which git indexes don't have
+func SparseIndexURL(source string) (string, bool) {
+	if !strings.HasPrefix(source, "sparse+") {
+		return "", false
+	}
+	return strings.TrimPrefix(source, "sparse+"), true
+}
+
+// ConfigURL returns the URL of a registry index's config.json
+func ConfigURL(indexURL string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(indexURL, "sparse+"), "/") + "/config.json"
+}
+
+// RegistryConfig is the config.json at the root of a registry index
+type RegistryConfig struct {
+	// DL is the download endpoint, a URL template which may hold the
+	// {crate}, {version}, {prefix}, {lowerprefix} and {sha256-checksum}
+	// markers
+	DL  string `json:"dl"`
+	API string `json:"api"`
+}
+
+// ParseRegistryConfig parses a registry index's config.json
+func ParseRegistryConfig(contents []byte) (*RegistryConfig, error) {
+	var config RegistryConfig
+	if err := json.Unmarshal(contents, &config); err != nil {
+		return nil, err
+	}
+	if config.DL == "" {
+		return nil, fmt.Errorf("No dl download endpoint in config.json")
+	}
+	return &config, nil
+}
+
+// cratePrefix returns the directory an index keeps a crate in: 1, 2 or
+// 3/a for names of up to three characters and ab/cd for longer ones
+func cratePrefix(name string) string {
+	switch len(name) {
+	case 1, 2:
+		return fmt.Sprintf("%d", len(name))
+	case 3:
+		return "3/" + name[:1]
+	}
+	return name[:2] + "/" + name[2:4]
+}
+
+// markers are the placeholders a dl template may hold
+var markers = []string{"{crate}", "{version}", "{prefix}", "{lowerprefix}", "{sha256-checksum}"}
+
+// DownloadURL returns where a registry whose download endpoint is dl
+// serves dep's .crate file. Templates without any marker have
+// /{crate}/{version}/download appended, as cargo does.
+func DownloadURL(dl string, dep CrateDependency) string {
+	hasMarker := false
+	for _, marker := range markers {
+		if strings.Contains(dl, marker) {
+			hasMarker = true
+		}
+	}
+	if !hasMarker {
+		return strings.TrimSuffix(dl, "/") + "/" + dep.Name + "/" + dep.Version + "/download"
+	}
+	return strings.NewReplacer(
+		"{crate}", dep.Name,
+		"{version}", dep.Version,
+		"{prefix}", cratePrefix(dep.Name),
+		"{lowerprefix}", strings.ToLower(cratePrefix(dep.Name)),
+		"{sha256-checksum}", dep.Checksum,
+	).Replace(dl)
+}
@@ -0,0 +1,57 @@
+package rust
+
+import (
+	"testing"
+)
+
+func TestDownloadURL(t *testing.T) {
+	tests := []struct {
+		dl       string
+		name     string
+		expected string
+	}{
+		{CratesIODownloadURL, "serde", "https://static.crates.io/crates/serde/1.0.188/download"},
+		{"https://crates.internal/api/v1/crates/", "serde", "https://crates.internal/api/v1/crates/serde/1.0.188/download"},
+		{"https://mirror.internal/{crate}/{crate}-{version}.crate", "serde", "https://mirror.internal/serde/serde-1.0.188.crate"},
+		{"https://mirror.internal/{prefix}/{crate}", "a", "https://mirror.internal/1/a"},
+		{"https://mirror.internal/{prefix}/{crate}", "cc", "https://mirror.internal/2/cc"},
+		{"https://mirror.internal/{prefix}/{crate}", "Syn", "https://mirror.internal/3/S/Syn"},
+		{"https://mirror.internal/{lowerprefix}/{crate}", "Serde", "https://mirror.internal/se/rd/Serde"},
+		{"https://mirror.internal/by-hash/{sha256-checksum}", "serde", "https://mirror.internal/by-hash/abc123"},
+	}
+	for _, test := range tests {
+		dep := CrateDependency{Name: test.name, Version: "1.0.188", Checksum: "abc123"}
+		if crateURL := DownloadURL(test.dl, dep); crateURL != test.expected {
+			t.Errorf("Expected %s, got %s", test.expected, crateURL)
+		}
+	}
+}
+
+func TestParseRegistryConfig(t *testing.T) {
+	config, err := ParseRegistryConfig([]byte(`{"dl": "https://static.crates.io/crates", "api": "https://crates.io"}`))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if config.DL != CratesIODownloadURL || config.API != "https://crates.io" {
+		t.Errorf("Unexpected config %+v", config)
+	}
+
+	if _, err = ParseRegistryConfig([]byte(`{"api": "https://crates.io"}`)); err == nil {
+		t.Errorf("Expected an error for a config without dl")
+	}
+}
+
+func TestConfigURL(t *testing.T) {
+	tests := []struct {
+		indexURL string
+		expected string
+	}{
+		{"https://index.crates.io/", "https://index.crates.io/config.json"},
+		{"sparse+https://crates.internal/index", "https://crates.internal/index/config.json"},
+	}
+	for _, test := range tests {
+		if configURL := ConfigURL(test.indexURL); configURL != test.expected {
+			t.Errorf("Expected %s, got %s", test.expected, configURL)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package rust
+
+import (
+	"archive/tar"
+	"bitbucket.org/bosgood/dep-get/lib/download"
+	"bitbucket.org/bosgood/dep-get/lib/fs"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// CargoChecksumFileName is the file cargo checks vendored crates against
+const CargoChecksumFileName = ".cargo-checksum.json"
+
+// CargoChecksum represents a .cargo-checksum.json file: the SHA-256 of
+// every file of a vendored crate, by path, and of its .crate file
+type CargoChecksum struct {
+	Files   map[string]string `json:"files"`
+	Package string            `json:"package"`
+}
+
+// crateFileNamePattern matches .crate file names. Crate names may hold
+// dashes followed by digits, such as sha-1, so the version is the
+// shortest suffix which is a whole semver version.
+var crateFileNamePattern = regexp.MustCompile(`^(.+)-(\d+\.\d+\.\d+(?:-[0-9A-Za-z.-]+)?(?:\+[0-9A-Za-z.-]+)?)\.crate$`)
+
+// ParseCrateFileName parses a .crate file name such as
+// serde_json-1.0.107.crate into the crate's name and version
+func ParseCrateFileName(fileName string) (string, string, bool) {
+	m := crateFileNamePattern.FindStringSubmatch(fileName)
+	if m == nil {
+		return "", "", false
+	}
+	return m[1], m[2], true
+}
+
+// ValidateCrate checks that r is gzipped, as .crate files are. Their
+// contents are only read when the crate is vendored.
+func ValidateCrate(r io.Reader) error {
+	return download.CheckMagic(bufio.NewReader(r), "gzip", 0, download.GzipMagic)
+}
+
+// vendorIgnored lists the files cargo vendor leaves out of the top level
+// of a vendored crate
+var vendorIgnored = []string{".gitattributes", ".gitignore", ".git", ".cargo-ok"}
+
+// VendorCrate unpacks the .crate file contents of a crate into dstDir as
+// cargo vendor does, writing a .cargo-checksum.json next to its files.
+// Anything already in dstDir is removed first, since cargo rejects a
+// vendored crate with files its checksums don't list
+func VendorCrate(fileSystem fs.FileSystem, contents []byte, name, version, dstDir string) error {
+	gz, err := gzip.NewReader(bytes.NewReader(contents))
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+	if err = fileSystem.RemoveAll(dstDir); err != nil {
+		return err
+	}
+
+	prefix := name + "-" + version + "/"
+	checksum := CargoChecksum{Files: make(map[string]string)}
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("Unreadable crate: %s", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		if !strings.HasPrefix(header.Name, prefix) {
+			return fmt.Errorf("%s is outside %s", header.Name, prefix)
+		}
+		relPath := strings.TrimPrefix(header.Name, prefix)
+		cleaned := path.Clean(relPath)
+		if cleaned != relPath || cleaned == "." || strings.HasPrefix(cleaned, "../") || path.IsAbs(cleaned) {
+			return fmt.Errorf("Unsafe path %s in crate", header.Name)
+		}
+		ignored := false
+		for _, ignoredName := range vendorIgnored {
+			if relPath == ignoredName || strings.HasPrefix(relPath, ignoredName+"/") {
+				ignored = true
+			}
+		}
+		if ignored {
+			continue
+		}
+
+		filePath := path.Join(dstDir, relPath)
+		if err = fileSystem.MkdirAll(path.Dir(filePath), 0755); err != nil {
+			return err
+		}
+		outFile, err := fileSystem.Create(filePath)
+		if err != nil {
+			return err
+		}
+		hash := sha256.New()
+		_, err = io.Copy(io.MultiWriter(outFile, hash), tr)
+		if cerr := outFile.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return err
+		}
+		checksum.Files[relPath] = hex.EncodeToString(hash.Sum(nil))
+	}
+
+	packageHash := sha256.Sum256(contents)
+	checksum.Package = hex.EncodeToString(packageHash[:])
+	checksumJSON, err := json.Marshal(checksum)
+	if err != nil {
+		return err
+	}
+	if err = fileSystem.MkdirAll(dstDir, 0755); err != nil {
+		return err
+	}
+	checksumFile, err := fileSystem.Create(path.Join(dstDir, CargoChecksumFileName))
+	if err != nil {
+		return err
+	}
+	_, err = checksumFile.Write(checksumJSON)
+	if cerr := checksumFile.Close(); cerr != nil && err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// VendorDirName returns the directory cargo vendor puts a crate in: its
+// name, or its name and version when several versions are vendored
+func VendorDirName(name, version string, versioned bool) string {
+	if versioned {
+		return name + "-" + version
+	}
+	return name
+}
@@ -0,0 +1,198 @@
+package rust
+
+import (
+	"archive/tar"
+	"bitbucket.org/bosgood/dep-get/lib/fs"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+)
+
+func TestParseCrateFileName(t *testing.T) {
+	tests := []struct {
+		fileName string
+		name     string
+		version  string
+		ok       bool
+	}{
+		{"serde_json-1.0.107.crate", "serde_json", "1.0.107", true},
+		{"sha-1-0.10.1.crate", "sha-1", "0.10.1", true},
+		{"foo-1.0.0-alpha.1.crate", "foo", "1.0.0-alpha.1", true},
+		{"foo-1.0.0+build.5.crate", "foo", "1.0.0+build.5", true},
+		{"foo-1.0.crate", "", "", false},
+		{"foo-1.0.0.tar.gz", "", "", false},
+	}
+	for _, test := range tests {
+		name, version, ok := ParseCrateFileName(test.fileName)
+		if name != test.name || version != test.version || ok != test.ok {
+			t.Errorf("Expected %s to parse as %s %s %t, got %s %s %t", test.fileName, test.name, test.version, test.ok, name, version, ok)
+		}
+	}
+}
+
+func TestValidateCrate(t *testing.T) {
+	if err := ValidateCrate(bytes.NewReader([]byte{0x1f, 0x8b, 0x08})); err != nil {
+		t.Errorf("err: %s", err)
+	}
+	if err := ValidateCrate(bytes.NewReader([]byte("<html>"))); err == nil {
+		t.Errorf("Expected an error for an HTML page")
+	}
+}
+
+// testCrate returns a .crate file holding files under name-version/
+func testCrate(t *testing.T, prefix string, files map[string]string) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, name := range []string{"Cargo.toml", "src/lib.rs", ".cargo-ok", ".gitignore"} {
+		contents, ok := files[name]
+		if !ok {
+			continue
+		}
+		err := tw.WriteHeader(&tar.Header{
+			Name:     prefix + name,
+			Mode:     0644,
+			Size:     int64(len(contents)),
+			Typeflag: tar.TypeReg,
+		})
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if _, err = tw.Write([]byte(contents)); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	return buf.Bytes()
+}
+
+func sha256Hex(contents []byte) string {
+	sum := sha256.Sum256(contents)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestVendorCrate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dep-get-rust")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	crate := testCrate(t, "serde-1.0.188/", map[string]string{
+		"Cargo.toml": "[package]\nname = \"serde\"\n",
+		"src/lib.rs": "pub trait Serialize {}\n",
+		".cargo-ok":  "ok",
+		".gitignore": "target\n",
+	})
+	dstDir := path.Join(dir, "vendor", "serde")
+	if err = VendorCrate(&fs.OSFS{}, crate, "serde", "1.0.188", dstDir); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	lib, err := ioutil.ReadFile(path.Join(dstDir, "src", "lib.rs"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if string(lib) != "pub trait Serialize {}\n" {
+		t.Errorf("Unexpected src/lib.rs %q", lib)
+	}
+	if _, err = os.Stat(path.Join(dstDir, ".cargo-ok")); !os.IsNotExist(err) {
+		t.Errorf("Expected .cargo-ok to be left out")
+	}
+
+	checksumJSON, err := ioutil.ReadFile(path.Join(dstDir, CargoChecksumFileName))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	var checksum CargoChecksum
+	if err = json.Unmarshal(checksumJSON, &checksum); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	expected := CargoChecksum{
+		Files: map[string]string{
+			"Cargo.toml": sha256Hex([]byte("[package]\nname = \"serde\"\n")),
+			"src/lib.rs": sha256Hex(lib),
+		},
+		Package: sha256Hex(crate),
+	}
+	if !reflect.DeepEqual(checksum, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, checksum)
+	}
+}
+
+func TestVendorCrateReplacesStaleFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dep-get-rust")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	dstDir := path.Join(dir, "vendor", "serde")
+	old := testCrate(t, "serde-1.0.187/", map[string]string{
+		"Cargo.toml":   "[package]\nname = \"serde\"\n",
+		"src/lib.rs":   "pub trait Serialize {}\n",
+		"src/stale.rs": "pub struct Stale;\n",
+	})
+	if err = VendorCrate(&fs.OSFS{}, old, "serde", "1.0.187", dstDir); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	crate := testCrate(t, "serde-1.0.188/", map[string]string{
+		"Cargo.toml": "[package]\nname = \"serde\"\n",
+		"src/lib.rs": "pub trait Serialize {}\n",
+	})
+	if err = VendorCrate(&fs.OSFS{}, crate, "serde", "1.0.188", dstDir); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if _, err = os.Stat(path.Join(dstDir, "src", "stale.rs")); !os.IsNotExist(err) {
+		t.Errorf("Expected src/stale.rs from the earlier version to be removed")
+	}
+	checksumJSON, err := ioutil.ReadFile(path.Join(dstDir, CargoChecksumFileName))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	var checksum CargoChecksum
+	if err = json.Unmarshal(checksumJSON, &checksum); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	files, err := fs.Walk(&fs.OSFS{}, dstDir)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(files) != len(checksum.Files) {
+		t.Errorf("Expected the vendored files %v to match the checksums %+v", files, checksum.Files)
+	}
+	for _, file := range files {
+		if _, ok := checksum.Files[file]; !ok {
+			t.Errorf("Expected a checksum for %s", file)
+		}
+	}
+	if checksum.Package != sha256Hex(crate) {
+		t.Errorf("Expected the package checksum of the new crate, got %s", checksum.Package)
+	}
+}
+
+func TestVendorCrateOutsidePrefix(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dep-get-rust")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	crate := testCrate(t, "other-1.0.0/", map[string]string{"Cargo.toml": ""})
+	if err = VendorCrate(&fs.OSFS{}, crate, "serde", "1.0.188", dir); err == nil {
+		t.Errorf("Expected an error for files outside serde-1.0.188/")
+	}
+}